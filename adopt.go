@@ -0,0 +1,144 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const ignoreRevsFilename = ".git-blame-ignore-revs"
+
+// runAdopt formats all Cadence files of the repository in a single commit,
+// and records that commit in the ignore-revs file,
+// so git blame skips over the reformatting
+func runAdopt(args []string) {
+	flags := flag.NewFlagSet("adopt", flag.ExitOnError)
+	optionFlags := addOptionFlags(flags)
+	messageFlag := flags.String("m", "Format Cadence code with cadencefmt", "message of the formatting commit")
+	forceFlag := flags.Bool("force", false, "run even if the working tree has uncommitted changes")
+	_ = flags.Parse(args)
+
+	options, err := optionFlags.cliOptions()
+	if err != nil {
+		fatal(err)
+	}
+	options.write = true
+
+	//the commit must only contain the reformatting
+	status, err := git("status", "--porcelain")
+	if err != nil {
+		fatal(err)
+	}
+	if strings.TrimSpace(status) != "" && !*forceFlag {
+		fatal(errors.New("the working tree has uncommitted changes, commit or stash them, or use -force"))
+	}
+
+	toplevel, err := git("rev-parse", "--show-toplevel")
+	if err != nil {
+		fatal(err)
+	}
+	toplevel = strings.TrimSpace(toplevel)
+
+	output, err := git("-C", toplevel, "ls-files", "--", "*.cdc")
+	if err != nil {
+		fatal(err)
+	}
+
+	var formatted []string
+	for _, filename := range strings.Fields(output) {
+		path := filepath.Join(toplevel, filename)
+		//the declarations of files which do not parse are kept as written,
+		//the parse errors are printed
+		_, err := formatFile(path, options, io.Discard, os.Stderr)
+		if err != nil && !errors.Is(err, errParseFailed) {
+			fmt.Fprintf(os.Stderr, "warning: skipped %s\n", err)
+			continue
+		}
+		formatted = append(formatted, filename)
+	}
+
+	changed, err := git(append([]string{"-C", toplevel, "diff", "--name-only", "--"}, formatted...)...)
+	if err != nil {
+		fatal(err)
+	}
+	if strings.TrimSpace(changed) == "" {
+		fmt.Println("All Cadence files are already formatted")
+		return
+	}
+
+	if _, err := git(append([]string{"-C", toplevel, "add", "--"}, formatted...)...); err != nil {
+		fatal(err)
+	}
+	if _, err := git("-C", toplevel, "commit", "--quiet", "-m", *messageFlag); err != nil {
+		fatal(err)
+	}
+
+	hash, err := git("-C", toplevel, "rev-parse", "HEAD")
+	if err != nil {
+		fatal(err)
+	}
+	hash = strings.TrimSpace(hash)
+
+	if err := appendIgnoreRev(filepath.Join(toplevel, ignoreRevsFilename), hash, *messageFlag); err != nil {
+		fatal(err)
+	}
+	if _, err := git("-C", toplevel, "add", "--", ignoreRevsFilename); err != nil {
+		fatal(err)
+	}
+	if _, err := git("-C", toplevel, "commit", "--quiet", "-m", "Ignore cadencefmt formatting in git blame"); err != nil {
+		fatal(err)
+	}
+
+	fmt.Printf("Formatted %d files in %s\n", len(strings.Fields(changed)), hash)
+	fmt.Printf("Added the commit to %s. GitHub uses it automatically, for local git blame run:\n\n", ignoreRevsFilename)
+	fmt.Printf("\tgit config blame.ignoreRevsFile %s\n", ignoreRevsFilename)
+}
+
+// appendIgnoreRev adds the commit, described by the comment, to the ignore-revs file
+func appendIgnoreRev(path string, hash string, comment string) error {
+	existing, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	var entry strings.Builder
+	if len(existing) > 0 && !strings.HasSuffix(string(existing), "\n") {
+		entry.WriteString("\n")
+	}
+	if len(existing) > 0 {
+		entry.WriteString("\n")
+	}
+	fmt.Fprintf(&entry, "# %s\n%s\n", firstLine(comment), hash)
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.WriteString(entry.String()); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,177 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// argumentKeys is the order of the keys of JSON-Cadence values,
+// the other keys follow in alphabetical order
+var argumentKeys = []string{
+	"type", "kind", "typeID", "id", "name", "key", "value", "fields",
+	"domain", "identifier", "address", "path", "borrowType", "staticType",
+}
+
+// runArgs formats JSON-Cadence argument files, as used by flow transactions send --args-json
+func runArgs(args []string) {
+	flags := flag.NewFlagSet("args", flag.ExitOnError)
+	indentFlag := flags.Int("indent", 2, "number of spaces per indentation level")
+	writeFlag := flags.Bool("w", false, "write the results to the files instead of stdout")
+	checkFlag := flags.Bool("check", false, "list files which are not formatted and exit with 1")
+	_ = flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		fatal(errors.New("args requires JSON-Cadence files"))
+	}
+
+	unformatted := 0
+	for _, filename := range flags.Args() {
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			fatal(err)
+		}
+		result, err := formatArguments(data, strings.Repeat(" ", *indentFlag))
+		if err != nil {
+			fatal(fmt.Errorf("%s: %w", filename, err))
+		}
+
+		switch {
+		case *checkFlag:
+			if !bytes.Equal(data, result) {
+				fmt.Println(filename)
+				unformatted++
+			}
+		case *writeFlag:
+			if !bytes.Equal(data, result) {
+				if err := writeFormatted(filename, data, result, ""); err != nil {
+					fatal(err)
+				}
+			}
+		default:
+			_, _ = os.Stdout.Write(result)
+		}
+	}
+	if unformatted > 0 {
+		os.Exit(exitNotFormatted)
+	}
+}
+
+// formatArguments formats a JSON-Cadence document with the keys of its objects in the canonical order
+func formatArguments(data []byte, indent string) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("unexpected data after the JSON value")
+	}
+
+	var buffer bytes.Buffer
+	if err := writeArgument(&buffer, value, indent, ""); err != nil {
+		return nil, err
+	}
+	buffer.WriteByte('\n')
+	return buffer.Bytes(), nil
+}
+
+// writeArgument writes a JSON value, indenting its nested lines by the prefix
+func writeArgument(buffer *bytes.Buffer, value interface{}, indent string, prefix string) error {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		if len(value) == 0 {
+			buffer.WriteString("{}")
+			return nil
+		}
+		buffer.WriteString("{\n")
+		for i, key := range argumentKeyOrder(value) {
+			if i > 0 {
+				buffer.WriteString(",\n")
+			}
+			buffer.WriteString(prefix + indent)
+			if err := writeArgument(buffer, key, indent, prefix+indent); err != nil {
+				return err
+			}
+			buffer.WriteString(": ")
+			if err := writeArgument(buffer, value[key], indent, prefix+indent); err != nil {
+				return err
+			}
+		}
+		buffer.WriteString("\n" + prefix + "}")
+	case []interface{}:
+		if len(value) == 0 {
+			buffer.WriteString("[]")
+			return nil
+		}
+		buffer.WriteString("[\n")
+		for i, element := range value {
+			if i > 0 {
+				buffer.WriteString(",\n")
+			}
+			buffer.WriteString(prefix + indent)
+			if err := writeArgument(buffer, element, indent, prefix+indent); err != nil {
+				return err
+			}
+		}
+		buffer.WriteString("\n" + prefix + "]")
+	default:
+		encoder := json.NewEncoder(buffer)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(value); err != nil {
+			return err
+		}
+		//the encoder ends the value with a newline
+		buffer.Truncate(buffer.Len() - 1)
+	}
+	return nil
+}
+
+// argumentKeyOrder returns the keys of the object in the canonical order
+func argumentKeyOrder(object map[string]interface{}) []string {
+	rank := func(key string) int {
+		for i, known := range argumentKeys {
+			if key == known {
+				return i
+			}
+		}
+		return len(argumentKeys)
+	}
+
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		ri, rj := rank(keys[i]), rank(keys[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
@@ -0,0 +1,57 @@
+//go:build !noui
+
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/onflow/cadence/runtime/parser"
+)
+
+// ASTRequest is the body of /ast
+type ASTRequest struct {
+	Code string `json:"code"`
+}
+
+// handleAST responds with the program of the code, as encoded by the Cadence AST,
+// or with the parse errors
+func handleAST(w http.ResponseWriter, r *http.Request) {
+	var req ASTRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeBodyError(w, err)
+		return
+	}
+
+	program, err := parser.ParseProgram(nil, []byte(req.Code), parser.Config{})
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	encoded, err := json.Marshal(program)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(encoded)
+}
@@ -0,0 +1,73 @@
+//go:build !noui
+
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+const apiKeyEnv = "CADENCEFMT_API_KEY"
+
+var errUnauthorized = errors.New("missing or invalid API key")
+
+// requireAPIKey rejects requests other than GET and HEAD,
+// and WebSocket upgrades, which format code as well, and the profiler,
+// which do not carry one of the keys, comma separated,
+// either as a bearer token or in the X-API-Key header
+func requireAPIKey(keys string, next http.Handler) http.Handler {
+	var allowed [][]byte
+	for _, key := range strings.Split(keys, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			allowed = append(allowed, []byte(key))
+		}
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if (r.Method == http.MethodGet || r.Method == http.MethodHead) && !isWebSocketUpgrade(r) && !isProfiler(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := r.Header.Get("X-API-Key")
+		if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			key = token
+		}
+
+		for _, allowedKey := range allowed {
+			if subtle.ConstantTimeCompare([]byte(key), allowedKey) == 1 {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, http.StatusUnauthorized, errUnauthorized)
+	})
+}
+
+// isProfiler reports if the request is for the Go profiler of -pprof,
+// which exposes the memory and the command line of the server
+func isProfiler(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, "/debug/pprof/")
+}
@@ -0,0 +1,107 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// baseline records the files which were not formatted when check mode was adopted,
+// so check mode only fails for other files, or for files whose formatting got worse
+type baseline struct {
+	path string
+	// record replaces the baseline with the files of this run
+	record bool
+	mutex  sync.Mutex
+	// Files are the changed lines formatting each file would make
+	Files map[string]int `json:"files"`
+}
+
+// loadBaseline reads the baseline file.
+// If it does not exist yet, or update is set, the files of this run are recorded
+func loadBaseline(path string, update bool) (*baseline, error) {
+	b := &baseline{
+		path:   path,
+		record: update,
+		Files:  map[string]int{},
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		b.record = true
+		return b, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if update {
+		return b, nil
+	}
+
+	if err := json.Unmarshal(data, b); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return b, nil
+}
+
+// allows reports if the file may stay unformatted,
+// as it is in the baseline and formatting it changes no more lines than recorded.
+// When recording, all files are allowed and added to the baseline
+func (b *baseline) allows(filename, code, result string, report *fileReport) bool {
+	changed := editDistance(strings.Split(code, "\n"), strings.Split(result, "\n"))
+	key := filepath.ToSlash(filepath.Clean(filename))
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.record {
+		b.Files[key] = changed
+		return true
+	}
+
+	recorded, ok := b.Files[key]
+	if !ok {
+		return false
+	}
+	if changed > recorded {
+		report.add(diagnostic{
+			Severity: severityWarning,
+			Message:  fmt.Sprintf("formatting changes %d lines, baseline allows %d", changed, recorded),
+		})
+		return false
+	}
+	return true
+}
+
+// save writes the recorded baseline
+func (b *baseline) save() error {
+	if !b.record {
+		return nil
+	}
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(b.path, append(data, '\n'), 0644)
+}
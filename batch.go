@@ -0,0 +1,270 @@
+//go:build !noui
+
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"cadencefmt/format"
+)
+
+// BatchRequest formats many files in a single request
+type BatchRequest struct {
+	RequestOptions
+	Entries []BatchEntry `json:"entries"`
+}
+
+type BatchEntry struct {
+	Path string `json:"path"`
+	Code string `json:"code"`
+}
+
+// BatchResult is the formatted code of an entry,
+// or the error if it could not be formatted
+type BatchResult struct {
+	Path string `json:"path"`
+	Code string `json:"code"`
+	*ErrorResponse
+}
+
+type BatchResponse struct {
+	Results []BatchResult `json:"results"`
+}
+
+const ndjsonContentType = "application/x-ndjson"
+
+// batchLimits are the limits of batch requests
+type batchLimits struct {
+	// size limits the body of buffered batches, and each line of streamed ones
+	size int64
+	// formatTimeout limits the formatting of each entry
+	formatTimeout time.Duration
+	// readTimeout and writeTimeout limit reading each entry of streamed batches, and writing its result,
+	// instead of the whole request
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+}
+
+// handleBatch returns the handler which formats all entries of the request.
+// Errors of single entries are reported in their results,
+// so the response is successful even if some entries do not parse.
+// The format timeout applies to each entry, as a batch may have any number of them.
+//
+// Clients accepting NDJSON get each result on its own line as soon as it is formatted,
+// and may also send the request as NDJSON, with the options on the first line
+// and an entry on each following line, so neither side holds the whole batch
+func handleBatch(profiles profileDir, cache *formatCache, limits batchLimits) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.Header.Get("Accept"), ndjsonContentType) {
+			streamBatch(w, r, profiles, cache, limits)
+			return
+		}
+
+		var req BatchRequest
+		if err := decodeBody(r, &req); err != nil {
+			writeBodyError(w, err)
+			return
+		}
+
+		options, err := profiles.options(req.RequestOptions)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		response := BatchResponse{
+			Results: make([]BatchResult, 0, len(req.Entries)),
+		}
+		for _, entry := range req.Entries {
+			response.Results = append(response.Results, formatEntry(r.Context(), cache, entry, options, limits.formatTimeout))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	}
+}
+
+// formatEntry formats the code of the entry within the timeout, zero for no limit
+func formatEntry(ctx context.Context, cache *formatCache, entry BatchEntry, options format.Options, timeout time.Duration) BatchResult {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	result := BatchResult{Path: entry.Path}
+	code, err := cache.format(ctx, entry.Code, options)
+	if err != nil {
+		result.ErrorResponse = newErrorResponse(err)
+		return result
+	}
+	result.Code = code
+	return result
+}
+
+// streamBatch writes the result of each entry as soon as it is formatted.
+// Writes block while the client does not read, so the server does not get ahead of it.
+// The limits apply to each entry, as a batch may take arbitrarily long
+func streamBatch(w http.ResponseWriter, r *http.Request, profiles profileDir, cache *formatCache, limits batchLimits) {
+	controller := http.NewResponseController(w)
+	extendDeadlines := func() {
+		if limits.readTimeout > 0 {
+			_ = controller.SetReadDeadline(time.Now().Add(limits.readTimeout))
+		}
+		if limits.writeTimeout > 0 {
+			_ = controller.SetWriteDeadline(time.Now().Add(limits.writeTimeout))
+		}
+	}
+
+	entries, options, err := batchEntries(r, limits.size)
+	if err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	formatOptions, err := profiles.options(options)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", ndjsonContentType)
+	encoder := json.NewEncoder(w)
+	//the results are written while the entries are still read, which HTTP/1 servers stop by default
+	_ = controller.EnableFullDuplex()
+
+	for {
+		extendDeadlines()
+		entry, ok, err := entries()
+		if err != nil {
+			//the status was already sent, so the error is the last line
+			_ = encoder.Encode(newErrorResponse(err))
+			return
+		}
+		if !ok {
+			return
+		}
+
+		if err := encoder.Encode(formatEntry(r.Context(), cache, entry, formatOptions, limits.formatTimeout)); err != nil {
+			return
+		}
+		_ = controller.Flush()
+
+		//the client went away
+		if r.Context().Err() != nil {
+			return
+		}
+	}
+}
+
+// streamsBody reports if the request is a batch sent and answered as NDJSON,
+// whose body is read entry by entry, so its size is only limited for each line, see batchEntries
+func streamsBody(r *http.Request) bool {
+	return r.URL.Path == "/pretty/batch" &&
+		strings.HasPrefix(r.Header.Get("Content-Type"), ndjsonContentType) &&
+		strings.Contains(r.Header.Get("Accept"), ndjsonContentType)
+}
+
+// batchEntries returns the options of the batch, and a function returning its entries one by one.
+// NDJSON requests are read entry by entry, each line limited to the size, zero for no limit.
+// JSON requests are read at once, their body is limited like the ones of other requests
+func batchEntries(r *http.Request, size int64) (
+	next func() (BatchEntry, bool, error),
+	options RequestOptions,
+	err error,
+) {
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), ndjsonContentType) {
+		var req BatchRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return nil, RequestOptions{}, err
+		}
+		next = func() (BatchEntry, bool, error) {
+			if len(req.Entries) == 0 {
+				return BatchEntry{}, false, nil
+			}
+			entry := req.Entries[0]
+			req.Entries = req.Entries[1:]
+			return entry, true, nil
+		}
+		return next, req.RequestOptions, nil
+	}
+
+	reader := bufio.NewReader(r.Body)
+	line, err := readLine(reader, size)
+	if err == io.EOF {
+		return nil, RequestOptions{}, errors.New("the batch has no options line")
+	}
+	if err != nil {
+		return nil, RequestOptions{}, err
+	}
+	if err := json.Unmarshal(line, &options); err != nil {
+		return nil, RequestOptions{}, err
+	}
+	next = func() (BatchEntry, bool, error) {
+		for {
+			line, err := readLine(reader, size)
+			if err == io.EOF {
+				return BatchEntry{}, false, nil
+			}
+			if err != nil {
+				return BatchEntry{}, false, err
+			}
+			if len(bytes.TrimSpace(line)) == 0 {
+				continue
+			}
+			var entry BatchEntry
+			if err := json.Unmarshal(line, &entry); err != nil {
+				return BatchEntry{}, false, err
+			}
+			return entry, true, nil
+		}
+	}
+	return next, options, nil
+}
+
+// readLine reads the next line, failing with a MaxBytesError if it is longer than the limit, zero for no limit.
+// It returns io.EOF at the end of the reader
+func readLine(reader *bufio.Reader, limit int64) ([]byte, error) {
+	var line []byte
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		line = append(line, chunk...)
+		if limit > 0 && int64(len(line)) > limit {
+			return nil, fmt.Errorf("line exceeds %d bytes: %w", limit, &http.MaxBytesError{Limit: limit})
+		}
+		switch {
+		case err == bufio.ErrBufferFull:
+			continue
+		case err == io.EOF && len(line) > 0:
+			return line, nil
+		case err != nil:
+			return nil, err
+		}
+		return line, nil
+	}
+}
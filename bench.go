@@ -0,0 +1,141 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"time"
+
+	"cadencefmt/format"
+)
+
+// benchResult is the summary of a bench run, which releases can be compared by
+type benchResult struct {
+	Version string `json:"version"`
+	Files   int    `json:"files"`
+	Runs    int    `json:"runs"`
+	Bytes   int64  `json:"bytes"`
+	// Throughput is in KB of code formatted per second
+	Throughput float64 `json:"throughputKBs"`
+	// AllocsPerRun and BytesPerRun are the allocations to format a file once, on average
+	AllocsPerRun uint64 `json:"allocsPerRun"`
+	BytesPerRun  uint64 `json:"bytesPerRun"`
+	// P50 and P99 are the latencies of formatting a file
+	P50 time.Duration `json:"p50ns"`
+	P99 time.Duration `json:"p99ns"`
+}
+
+// runBench formats each file of the corpus n times, and reports the throughput,
+// the allocations and the latencies, so the performance of releases can be compared
+func runBench(args []string) {
+	flags := flag.NewFlagSet("bench", flag.ExitOnError)
+	optionFlags := addOptionFlags(flags)
+	roundsFlag := flags.Int("n", 10, "times each file is formatted")
+	jsonFlag := flags.Bool("json", false, "print the summary as JSON")
+	_ = flags.Parse(args)
+
+	cliOptions, err := optionFlags.cliOptions()
+	if err != nil {
+		fatal(err)
+	}
+	options := cliOptions.Options
+
+	if flags.NArg() == 0 || *roundsFlag <= 0 {
+		fatal(errors.New("usage: cadencefmt bench [-n runs] <corpus>..."))
+	}
+
+	filenames, err := cadenceFiles(flags.Args())
+	if err != nil {
+		fatal(err)
+	}
+
+	var codes []string
+	for _, filename := range filenames {
+		code, err := os.ReadFile(filename)
+		if err != nil {
+			fatal(err)
+		}
+		//files which do not format would only measure the parser
+		if _, err := format.Source(string(code), options); err != nil {
+			fmt.Fprintf(os.Stderr, "SKIP %s: %s\n", filename, firstLine(err.Error()))
+			continue
+		}
+		codes = append(codes, string(code))
+	}
+	if len(codes) == 0 {
+		fatal(errors.New("no file of the corpus formats"))
+	}
+
+	result := benchResult{
+		Version: currentVersion().Version,
+		Files:   len(codes),
+		Runs:    len(codes) * *roundsFlag,
+	}
+	latencies := make([]time.Duration, 0, result.Runs)
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	for round := 0; round < *roundsFlag; round++ {
+		for _, code := range codes {
+			runStart := time.Now()
+			if _, err := format.Source(code, options); err != nil {
+				fatal(err)
+			}
+			latencies = append(latencies, time.Since(runStart))
+			result.Bytes += int64(len(code))
+		}
+	}
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	result.Throughput = float64(result.Bytes) / 1024 / elapsed.Seconds()
+	result.AllocsPerRun = (after.Mallocs - before.Mallocs) / uint64(result.Runs)
+	result.BytesPerRun = (after.TotalAlloc - before.TotalAlloc) / uint64(result.Runs)
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	result.P50 = percentile(latencies, 50)
+	result.P99 = percentile(latencies, 99)
+
+	if *jsonFlag {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result); err != nil {
+			fatal(err)
+		}
+		return
+	}
+
+	fmt.Printf("cadencefmt %s, %d files formatted %d times\n", result.Version, result.Files, *roundsFlag)
+	fmt.Printf("throughput  %.1f KB/s\n", result.Throughput)
+	fmt.Printf("allocations %d per file, %d bytes\n", result.AllocsPerRun, result.BytesPerRun)
+	fmt.Printf("latency     p50 %s, p99 %s\n", result.P50, result.P99)
+}
+
+// percentile returns the latency below which the percentage of the sorted latencies are
+func percentile(sorted []time.Duration, percentage int) time.Duration {
+	index := (len(sorted)*percentage+99)/100 - 1
+	return sorted[max(index, 0)]
+}
@@ -0,0 +1,81 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/onflow/cadence/runtime/parser/lexer"
+)
+
+// sizeReport is the UTF-8 byte size of formatted code,
+// which is what counts against on-chain contract size limits
+type sizeReport struct {
+	Bytes                int `json:"bytes"`
+	BytesWithoutComments int `json:"bytesWithoutComments"`
+	Budget               int `json:"budget,omitempty"`
+}
+
+func measureSize(code string, budget int) sizeReport {
+	commentBytes := 0
+
+	tokens := lexer.Lex([]byte(code), nil)
+	defer tokens.Reclaim()
+
+	for {
+		token := tokens.Next()
+		if token.Is(lexer.TokenEOF) {
+			break
+		}
+
+		switch token.Type {
+		case lexer.TokenLineComment,
+			lexer.TokenBlockCommentStart,
+			lexer.TokenBlockCommentContent,
+			lexer.TokenBlockCommentEnd:
+
+			commentBytes += token.EndPos.Offset - token.StartPos.Offset + 1
+		}
+	}
+
+	return sizeReport{
+		Bytes:                len(code),
+		BytesWithoutComments: len(code) - commentBytes,
+		Budget:               budget,
+	}
+}
+
+// OverBudget reports if the code exceeds the budget, comments included.
+// A budget of zero disables the check
+func (r sizeReport) OverBudget() bool {
+	return r.Budget > 0 && r.Bytes > r.Budget
+}
+
+func (r sizeReport) String() string {
+	message := fmt.Sprintf("%d bytes (%d without comments)", r.Bytes, r.BytesWithoutComments)
+
+	if r.OverBudget() {
+		message += fmt.Sprintf(", exceeds budget of %d bytes", r.Budget)
+		if r.BytesWithoutComments <= r.Budget {
+			message += " (fits without comments)"
+		}
+	}
+
+	return message
+}
@@ -0,0 +1,57 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestBudgetReport(t *testing.T) {
+	code := "// a comment\npub fun a() {}\n"
+	report := newFileReport("A.cdc", true, false, nil)
+	options := cliOptions{budget: 10, finalNewline: true}
+
+	var output bytes.Buffer
+	if err := outputResult(report, code, code, options, &output); err != nil {
+		t.Fatal(err)
+	}
+	var encoded bytes.Buffer
+	if err := report.write(&encoded); err != nil {
+		t.Fatal(err)
+	}
+
+	var record struct {
+		Bytes                *int `json:"bytes"`
+		BytesWithoutComments *int `json:"bytesWithoutComments"`
+		Budget               int  `json:"budget"`
+	}
+	if err := json.Unmarshal(encoded.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record.Bytes == nil || *record.Bytes != len(code) {
+		t.Errorf("expected %d bytes, got %s", len(code), encoded.String())
+	}
+	if record.BytesWithoutComments == nil || *record.BytesWithoutComments != len(code)-len("// a comment") {
+		t.Errorf("expected %d bytes without comments, got %s", len(code)-len("// a comment"), encoded.String())
+	}
+	if record.Budget != 10 {
+		t.Errorf("expected the budget, got %s", encoded.String())
+	}
+}
@@ -0,0 +1,63 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"sync"
+)
+
+// maxPooledBuffer is the capacity above which buffers are not reused,
+// so a single large request does not keep its memory
+const maxPooledBuffer = 1 << 20
+
+// buffers are reused between requests for reading their bodies and writing messages,
+// as the web UI formats on every keystroke
+var buffers = sync.Pool{
+	New: func() any {
+		return new(bytes.Buffer)
+	},
+}
+
+// getBuffer returns an empty buffer from the pool
+func getBuffer() *bytes.Buffer {
+	buffer := buffers.Get().(*bytes.Buffer)
+	buffer.Reset()
+	return buffer
+}
+
+// putBuffer returns the buffer to the pool, unless it grew too large
+func putBuffer(buffer *bytes.Buffer) {
+	if buffer.Cap() <= maxPooledBuffer {
+		buffers.Put(buffer)
+	}
+}
+
+// decodeBody decodes the JSON body of the request, which is read into a reused buffer
+func decodeBody(r *http.Request, value any) error {
+	buffer := getBuffer()
+	defer putBuffer(buffer)
+
+	if _, err := buffer.ReadFrom(r.Body); err != nil {
+		return err
+	}
+	return json.Unmarshal(buffer.Bytes(), value)
+}
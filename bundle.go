@@ -0,0 +1,196 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"cadencefmt/format"
+)
+
+// codeString is a JSON string of a bundle which is Cadence code,
+// at its offsets in the bundle
+type codeString struct {
+	name  string
+	start int
+	end   int
+}
+
+// codeStrings returns the strings of the code in the bundle,
+// which maps names to either sources or objects with a "code" field, in order
+func codeStrings(data []byte) ([]codeString, error) {
+	var result []codeString
+	err := objectValues(data, 0, func(name string, value json.RawMessage, offset int) error {
+		found, err := entryCodeStrings(name, value, offset)
+		result = append(result, found...)
+		return err
+	})
+	return result, err
+}
+
+// entryCodeStrings returns the strings of the code of the entry at the offset
+func entryCodeStrings(name string, value json.RawMessage, offset int) ([]codeString, error) {
+	switch {
+	case bytes.HasPrefix(value, []byte(`"`)):
+		return []codeString{{name: name, start: offset, end: offset + len(value)}}, nil
+	case bytes.HasPrefix(value, []byte("{")):
+		var result []codeString
+		err := objectValues(value, offset, func(key string, value json.RawMessage, offset int) error {
+			if key != "code" {
+				return nil
+			}
+			found, err := entryCodeStrings(name, value, offset)
+			result = append(result, found...)
+			return err
+		})
+		return result, err
+	}
+	//other values are not code
+	return nil, nil
+}
+
+// objectValues calls the function with the values of the JSON object, and their offsets,
+// the object starting at the offset
+func objectValues(data []byte, offset int, f func(key string, value json.RawMessage, offset int) error) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token != json.Delim('{') {
+		return fmt.Errorf("expected object")
+	}
+
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		key := token.(string)
+
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return err
+		}
+		//the raw value is the text of the value, which ends at the offset of the decoder
+		end := offset + int(decoder.InputOffset())
+		if err := f(key, value, end-len(value)); err != nil {
+			return err
+		}
+	}
+
+	_, err = decoder.Token()
+	return err
+}
+
+// marshalJSON encodes the value without escaping HTML characters,
+// which are common in Cadence code, e.g. the move operator `<-`
+func marshalJSON(value any) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buffer.Bytes(), []byte("\n")), nil
+}
+
+// runBundle formats all Cadence sources of a JSON bundle,
+// which maps names to either sources or objects with a "code" field,
+// and writes the formatted sources back into the bundle, leaving all other bytes as they are
+func runBundle(args []string) {
+	flags := flag.NewFlagSet("bundle", flag.ExitOnError)
+	optionFlags := addOptionFlags(flags)
+	_ = flags.Parse(args)
+
+	cliOptions, err := optionFlags.cliOptions()
+	if err != nil {
+		fatal(err)
+	}
+	options := cliOptions.Options
+
+	filename := flags.Arg(0)
+	if filename == "" {
+		fatal(errors.New("usage: cadencefmt bundle <file.json>"))
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		fatal(err)
+	}
+
+	codes, err := codeStrings(data)
+	if err != nil {
+		fatal(fmt.Errorf("%s: %w", filename, err))
+	}
+
+	var result bytes.Buffer
+	code := exitOK
+	next := 0
+	for _, codeString := range codes {
+		result.Write(data[next:codeString.start])
+		next = codeString.start
+
+		formatted, err := formatBundleCode(data[codeString.start:codeString.end], options)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s: %s\n", filename, codeString.name, err)
+			code = max(code, exitCode(err))
+			continue
+		}
+		result.Write(formatted)
+		next = codeString.end
+	}
+	result.Write(data[next:])
+
+	if !bytes.Equal(result.Bytes(), data) {
+		if err := writeFormatted(filename, data, result.Bytes(), ""); err != nil {
+			fatal(err)
+		}
+	}
+
+	os.Exit(code)
+}
+
+// formatBundleCode formats the code of the JSON string, and returns it as a JSON string
+func formatBundleCode(value json.RawMessage, options format.Options) (json.RawMessage, error) {
+	var code string
+	if err := json.Unmarshal(value, &code); err != nil {
+		return nil, err
+	}
+	formatted, err := verifiedSource(context.Background(), code, options)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasSuffix(code, "\n") && !strings.HasSuffix(formatted, "\n") {
+		formatted += "\n"
+	}
+	//unchanged code keeps the escapes it was written with
+	if formatted == code {
+		return value, nil
+	}
+	return marshalJSON(formatted)
+}
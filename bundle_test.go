@@ -0,0 +1,54 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package main
+
+import (
+	"testing"
+)
+
+func TestCodeStrings(t *testing.T) {
+	data := []byte(`{
+    "A":   "pub fun a(){}\n",
+    "meta": {"version": 1, "tags": ["x",  "y"]},
+    "B": {"code": "pub fun b( ) {}", "path":"b.cdc"},
+    "C": {"code": {"code": "pub fun c() {}"}},
+    "D": 1
+}`)
+
+	codes, err := codeStrings(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []struct {
+		name string
+		text string
+	}{
+		{"A", `"pub fun a(){}\n"`},
+		{"B", `"pub fun b( ) {}"`},
+		{"C", `"pub fun c() {}"`},
+	}
+	if len(codes) != len(expected) {
+		t.Fatalf("expected %d code strings, got %v", len(expected), codes)
+	}
+	for i, code := range codes {
+		if code.name != expected[i].name || string(data[code.start:code.end]) != expected[i].text {
+			t.Errorf("expected %s at %s, got %s at %s", expected[i].name, expected[i].text, code.name, data[code.start:code.end])
+		}
+	}
+}
@@ -0,0 +1,104 @@
+//go:build !noui
+
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"sync"
+
+	"cadencefmt/format"
+)
+
+// formatCache keeps the results of the most recently formatted code,
+// so the same code, e.g. sent again by the web UI, is not parsed again
+type formatCache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[formatCacheKey]*list.Element
+}
+
+type formatCacheKey struct {
+	code    [sha256.Size]byte
+	options format.Options
+}
+
+type formatCacheEntry struct {
+	key    formatCacheKey
+	result string
+	err    error
+}
+
+// newFormatCache returns a cache of the given number of results,
+// or nil if the size is zero, which formats without caching
+func newFormatCache(size int) *formatCache {
+	if size <= 0 {
+		return nil
+	}
+	return &formatCache{
+		size:    size,
+		order:   list.New(),
+		entries: map[formatCacheKey]*list.Element{},
+	}
+}
+
+// format formats the code and checks the result, see verifiedSource,
+// or returns the cached result
+func (c *formatCache) format(ctx context.Context, code string, options format.Options) (string, error) {
+	if c == nil {
+		return verifiedSource(ctx, code, options)
+	}
+
+	key := formatCacheKey{
+		code:    sha256.Sum256([]byte(code)),
+		options: options,
+	}
+
+	c.mu.Lock()
+	if element, ok := c.entries[key]; ok {
+		c.order.MoveToFront(element)
+		entry := element.Value.(*formatCacheEntry)
+		c.mu.Unlock()
+		return entry.result, entry.err
+	}
+	c.mu.Unlock()
+
+	result, err := verifiedSource(ctx, code, options)
+	//the code did not fail, the request was cancelled or timed out
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return result, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; !ok {
+		c.entries[key] = c.order.PushFront(&formatCacheEntry{key: key, result: result, err: err})
+		if c.order.Len() > c.size {
+			oldest := c.order.Back()
+			c.order.Remove(oldest)
+			delete(c.entries, oldest.Value.(*formatCacheEntry).key)
+		}
+	}
+	return result, err
+}
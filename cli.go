@@ -0,0 +1,671 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/onflow/cadence/runtime/parser"
+
+	"cadencefmt/format"
+)
+
+// cliOptions are the settings of a command line run
+type cliOptions struct {
+	format.Options
+	budget int
+	// lines restricts formatting to the declarations touching these lines
+	lines []format.LineRange
+	// diffBase restricts formatting to the lines changed since this git ref
+	diffBase string
+	write    bool
+	// dryRun only previews the changes of each file, instead of printing or writing the results
+	dryRun bool
+	// check only reports files which are not formatted
+	check bool
+	// verifyTokens also checks that no tokens are lost,
+	// besides the checks of every run
+	verifyTokens bool
+	// skipASTCheck skips comparing the syntax trees of the code and the result
+	skipASTCheck bool
+	// jsonOutput prints a JSON report for each file instead of the results
+	jsonOutput bool
+	// edits prints the edits turning each file into its result, see textEdits
+	edits bool
+	// baseline lists the files check mode allows to stay unformatted, if any
+	baseline *baseline
+	// postProcessors run on the final text
+	postProcessors []postProcessor
+	// widthExceptions match text which is exempt from width warnings
+	widthExceptions []*regexp.Regexp
+	// migrate rewrites the formatted code to Cadence 1.0, see format.Migrate
+	migrate bool
+	// finalNewline ends the results with a line break, otherwise they end without one
+	finalNewline bool
+	// whitespaceOnly only cleans up the whitespace of the code, see cleanWhitespace
+	whitespaceOnly bool
+	// compact prints the code with minimal whitespace and without comments, see format.Compact
+	compact bool
+	// maxSize refuses files larger than this many bytes, 0 for no limit
+	maxSize int64
+	// backupSuffix keeps the original of each file written in place, in the file with this suffix
+	backupSuffix string
+	// quiet only prints errors, and verbose also prints each file as it is formatted
+	quiet   bool
+	verbose bool
+	// assumeFilename is the name of the file the code on stdin is formatted as
+	assumeFilename string
+}
+
+// stdinFilename stands for the code on stdin in the list of files
+const stdinFilename = "-"
+
+// displayName is the name of the file in diagnostics and reports,
+// for stdin the assumed filename, if any
+func (o cliOptions) displayName(filename string) string {
+	if filename != stdinFilename {
+		return filename
+	}
+	if o.assumeFilename != "" {
+		return o.assumeFilename
+	}
+	return "<stdin>"
+}
+
+// optionFlags are the flags shared by all commands which format code
+type optionFlags struct {
+	columns            *int
+	tabs               *bool
+	comments           *string
+	emptyBodies        *string
+	commentStyle       *string
+	parameterWrap      *string
+	returnType         *string
+	enumCases          *string
+	operatorPosition   *string
+	braces             *string
+	lineEndings        *string
+	groupFields        *bool
+	alignComments      *bool
+	alignValues        *bool
+	alignParameters    *bool
+	reflowDocs         *bool
+	maxBlankLines      *optionalInt
+	sortImports        *bool
+	cleanImports       *bool
+	normalizeEscapes   *bool
+	importGroups       *string
+	trailingCommas     *bool
+	oneLineFunctions   *bool
+	oneLineCases       *bool
+	exactBlankLines    *bool
+	reorderMembers     *bool
+	keepBOM            *bool
+	finalNewline       *bool
+	collectionElements *int
+	collectionWidth    *int
+	cadenceVersion     *string
+	config             *string
+	allowExec          *bool
+
+	//configDir is where the nearest configuration is searched, the working directory if empty
+	configDir string
+}
+
+func addOptionFlags(flags *flag.FlagSet) *optionFlags {
+	return &optionFlags{
+		columns:            flags.Int("c", 0, "columns (default 80)"),
+		tabs:               flags.Bool("t", false, "tabs"),
+		comments:           flags.String("comments", "", "comment strategy, re-anchor (default) or strict"),
+		groupFields:        flags.Bool("group-fields", false, "separate fields only between groups of access levels"),
+		alignComments:      flags.Bool("align-comments", false, "align the trailing comments of consecutive lines"),
+		alignValues:        flags.Bool("align-values", false, "align the values of broken dictionary literals and the = of consecutive assignments"),
+		alignParameters:    flags.Bool("align-parameters", false, "align the types of broken parameter lists with a parameter on each line"),
+		reflowDocs:         flags.Bool("reflow-docs", false, "re-wrap the paragraphs of doc comments to the columns"),
+		maxBlankLines:      optionalIntFlag(flags, "max-blank-lines", "maximum `number` of consecutive blank lines kept (default 1)"),
+		sortImports:        flags.Bool("sort-imports", false, "sort the imports at the top of the code alphabetically"),
+		normalizeEscapes:   flags.Bool("normalize-escapes", false, "write the \\u{...} escapes of strings with upper case digits and without leading zeros"),
+		cleanImports:       flags.Bool("clean-imports", false, "remove duplicate imports, and imports of names the code never references"),
+		trailingCommas:     flags.Bool("trailing-commas", false, "add a comma after the last parameter or argument of broken lists"),
+		exactBlankLines:    flags.Bool("exact-blank-lines", false, "separate declarations and members with exactly one blank line"),
+		reorderMembers:     flags.Bool("reorder-members", false, "order the members of composites: events, fields, init, public functions, other functions"),
+		oneLineFunctions:   flags.Bool("one-line-functions", false, "keep functions with a single simple statement on one line if they fit"),
+		oneLineCases:       flags.Bool("one-line-cases", false, "keep switch cases with a single simple statement on one line if they fit, aligned"),
+		keepBOM:            flags.Bool("keep-bom", false, "keep the byte order mark at the start of files, which is removed otherwise"),
+		finalNewline:       flags.Bool("final-newline", true, "end files with exactly one line break, -final-newline=false ends them without one"),
+		collectionElements: flags.Int("collection-elements", 0, "break array and dictionary literals with more elements, one per line"),
+		collectionWidth:    flags.Int("collection-width", 0, "break array and dictionary literals wider than this on one line, one element per line"),
+		importGroups:       flags.String("import-groups", "", "order the imports by group, separated by blank lines, e.g. "+format.DefaultImportGroups),
+		emptyBodies:        flags.String("empty-bodies", "", "empty function bodies, compact {} (default), spaced { } or split"),
+		commentStyle:       flags.String("comment-style", "", "convert comments, keep (default), line for single line /* */ comments to //, doc for doc comments to ///, or doc-block for doc comments to /** */"),
+		parameterWrap:      flags.String("parameter-wrap", "", "parameters of signatures which do not fit, separate lines (default) or packed to the columns"),
+		returnType:         flags.String("return-type", "", "return type of signatures which do not fit, same-line (default) as the closing parenthesis or own-line"),
+		enumCases:          flags.String("enum-cases", "", "cases of enums, separate lines (default) or packed to the columns"),
+		operatorPosition:   flags.String("operator-position", "", "operators of broken chains of && and ||, leading (default) the continuation lines or trailing the broken ones"),
+		braces:             flags.String("braces", "", "opening braces of composites and functions, same-line (default) or next-line"),
+		lineEndings:        flags.String("line-endings", "", "line endings, auto (default) keeps the ending of most lines, lf or crlf"),
+		cadenceVersion:     flags.String("cadence-version", "", "Cadence release of the code, e.g. 0.40, or its syntax, legacy (before 1.0) or current, fails if this build has the parser of another release"),
+		config:             flags.String("config", "", "configuration file (default: nearest "+configFilename+")"),
+		allowExec:          flags.Bool("allow-exec", false, "run the exec post-processors of the configuration, which run commands"),
+	}
+}
+
+// optionalInt is a flag of a number which is not negative,
+// which keeps the value of the configuration when it is not set
+type optionalInt struct {
+	value int
+	set   bool
+}
+
+func optionalIntFlag(flags *flag.FlagSet, name string, usage string) *optionalInt {
+	value := &optionalInt{}
+	flags.Var(value, name, usage)
+	return value
+}
+
+func (i *optionalInt) String() string {
+	if i == nil || !i.set {
+		return ""
+	}
+	return strconv.Itoa(i.value)
+}
+
+func (i *optionalInt) Set(s string) error {
+	value, err := strconv.Atoi(s)
+	if err != nil {
+		return err
+	}
+	if value < 0 {
+		return errors.New("must not be negative")
+	}
+	i.value, i.set = value, true
+	return nil
+}
+
+// cliOptions returns the options set by the flags and the configuration file
+func (f *optionFlags) cliOptions() (cliOptions, error) {
+	cfg, err := loadConfigFrom(*f.config, f.configDir)
+	if err != nil {
+		return cliOptions{}, err
+	}
+
+	//a configuration found in a checkout must not run the commands of whoever committed it
+	if !*f.allowExec && hasExecPostProcessor(cfg.PostProcessors) {
+		if *f.config != "" {
+			return cliOptions{}, fmt.Errorf("%s: exec post-processors run commands, which requires -allow-exec", *f.config)
+		}
+		fmt.Fprintln(os.Stderr, "warning: ignoring the exec post-processors of the configuration, -allow-exec runs them")
+		cfg.PostProcessors = withoutExecPostProcessors(cfg.PostProcessors)
+	}
+
+	if *f.columns > 0 {
+		cfg.MaxLineLength = *f.columns
+	}
+	cfg.Tabs = cfg.Tabs || *f.tabs
+	cfg.GroupFields = cfg.GroupFields || *f.groupFields
+	cfg.AlignComments = cfg.AlignComments || *f.alignComments
+	cfg.AlignValues = cfg.AlignValues || *f.alignValues
+	cfg.AlignParameters = cfg.AlignParameters || *f.alignParameters
+	cfg.ReflowDocs = cfg.ReflowDocs || *f.reflowDocs
+	cfg.SortImports = cfg.SortImports || *f.sortImports
+	cfg.CleanImports = cfg.CleanImports || *f.cleanImports
+	cfg.NormalizeEscapes = cfg.NormalizeEscapes || *f.normalizeEscapes
+	cfg.TrailingCommas = cfg.TrailingCommas || *f.trailingCommas
+	cfg.OneLineFunctions = cfg.OneLineFunctions || *f.oneLineFunctions
+	cfg.OneLineCases = cfg.OneLineCases || *f.oneLineCases
+	cfg.ExactBlankLines = cfg.ExactBlankLines || *f.exactBlankLines
+	cfg.ReorderMembers = cfg.ReorderMembers || *f.reorderMembers
+	cfg.KeepBOM = cfg.KeepBOM || *f.keepBOM
+	if *f.collectionElements > 0 {
+		cfg.CollectionElements = *f.collectionElements
+	}
+	if *f.collectionWidth > 0 {
+		cfg.CollectionWidth = *f.collectionWidth
+	}
+	if f.maxBlankLines.set {
+		cfg.MaxBlankLines = &f.maxBlankLines.value
+	}
+	if !*f.finalNewline {
+		cfg.FinalNewline = f.finalNewline
+	}
+	cfg.Comments = firstNonEmpty(*f.comments, cfg.Comments)
+	cfg.EmptyBodies = firstNonEmpty(*f.emptyBodies, cfg.EmptyBodies)
+	cfg.CommentStyle = firstNonEmpty(*f.commentStyle, cfg.CommentStyle)
+	cfg.ParameterWrap = firstNonEmpty(*f.parameterWrap, cfg.ParameterWrap)
+	cfg.ReturnType = firstNonEmpty(*f.returnType, cfg.ReturnType)
+	cfg.EnumCases = firstNonEmpty(*f.enumCases, cfg.EnumCases)
+	cfg.OperatorPosition = firstNonEmpty(*f.operatorPosition, cfg.OperatorPosition)
+	cfg.Braces = firstNonEmpty(*f.braces, cfg.Braces)
+	cfg.LineEndings = firstNonEmpty(*f.lineEndings, cfg.LineEndings)
+	if *f.importGroups != "" {
+		cfg.ImportGroups = strings.Split(*f.importGroups, ",")
+	}
+
+	if err := checkCadenceVersion(firstNonEmpty(*f.cadenceVersion, cfg.CadenceVersion)); err != nil {
+		return cliOptions{}, err
+	}
+
+	var options cliOptions
+	options.Options, err = cfg.Options()
+	if err != nil {
+		return cliOptions{}, err
+	}
+	options.finalNewline = cfg.FinalNewline == nil || *cfg.FinalNewline
+
+	options.postProcessors, err = newPostProcessors(cfg.PostProcessors)
+	if err != nil {
+		return cliOptions{}, err
+	}
+
+	options.widthExceptions, err = compileWidthExceptions(cfg.WidthExceptions)
+	if err != nil {
+		return cliOptions{}, err
+	}
+
+	return options, nil
+}
+
+// errNotFormatted is reported in check mode
+// for files which are not formatted or are over budget
+var errNotFormatted = errors.New("not formatted")
+
+// errParseFailed is reported for files with parse errors,
+// after printing them
+var errParseFailed = errors.New("parse failed")
+
+// The exit codes of formatting files, which scripts can tell apart
+const (
+	// exitOK is for files which are formatted, or were formatted
+	exitOK = 0
+	// exitNotFormatted is for files check mode, the hook and the corpus tests report as not formatted
+	exitNotFormatted = 1
+	// exitError is for files which do not parse, errors reading or writing files, and invalid options
+	exitError = 2
+	// exitInternal is for results which fail the safety checks, and crashes of the formatter
+	exitInternal = 3
+)
+
+// exitCode returns the exit code for the error which stopped the run
+func exitCode(err error) int {
+	var safetyErr *format.SafetyError
+	if errors.As(err, &safetyErr) {
+		return exitInternal
+	}
+	return exitError
+}
+
+// fatal logs the error which stopped the run, and exits with its exit code
+func fatal(err error) {
+	log.Print(err)
+	os.Exit(exitCode(err))
+}
+
+// fatalInternal logs the failure of cadencefmt itself, not of its input, and exits with exitInternal
+func fatalInternal(err error) {
+	log.Print(err)
+	os.Exit(exitInternal)
+}
+
+// checkSize refuses files larger than the limit, as the time and memory to format them
+// grow faster than their size. A limit of zero allows any size
+func checkSize(filename string, limit int64) error {
+	if limit <= 0 {
+		return nil
+	}
+	info, err := os.Stat(filename)
+	if err != nil {
+		return err
+	}
+	if info.Size() > limit {
+		return fmt.Errorf("%s: %d bytes is larger than -max-size of %d bytes, use -force to format it anyway", filename, info.Size(), limit)
+	}
+	return nil
+}
+
+// readCode reads the file, or stdin, refusing code larger than the limit
+func readCode(filename string, limit int64) ([]byte, error) {
+	if filename != stdinFilename {
+		if err := checkSize(filename, limit); err != nil {
+			return nil, err
+		}
+		return os.ReadFile(filename)
+	}
+
+	if limit <= 0 {
+		return io.ReadAll(os.Stdin)
+	}
+	code, err := io.ReadAll(io.LimitReader(os.Stdin, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(code)) > limit {
+		return nil, fmt.Errorf("stdin is larger than -max-size of %d bytes, use -force to format it anyway", limit)
+	}
+	return code, nil
+}
+
+// formatSource formats the code, restricted to the given lines, if any,
+// and applies the post-processors
+func formatSource(code string, lines []format.LineRange, options cliOptions) (string, error) {
+	if options.whitespaceOnly {
+		return cleanWhitespace(code, options), nil
+	}
+	if options.compact {
+		result, err := format.Compact(code)
+		if err != nil {
+			return "", err
+		}
+		return finalNewline(result, options.finalNewline), nil
+	}
+
+	var result string
+	var err error
+	if lines != nil {
+		result, err = format.Lines(code, options.Options, lines, checkSpan(options))
+	} else {
+		result, err = formatVerified(code, options)
+		//empty files stay empty
+		if err == nil && result != "" {
+			result = withLineEndings(result+"\n", code, options.Options)
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	if options.migrate {
+		result, err = format.Migrate(result)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	result, err = postProcess(result, options.postProcessors)
+	if err != nil || lines != nil {
+		return result, err
+	}
+	return finalNewline(result, options.finalNewline), nil
+}
+
+// finalNewline ends the result with exactly one line break, or none,
+// unless it is empty
+func finalNewline(result string, newline bool) string {
+	ending := "\n"
+	if strings.Contains(result, "\r\n") {
+		ending = "\r\n"
+	}
+	result = strings.TrimRight(result, " \t\r\n")
+	if result == "" || !newline {
+		return result
+	}
+	return result + ending
+}
+
+// withLineEndings converts the line endings of the result,
+// which ends with a line break added after formatting
+func withLineEndings(result string, code string, options format.Options) string {
+	//the header comment of the file may set other line endings
+	fileOptions, err := format.FileOptions(code, options)
+	if err != nil {
+		return result
+	}
+	return fileOptions.LineEndings.Convert(result, code)
+}
+
+// formatPartial formats the declarations of the code which parse, keeps the broken ones as written,
+// and applies the post-processors
+func formatPartial(code string, options cliOptions) (string, []format.BrokenDeclaration, error) {
+	result, broken, err := format.Partial(code, options.Options, func(code, formatted string) error {
+		return checkFormatted(code, formatted, options)
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	if options.migrate {
+		//the broken declarations do not parse, so they are not migrated
+		if migrated, err := format.Migrate(result); err == nil {
+			result = migrated
+		}
+	}
+
+	result, err = postProcess(withLineEndings(result+"\n", code, options.Options), options.postProcessors)
+	if err != nil {
+		return "", nil, err
+	}
+	return finalNewline(result, options.finalNewline), broken, nil
+}
+
+// formatFile formats the given file,
+// and prints the result, writes it back to the file,
+// or only checks if it is formatted.
+// With JSON output, the report of the file is printed instead of the result
+func formatFile(filename string, options cliOptions, stdout, stderr io.Writer) (report *fileReport, err error) {
+	source := filename
+	filename = options.displayName(source)
+	report = newFileReport(filename, options.jsonOutput, options.quiet, stderr)
+	defer func() {
+		if options.jsonOutput && (err == nil || errors.Is(err, errNotFormatted) || errors.Is(err, errParseFailed)) {
+			if writeErr := report.write(stdout); writeErr != nil {
+				err = writeErr
+			}
+		}
+		if options.verbose && !options.jsonOutput && (err == nil || errors.Is(err, errNotFormatted)) {
+			fmt.Fprintln(stderr, report.progress())
+		}
+	}()
+	//a crash of the formatter is reported like a failed safety check,
+	//before the deferred report sees the error
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s: %w", filename, &format.SafetyError{Check: "panic", Message: fmt.Sprint(r)})
+		}
+	}()
+
+	code, err := readCode(source, options.maxSize)
+	if err != nil {
+		return report, err
+	}
+
+	if formatEmbedded := embeddedFormatter(filename); formatEmbedded != nil {
+		//the code blocks are formatted with \n line endings, which are converted at the end
+		result, err := formatEmbedded(strings.ReplaceAll(string(code), "\r\n", "\n"), options)
+		if err != nil {
+			return report, fmt.Errorf("%s: %w", filename, err)
+		}
+		result = options.LineEndings.Convert(result, string(code))
+		report.Changed = result != string(code)
+		return report, outputResult(report, string(code), result, options, stdout)
+	}
+
+	lines := options.lines
+	if options.diffBase != "" {
+		lines, err = changedLines(options.diffBase, filename)
+		if err != nil {
+			return report, err
+		}
+		if lines == nil {
+			lines = []format.LineRange{}
+		}
+	}
+
+	result, err := formatSource(string(code), lines, options)
+	var broken []format.BrokenDeclaration
+	var parseErr parser.Error
+	if errors.As(err, &parseErr) && lines == nil && !options.compact {
+		//format the declarations which parse, and keep the broken ones
+		result, broken, err = formatPartial(string(code), options)
+	}
+	if diagnostics, ok := parseDiagnostics(err, ""); ok {
+		report.add(diagnostics...)
+		return report, errParseFailed
+	}
+	if err != nil {
+		return report, fmt.Errorf("%s: %w", filename, err)
+	}
+	for _, declaration := range broken {
+		note := fmt.Sprintf(" (lines %d-%d are kept as written)", declaration.StartLine, declaration.EndLine)
+		diagnostics, _ := parseDiagnostics(declaration.Err, note)
+		report.add(diagnostics...)
+	}
+
+	report.Changed = result != string(code)
+	if report.Changed {
+		report.LinesChanged = editDistance(strings.Split(string(code), "\n"), strings.Split(result, "\n"))
+	}
+	err = outputResult(report, string(code), result, options, stdout)
+	if len(broken) > 0 {
+		return report, errors.Join(errParseFailed, err)
+	}
+	return report, err
+}
+
+// outputResult prints the formatted code, writes it to the file,
+// or only checks if the code is formatted
+func outputResult(report *fileReport, code string, result string, options cliOptions, stdout io.Writer) error {
+	filename := report.File
+
+	overBudget := false
+	if options.budget > 0 {
+		size := measureSize(result, options.budget)
+		report.sizeReport = &size
+		overBudget = size.OverBudget()
+		if overBudget {
+			report.add(diagnostic{Severity: severityWarning, Message: size.String()})
+		} else {
+			report.add(diagnostic{Severity: severityInfo, Message: size.String()})
+		}
+	}
+
+	switch {
+	case options.check:
+		//the header comment of the file may set another width
+		fileOptions, err := format.FileOptions(code, options.Options)
+		if err != nil {
+			return fmt.Errorf("%s: %w", filename, err)
+		}
+		for _, wide := range wideLines(result, fileOptions.MaxLineLength, options.widthExceptions) {
+			report.add(diagnostic{
+				Severity: severityWarning,
+				Line:     wide.Line,
+				Message:  fmt.Sprintf("%d columns, exceeds %d", wide.Width, fileOptions.MaxLineLength),
+			})
+		}
+		if report.Changed &&
+			(options.baseline == nil || !options.baseline.allows(filename, code, result, report)) {
+
+			if !options.jsonOutput && !options.quiet {
+				fmt.Fprintln(stdout, filename)
+			}
+			return errNotFormatted
+		}
+		if overBudget {
+			return errNotFormatted
+		}
+		return nil
+
+	case options.dryRun:
+		if report.Changed && !options.jsonOutput {
+			fmt.Fprint(stdout, dryRunPreview(filename, code, result, report.LinesChanged))
+		}
+		return nil
+
+	case options.write:
+		if !report.Changed {
+			return nil
+		}
+		return writeFormatted(filename, []byte(code), []byte(result), options.backupSuffix)
+
+	case options.edits:
+		return json.NewEncoder(stdout).Encode(fileEdits{File: filename, Edits: textEdits(code, result)})
+
+	case options.jsonOutput:
+		return nil
+	}
+
+	//the result ends as -final-newline says
+	fmt.Fprint(stdout, result)
+	return nil
+}
+
+// fileRun is the outcome of formatting a file
+type fileRun struct {
+	stdout bytes.Buffer
+	stderr bytes.Buffer
+	report *fileReport
+	err    error
+	done   chan struct{}
+}
+
+// formatFiles formats the files concurrently, using the given number of jobs,
+// and prints the results in order as soon as they are available.
+// Files which cannot be formatted are logged, and the others are still formatted;
+// if there were any, a summary of the run is printed at the end.
+// It returns the statistics of the run
+func formatFiles(filenames []string, options cliOptions, jobs int) (stats runStats) {
+	start := time.Now()
+	runs := make([]*fileRun, len(filenames))
+	for i := range runs {
+		runs[i] = &fileRun{done: make(chan struct{})}
+	}
+
+	work := make(chan int)
+	go func() {
+		defer close(work)
+		for i := range filenames {
+			work <- i
+		}
+	}()
+
+	for job := 0; job < max(jobs, 1); job++ {
+		go func() {
+			for i := range work {
+				run := runs[i]
+				run.report, run.err = formatFile(filenames[i], options, &run.stdout, &run.stderr)
+				close(run.done)
+			}
+		}()
+	}
+
+	for _, run := range runs {
+		<-run.done
+		_, _ = os.Stdout.Write(run.stdout.Bytes())
+		_, _ = os.Stderr.Write(run.stderr.Bytes())
+
+		if run.err != nil && !errors.Is(run.err, errNotFormatted) && !errors.Is(run.err, errParseFailed) {
+			log.Print(run.err)
+		}
+		stats.add(run.report, run.err)
+	}
+
+	stats.duration = time.Since(start)
+	if len(stats.failures) > 0 {
+		fmt.Fprint(os.Stderr, stats.summary())
+	}
+	return stats
+}
@@ -0,0 +1,239 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package client calls the HTTP API of a cadencefmt server,
+// so services can format code remotely without building the requests themselves.
+// The API is described in OpenAPI format at /openapi.json
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Options are the formatting options of a request,
+// the server uses its defaults, or the ones of the profile, for the ones not given
+type Options struct {
+	MaxLineLength      int    `json:"maxLineLength"`
+	Tabs               bool   `json:"tabs"`
+	Comments           string `json:"comments,omitempty"`
+	EmptyBodies        string `json:"emptyBodies,omitempty"`
+	CommentStyle       string `json:"commentStyle,omitempty"`
+	ParameterWrap      string `json:"parameterWrap,omitempty"`
+	ReturnType         string `json:"returnType,omitempty"`
+	EnumCases          string `json:"enumCases,omitempty"`
+	OperatorPosition   string `json:"operatorPosition,omitempty"`
+	Braces             string `json:"braces,omitempty"`
+	LineEndings        string `json:"lineEndings,omitempty"`
+	GroupFields        bool   `json:"groupFields,omitempty"`
+	AlignComments      bool   `json:"alignComments,omitempty"`
+	AlignValues        bool   `json:"alignValues,omitempty"`
+	AlignParameters    bool   `json:"alignParameters,omitempty"`
+	ReflowDocs         bool   `json:"reflowDocs,omitempty"`
+	MaxBlankLines      *int   `json:"maxBlankLines,omitempty"`
+	SortImports        bool   `json:"sortImports,omitempty"`
+	CleanImports       bool   `json:"cleanImports,omitempty"`
+	NormalizeEscapes   bool   `json:"normalizeEscapes,omitempty"`
+	TrailingCommas     bool   `json:"trailingCommas,omitempty"`
+	OneLineFunctions   bool   `json:"oneLineFunctions,omitempty"`
+	OneLineCases       bool   `json:"oneLineCases,omitempty"`
+	ExactBlankLines    bool   `json:"exactBlankLines,omitempty"`
+	ReorderMembers     bool   `json:"reorderMembers,omitempty"`
+	KeepBOM            bool   `json:"keepBOM,omitempty"`
+	CollectionElements int    `json:"collectionElements,omitempty"`
+	CollectionWidth    int    `json:"collectionWidth,omitempty"`
+	// ImportGroups and CoreContracts replace the ones of the profile
+	ImportGroups  []string `json:"importGroups,omitempty"`
+	CoreContracts []string `json:"coreContracts,omitempty"`
+	// Rules enable or disable layout rules by name,
+	// over the ones of the profile
+	Rules map[string]bool `json:"rules,omitempty"`
+	// Style enables or disables style rules by name,
+	// over the ones of the profile
+	Style map[string]bool `json:"style,omitempty"`
+	// Profile is the name of a server side configuration,
+	// which provides the options not given in the request
+	Profile string `json:"profile,omitempty"`
+}
+
+// PrettyRequest is the body of /pretty
+type PrettyRequest struct {
+	Code string `json:"code"`
+	Options
+}
+
+// PrettyResponse is the formatted code,
+// with the display width of each line
+type PrettyResponse struct {
+	Code  string         `json:"code"`
+	Lines []LineMetadata `json:"lines"`
+}
+
+type LineMetadata struct {
+	// Width is the number of display columns
+	Width    int  `json:"width"`
+	Overflow bool `json:"overflow"`
+}
+
+// BatchRequest is the body of /pretty/batch,
+// the options apply to all entries
+type BatchRequest struct {
+	Options
+	Entries []BatchEntry `json:"entries"`
+}
+
+type BatchEntry struct {
+	Path string `json:"path"`
+	Code string `json:"code"`
+}
+
+// BatchResult is the formatted code of an entry,
+// or the error if it could not be formatted
+type BatchResult struct {
+	Path string `json:"path"`
+	Code string `json:"code"`
+	*ErrorResponse
+}
+
+type BatchResponse struct {
+	Results []BatchResult `json:"results"`
+}
+
+// ASTRequest is the body of /ast
+type ASTRequest struct {
+	Code string `json:"code"`
+}
+
+// ErrorResponse is the body of a failed request
+type ErrorResponse struct {
+	Error string `json:"error"`
+	// Errors are the individual parse errors, if the code does not parse
+	Errors []ParseError `json:"errors,omitempty"`
+}
+
+// ParseError is a parse error and its position,
+// with lines starting at 1 and columns at 0
+type ParseError struct {
+	Message   string `json:"message"`
+	Line      int    `json:"line"`
+	Column    int    `json:"column"`
+	EndLine   int    `json:"endLine"`
+	EndColumn int    `json:"endColumn"`
+}
+
+// Error is the error of a request the server did not answer with success,
+// e.g. status 422 if the code does not parse
+type Error struct {
+	StatusCode int
+	ErrorResponse
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("cadencefmt: status %d: %s", e.StatusCode, e.ErrorResponse.Error)
+}
+
+// Client calls the API of a server
+type Client struct {
+	// BaseURL is the URL of the server, e.g. http://127.0.0.1:9090
+	BaseURL string
+	// APIKey is sent as bearer token, if the server requires one
+	APIKey string
+	// HTTPClient sends the requests, http.DefaultClient if nil
+	HTTPClient *http.Client
+}
+
+// New returns a client of the server at the URL
+func New(baseURL string) *Client {
+	return &Client{BaseURL: baseURL}
+}
+
+// Pretty formats the code
+func (c *Client) Pretty(ctx context.Context, request PrettyRequest) (*PrettyResponse, error) {
+	var response PrettyResponse
+	if err := c.post(ctx, "/pretty", request, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// Batch formats many files in a single request.
+// Entries which cannot be formatted have the error in their results,
+// the request fails only if the batch as a whole is rejected
+func (c *Client) Batch(ctx context.Context, request BatchRequest) (*BatchResponse, error) {
+	var response BatchResponse
+	if err := c.post(ctx, "/pretty/batch", request, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// AST returns the program parsed by Cadence, as JSON
+func (c *Client) AST(ctx context.Context, code string) (json.RawMessage, error) {
+	var response json.RawMessage
+	if err := c.post(ctx, "/ast", ASTRequest{Code: code}, &response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// post sends the body as JSON and decodes the JSON response,
+// or returns an *Error if the status is not 200
+func (c *Client) post(ctx context.Context, path string, body any, response any) error {
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(c.BaseURL, "/")+path, bytes.NewReader(encoded))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", "application/json")
+	if c.APIKey != "" {
+		request.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	result, err := httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer result.Body.Close()
+
+	if result.StatusCode != http.StatusOK {
+		apiErr := &Error{StatusCode: result.StatusCode}
+		data, _ := io.ReadAll(result.Body)
+		//responses not from the server, e.g. of a proxy, may not be JSON
+		if json.Unmarshal(data, &apiErr.ErrorResponse) != nil || apiErr.ErrorResponse.Error == "" {
+			apiErr.ErrorResponse.Error = strings.TrimSpace(string(data))
+			if apiErr.ErrorResponse.Error == "" {
+				apiErr.ErrorResponse.Error = http.StatusText(result.StatusCode)
+			}
+		}
+		return apiErr
+	}
+	return json.NewDecoder(result.Body).Decode(response)
+}
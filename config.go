@@ -0,0 +1,125 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"cadencefmt/format"
+)
+
+const configFilename = ".cadencefmt.json"
+
+// config is the contents of a configuration file,
+// the formatting options and the settings of the command line
+type config struct {
+	format.Config
+	PostProcessors []postProcessorConfig `json:"postProcessors,omitempty"`
+	// WidthExceptions are patterns of text which never counts
+	// towards the line width in check mode
+	WidthExceptions []string `json:"widthExceptions,omitempty"`
+	// CadenceVersion is the Cadence release of the code, e.g. "0.40", or its syntax, "legacy" or "current"
+	CadenceVersion string `json:"cadenceVersion,omitempty"`
+	// FinalNewline ends files with exactly one line break, true if not given
+	FinalNewline *bool `json:"finalNewline,omitempty"`
+}
+
+// loadConfig reads the configuration file at the given path.
+// If the path is empty, the nearest configuration file
+// in the current directory or its parents is used, if any
+func loadConfig(path string) (config, error) {
+	return loadConfigFrom(path, "")
+}
+
+// loadConfigFrom reads the configuration file at path, or the nearest one to dir
+// (the working directory if empty) when path is empty
+func loadConfigFrom(path string, dir string) (config, error) {
+	if path == "" {
+		var err error
+		if dir == "" {
+			dir, err = os.Getwd()
+			if err != nil {
+				return config{}, err
+			}
+		}
+		path, err = findConfig(dir)
+		if err != nil || path == "" {
+			return config{}, err
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config{}, err
+	}
+
+	var c config
+	if err := json.Unmarshal(data, &c); err != nil {
+		return config{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+// mergedRules returns the rules switched on or off by override over the ones of base,
+// without modifying base, which may be shared
+func mergedRules(base map[string]bool, override map[string]bool) map[string]bool {
+	if len(override) == 0 {
+		return base
+	}
+	rules := make(map[string]bool, len(base)+len(override))
+	for name, enabled := range base {
+		rules[name] = enabled
+	}
+	for name, enabled := range override {
+		rules[name] = enabled
+	}
+	return rules
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// findConfig returns the path of the nearest configuration file in the directory or its parents,
+// or an empty path if there is none
+func findConfig(dir string) (string, error) {
+	for {
+		path := filepath.Join(dir, configFilename)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
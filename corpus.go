@@ -0,0 +1,107 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// goldenExtension replaces the .cdc extension of a corpus file
+// for the file holding its expected formatted code
+const goldenExtension = ".golden"
+
+// runTestCorpus formats every file of the corpus and compares the result
+// to the stored golden file next to it, e.g. token.golden for token.cdc.
+// With -update, the golden files are written instead, to accept the current formatting
+func runTestCorpus(args []string) {
+	flags := flag.NewFlagSet("test-corpus", flag.ExitOnError)
+	optionFlags := addOptionFlags(flags)
+	updateFlag := flags.Bool("update", false, "write the golden files with the current formatting")
+	_ = flags.Parse(args)
+
+	options, err := optionFlags.cliOptions()
+	if err != nil {
+		fatal(err)
+	}
+
+	if flags.NArg() == 0 {
+		fatal(errors.New("usage: cadencefmt test-corpus [-update] <dir>..."))
+	}
+
+	filenames, err := cadenceFiles(flags.Args())
+	if err != nil {
+		fatal(err)
+	}
+
+	failures := 0
+	for _, filename := range filenames {
+		code, err := os.ReadFile(filename)
+		if err != nil {
+			fatal(err)
+		}
+		golden := goldenFilename(filename)
+
+		actual, err := formatSource(string(code), nil, options)
+		if err != nil {
+			fmt.Printf("FAIL %s: %s\n", filename, firstLine(err.Error()))
+			failures++
+			continue
+		}
+
+		if *updateFlag {
+			if err := os.WriteFile(golden, []byte(actual), 0644); err != nil {
+				fatal(err)
+			}
+			fmt.Printf("updated %s\n", golden)
+			continue
+		}
+
+		expected, err := os.ReadFile(golden)
+		if errors.Is(err, fs.ErrNotExist) {
+			fmt.Printf("FAIL %s: %s is missing, run with -update to create it\n", filename, golden)
+			failures++
+			continue
+		} else if err != nil {
+			fatal(err)
+		}
+
+		if line, ok := firstDifference(string(expected), actual); !ok {
+			fmt.Printf("FAIL %s: output differs from %s at line %d\n", filename, golden, line)
+			fmt.Printf("  expected: %q\n", lineAt(string(expected), line))
+			fmt.Printf("  actual:   %q\n", lineAt(actual, line))
+			failures++
+			continue
+		}
+		fmt.Printf("ok   %s\n", filename)
+	}
+
+	if failures > 0 {
+		fmt.Printf("%d of %d files failed\n", failures, len(filenames))
+		os.Exit(exitNotFormatted)
+	}
+}
+
+func goldenFilename(filename string) string {
+	return strings.TrimSuffix(filename, ".cdc") + goldenExtension
+}
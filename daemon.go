@@ -0,0 +1,245 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"io"
+	"log/slog"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"sync"
+	"time"
+
+	"cadencefmt/format"
+)
+
+// Formatter is the service exposed by the daemon.
+//
+// Requests are JSON-RPC calls of the form
+//
+//	{"method": "Formatter.Format", "params": [{"code": "...", "maxLineLength": 80}], "id": 1}
+type Formatter struct {
+	files *fileCache
+}
+
+type FormatArgs struct {
+	Code string `json:"code"`
+	RequestOptions
+	// Lines optionally restricts formatting to a line range, "from:to"
+	Lines string `json:"lines,omitempty"`
+	// Path optionally is the file of the code, whose previous result is updated
+	// by formatting only the edited declaration
+	Path string `json:"path,omitempty"`
+}
+
+type FormatReply struct {
+	Code string `json:"code"`
+}
+
+// FormatFileArgs are the file to format and the options
+type FormatFileArgs struct {
+	Path string `json:"path"`
+	RequestOptions
+}
+
+func (f *Formatter) Format(args FormatArgs, reply *FormatReply) error {
+	options, err := args.Options()
+	if err != nil {
+		return err
+	}
+
+	var result string
+	if args.Path != "" && args.Lines == "" {
+		result, err = f.files.format(args.Path, args.Code, nil, options)
+		if err != nil {
+			return err
+		}
+	} else if args.Lines != "" {
+		r, err := format.ParseLineRange(args.Lines)
+		if err != nil {
+			return err
+		}
+		result, err = format.Lines(args.Code, options, []format.LineRange{r}, checkSpan(cliOptions{Options: options}))
+		if err != nil {
+			return err
+		}
+	} else {
+		var err error
+		result, err = verifiedSource(context.Background(), args.Code, options)
+		if err != nil {
+			return err
+		}
+	}
+
+	reply.Code = result
+	return nil
+}
+
+// FormatFile formats the file as it is saved, e.g. for format on save.
+// The result is kept until the file or the options change, so unchanged files are answered at once
+func (f *Formatter) FormatFile(args FormatFileArgs, reply *FormatReply) error {
+	options, err := args.Options()
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(args.Path)
+	if err != nil {
+		return err
+	}
+	if result, ok := f.files.cached(args.Path, info, options); ok {
+		reply.Code = result
+		return nil
+	}
+
+	code, err := os.ReadFile(args.Path)
+	if err != nil {
+		return err
+	}
+	reply.Code, err = f.files.format(args.Path, string(code), info, options)
+	return err
+}
+
+// maxDaemonFiles is the number of files the daemon keeps the results of
+const maxDaemonFiles = 1000
+
+// fileCache keeps the last result of each file, by its path
+type fileCache struct {
+	mu      sync.Mutex
+	entries map[string]fileCacheEntry
+}
+
+type fileCacheEntry struct {
+	// modTime and size are of the file the result is of,
+	// zero if the code was sent instead
+	modTime   time.Time
+	size      int64
+	formatted format.Formatted
+}
+
+func newFileCache() *fileCache {
+	return &fileCache{entries: map[string]fileCacheEntry{}}
+}
+
+// cached returns the result of the file, if it did not change since
+func (c *fileCache) cached(path string, info os.FileInfo, options format.Options) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[path]
+	if !ok || entry.modTime.IsZero() || !entry.modTime.Equal(info.ModTime()) || entry.size != info.Size() ||
+		entry.formatted.Options != options {
+
+		return "", false
+	}
+	return entry.formatted.Result, true
+}
+
+// format formats the code of the file, only the edited declaration if it is an edit of the previous code,
+// and keeps the result
+// The file info is nil if the code was sent instead of read from the file
+func (c *fileCache) format(path string, code string, info os.FileInfo, options format.Options) (string, error) {
+	c.mu.Lock()
+	previous := c.entries[path].formatted
+	c.mu.Unlock()
+
+	result, ok := format.Reformat(previous, code, options)
+	var err error
+	if ok {
+		err = checkFormatted(code, result, cliOptions{Options: options})
+	} else {
+		result, err = verifiedSource(context.Background(), code, options)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[path]; !ok && len(c.entries) >= maxDaemonFiles {
+		//any file makes room, the daemon serves the files open in the editor
+		for other := range c.entries {
+			delete(c.entries, other)
+			break
+		}
+	}
+	entry := fileCacheEntry{formatted: format.Formatted{Code: code, Result: result, Options: options}}
+	if info != nil {
+		entry.modTime = info.ModTime()
+		entry.size = info.Size()
+	}
+	c.entries[path] = entry
+	return result, nil
+}
+
+// stdioConn joins stdin and stdout into a single connection
+type stdioConn struct {
+	io.Reader
+	io.Writer
+}
+
+func (stdioConn) Close() error {
+	return nil
+}
+
+// runDaemon keeps the process alive and serves format requests,
+// either over stdio or over a Unix socket,
+// so editors don't pay the startup cost on every save
+func runDaemon(args []string) {
+	flags := flag.NewFlagSet("daemon", flag.ExitOnError)
+	socketFlag := flags.String("socket", "", "listen on a Unix socket instead of stdio")
+	logFormatFlag := flags.String("log-format", "text", "log format, "+logFormats)
+	_ = flags.Parse(args)
+
+	if err := setLogFormat(*logFormatFlag); err != nil {
+		fatal(err)
+	}
+
+	server := rpc.NewServer()
+	if err := server.Register(&Formatter{files: newFileCache()}); err != nil {
+		fatalInternal(err)
+	}
+
+	if *socketFlag == "" {
+		server.ServeCodec(jsonrpc.NewServerCodec(stdioConn{os.Stdin, os.Stdout}))
+		return
+	}
+
+	ln, err := listenUnix(*socketFlag)
+	if err != nil {
+		fatal(err)
+	}
+	slog.Info("listening", "socket", ln.Addr().String())
+	closeOnSignal(ln)
+
+	for {
+		conn, err := ln.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			fatalInternal(err)
+		}
+		go server.ServeCodec(jsonrpc.NewServerCodec(conn))
+	}
+}
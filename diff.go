@@ -0,0 +1,198 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import "strings"
+
+// DiffOp is the operation of a line in a diff
+type DiffOp string
+
+const (
+	DiffEqual  DiffOp = "equal"
+	DiffInsert DiffOp = "insert"
+	DiffDelete DiffOp = "delete"
+)
+
+// DiffLine is a line of a diff,
+// deleted lines are from the old text, other lines from the new text
+type DiffLine struct {
+	Op   DiffOp `json:"op"`
+	Text string `json:"text"`
+}
+
+// maxDiffEdits limits the search of lineDiff,
+// which keeps the furthest points of each step to find the edits
+const maxDiffEdits = 2000
+
+// splitLines returns the lines of the text, without the empty line after the final newline
+func splitLines(text string) []string {
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// lineDiff returns the edits turning the lines a into the lines b.
+// If they differ in more than maxDiffEdits lines,
+// the lines between the common prefix and suffix are replaced as a whole
+func lineDiff(a, b []string) []DiffLine {
+	//the common prefix and suffix need no search
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix &&
+		a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+
+		suffix++
+	}
+
+	result := make([]DiffLine, 0, max(len(a), len(b)))
+	for _, line := range a[:prefix] {
+		result = append(result, DiffLine{Op: DiffEqual, Text: line})
+	}
+	result = append(result, middleDiff(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		result = append(result, DiffLine{Op: DiffEqual, Text: line})
+	}
+	return result
+}
+
+// middleDiff is the algorithm of Myers,
+// which records the furthest x on each diagonal of each step,
+// and walks back from the end through the recorded steps
+func middleDiff(a, b []string) []DiffLine {
+	n, m := len(a), len(b)
+	offset := n + m
+	v := make([]int, 2*(n+m)+2)
+	//trace[d] are the furthest x before step d, on the diagonals -d to d
+	var trace [][]int
+
+	steps := -1
+search:
+	for d := 0; d <= n+m; d++ {
+		if d > maxDiffEdits {
+			return replaceLines(a, b)
+		}
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				steps = d
+				break search
+			}
+		}
+	}
+
+	//walk back, collecting the lines in reverse
+	var reversed []DiffLine
+	x, y := n, m
+	for d := steps; d >= 0; d-- {
+		previous := trace[d]
+		k := x - y
+
+		var previousK int
+		if k == -d || (k != d && previous[k-1+d] < previous[k+1+d]) {
+			previousK = k + 1
+		} else {
+			previousK = k - 1
+		}
+
+		previousX := 0
+		if d > 0 {
+			previousX = previous[previousK+d]
+		}
+		previousY := previousX - previousK
+
+		for x > previousX && y > previousY {
+			x--
+			y--
+			reversed = append(reversed, DiffLine{Op: DiffEqual, Text: b[y]})
+		}
+		if d == 0 {
+			break
+		}
+		if x == previousX {
+			y--
+			reversed = append(reversed, DiffLine{Op: DiffInsert, Text: b[y]})
+		} else {
+			x--
+			reversed = append(reversed, DiffLine{Op: DiffDelete, Text: a[x]})
+		}
+	}
+
+	result := make([]DiffLine, 0, len(reversed))
+	for i := len(reversed) - 1; i >= 0; i-- {
+		result = append(result, reversed[i])
+	}
+	return result
+}
+
+// replaceLines deletes all lines a and inserts all lines b
+func replaceLines(a, b []string) []DiffLine {
+	result := make([]DiffLine, 0, len(a)+len(b))
+	for _, line := range a {
+		result = append(result, DiffLine{Op: DiffDelete, Text: line})
+	}
+	for _, line := range b {
+		result = append(result, DiffLine{Op: DiffInsert, Text: line})
+	}
+	return result
+}
+
+// editDistance returns the number of lines to insert and delete
+// to turn the lines a into the lines b, with the algorithm of Myers,
+// which takes time proportional to the size of the difference
+func editDistance(a, b []string) int {
+	n, m := len(a), len(b)
+	offset := n + m
+	//furthest x on each diagonal k = x - y
+	v := make([]int, 2*(n+m)+2)
+
+	for d := 0; d <= n+m; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return d
+			}
+		}
+	}
+	return n + m
+}
@@ -0,0 +1,162 @@
+//go:build !noui
+
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// DiffResponse is the response of /diff
+type DiffResponse struct {
+	Code string     `json:"code"`
+	Diff []DiffLine `json:"diff"`
+}
+
+// handleDiff returns the handler which responds with the formatted code,
+// and the line diff from the code to it, so the UI can show what formatting changed
+func handleDiff(profiles profileDir, cache *formatCache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req Request
+		if err := decodeBody(r, &req); err != nil {
+			writeBodyError(w, err)
+			return
+		}
+		options, err := profiles.options(req.RequestOptions)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		result, err := cache.format(r.Context(), req.Code, options)
+		if err != nil {
+			writeError(w, formatErrorStatus(err), err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(DiffResponse{
+			Code: result,
+			Diff: lineDiff(splitLines(req.Code), splitLines(result)),
+		})
+	}
+}
+
+// CompareRequest is the body of /compare,
+// the code and the two sets of options it is formatted with
+type CompareRequest struct {
+	Code   string         `json:"code"`
+	Before RequestOptions `json:"before"`
+	After  RequestOptions `json:"after"`
+}
+
+// CompareResponse is the code formatted with each set of options,
+// and the line diff from the former to the latter
+type CompareResponse struct {
+	Before string     `json:"before"`
+	After  string     `json:"after"`
+	Diff   []DiffLine `json:"diff"`
+	// Inserted and Deleted are the numbers of changed lines
+	Inserted int `json:"inserted"`
+	Deleted  int `json:"deleted"`
+}
+
+// handleCompare returns the handler which formats the code with two sets of options,
+// e.g. two widths, so the UI can show what changing the settings would change
+func handleCompare(profiles profileDir, cache *formatCache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req CompareRequest
+		if err := decodeBody(r, &req); err != nil {
+			writeBodyError(w, err)
+			return
+		}
+		before, err := profiles.options(req.Before)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("before: %w", err))
+			return
+		}
+		after, err := profiles.options(req.After)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("after: %w", err))
+			return
+		}
+
+		var response CompareResponse
+		response.Before, err = cache.format(r.Context(), req.Code, before)
+		if err == nil {
+			response.After, err = cache.format(r.Context(), req.Code, after)
+		}
+		if err != nil {
+			writeError(w, formatErrorStatus(err), err)
+			return
+		}
+
+		response.Diff = lineDiff(splitLines(response.Before), splitLines(response.After))
+		for _, line := range response.Diff {
+			switch line.Op {
+			case DiffInsert:
+				response.Inserted++
+			case DiffDelete:
+				response.Deleted++
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	}
+}
+
+// EditsResponse is the response of /edits
+type EditsResponse struct {
+	Edits []TextEdit `json:"edits"`
+}
+
+// handleEdits returns the handler which responds with the edits turning the code into the formatted code,
+// so editors can apply only the changes
+func handleEdits(profiles profileDir, cache *formatCache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req Request
+		if err := decodeBody(r, &req); err != nil {
+			writeBodyError(w, err)
+			return
+		}
+		options, err := profiles.options(req.RequestOptions)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		result, err := cache.format(r.Context(), req.Code, options)
+		if err != nil {
+			writeError(w, formatErrorStatus(err), err)
+			return
+		}
+		//the edits apply to files, which end with a line break like formatted files do,
+		//so formatted files have no edits
+		if result != "" {
+			result = finalNewline(withLineEndings(result+"\n", req.Code, options), true)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(EditsResponse{Edits: textEdits(req.Code, result)})
+	}
+}
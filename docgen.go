@@ -0,0 +1,129 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"cadencefmt/format"
+)
+
+// runDocgen prints the API documentation of Cadence files:
+// the declarations with their formatted signatures and doc comments
+func runDocgen(args []string) {
+	flags := flag.NewFlagSet("docgen", flag.ExitOnError)
+	optionFlags := addOptionFlags(flags)
+	formatFlag := flags.String("format", "markdown", "output format, markdown or json")
+
+	//flags may also follow the files, e.g. docgen file.cdc --format=json
+	var paths []string
+	for {
+		_ = flags.Parse(args)
+		if flags.NArg() == 0 {
+			break
+		}
+		paths = append(paths, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+
+	cliOptions, err := optionFlags.cliOptions()
+	if err != nil {
+		fatal(err)
+	}
+	options := cliOptions.Options
+
+	if len(paths) == 0 {
+		fatal(errors.New("usage: cadencefmt docgen [-format markdown|json] <file.cdc>..."))
+	}
+	if *formatFlag != "markdown" && *formatFlag != "json" {
+		fatal(fmt.Errorf("unknown format %q, expected markdown or json", *formatFlag))
+	}
+
+	filenames, err := cadenceFiles(paths)
+	if err != nil {
+		fatal(err)
+	}
+
+	files := make([]fileDocs, 0, len(filenames))
+	for _, filename := range filenames {
+		code, err := os.ReadFile(filename)
+		if err != nil {
+			fatal(err)
+		}
+		declarations, err := format.Docs(string(code), options)
+		if err != nil {
+			fatal(fmt.Errorf("%s: %w", filename, err))
+		}
+		files = append(files, fileDocs{Path: filename, Declarations: declarations})
+	}
+
+	if *formatFlag == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(files); err != nil {
+			fatal(err)
+		}
+		return
+	}
+
+	for i, file := range files {
+		if i > 0 {
+			fmt.Println()
+		}
+		writeMarkdownDocs(os.Stdout, file)
+	}
+}
+
+// fileDocs is the documentation of a file
+type fileDocs struct {
+	Path         string               `json:"path"`
+	Declarations []format.Declaration `json:"declarations"`
+}
+
+func writeMarkdownDocs(w io.Writer, file fileDocs) {
+	fmt.Fprintf(w, "# %s\n", file.Path)
+	for _, declaration := range file.Declarations {
+		writeMarkdownDeclaration(w, declaration, "", 2)
+	}
+}
+
+// writeMarkdownDeclaration writes a section of the declaration,
+// and sections of its members one heading level below it
+func writeMarkdownDeclaration(w io.Writer, declaration format.Declaration, parent string, level int) {
+	name := declaration.Name
+	if parent != "" {
+		name = parent + "." + name
+	}
+
+	fmt.Fprintf(w, "\n%s %s `%s`\n", strings.Repeat("#", min(level, 6)), declaration.Kind, name)
+	fmt.Fprintf(w, "\n```cadence\n%s\n```\n", declaration.Signature)
+	if declaration.Doc != "" {
+		fmt.Fprintf(w, "\n%s\n", declaration.Doc)
+	}
+
+	for _, member := range declaration.Members {
+		writeMarkdownDeclaration(w, member, name, level+1)
+	}
+}
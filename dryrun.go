@@ -0,0 +1,61 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// dryRunPreviewLines is the number of changed lines -dry-run shows of each file
+const dryRunPreviewLines = 8
+
+// dryRunPreview describes how formatting would change the file:
+// the number of changed lines, and the first of them, deleted lines with - and inserted lines with +
+func dryRunPreview(filename string, code string, result string, linesChanged int) string {
+	var preview strings.Builder
+	fmt.Fprintf(&preview, "%s: %d lines would change\n", filename, linesChanged)
+
+	shown := 0
+	line := 1
+	previous := 0
+	for _, diffLine := range lineDiff(splitLines(code), splitLines(result)) {
+		if diffLine.Op == DiffEqual {
+			line++
+			continue
+		}
+		if shown == dryRunPreviewLines {
+			fmt.Fprintf(&preview, "  ... %d more\n", linesChanged-shown)
+			break
+		}
+		//the first change of each run starts with its line in the formatted code
+		if line != previous {
+			fmt.Fprintf(&preview, "  @@ line %d\n", line)
+		}
+		marker := "-"
+		if diffLine.Op == DiffInsert {
+			marker = "+"
+			line++
+		}
+		previous = line
+		fmt.Fprintf(&preview, "  %s %s\n", marker, diffLine.Text)
+		shown++
+	}
+	return preview.String()
+}
@@ -0,0 +1,103 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// editsFormats are the formats of -edits
+const editsFormats = "json for a JSON record of the edits of each file"
+
+// TextEdit replaces the bytes of the code from StartOffset to EndOffset,
+// which is exclusive, with NewText
+type TextEdit struct {
+	StartOffset int    `json:"startOffset"`
+	EndOffset   int    `json:"endOffset"`
+	NewText     string `json:"newText"`
+}
+
+// fileEdits are the edits of a file, as printed by -edits json
+type fileEdits struct {
+	File  string     `json:"file"`
+	Edits []TextEdit `json:"edits"`
+}
+
+// textEdits returns the edits turning the code into the result,
+// in order and not overlapping: each run of changed lines of the line diff,
+// without the text the old and the new lines have in common at the start and at the end,
+// so editors keep the cursor and the undo history of the unchanged text
+func textEdits(code string, result string) []TextEdit {
+	edits := []TextEdit{}
+
+	var deleted, inserted strings.Builder
+	start := 0
+	offset := 0
+	flush := func() {
+		if deleted.Len() == 0 && inserted.Len() == 0 {
+			return
+		}
+		before, after := deleted.String(), inserted.String()
+		prefix, suffix := commonAffixes(before, after)
+		edits = append(edits, TextEdit{
+			StartOffset: start + prefix,
+			EndOffset:   start + len(before) - suffix,
+			NewText:     after[prefix : len(after)-suffix],
+		})
+		deleted.Reset()
+		inserted.Reset()
+	}
+
+	for _, line := range lineDiff(strings.SplitAfter(code, "\n"), strings.SplitAfter(result, "\n")) {
+		switch line.Op {
+		case DiffEqual:
+			flush()
+			offset += len(line.Text)
+			start = offset
+		case DiffDelete:
+			deleted.WriteString(line.Text)
+			offset += len(line.Text)
+		case DiffInsert:
+			inserted.WriteString(line.Text)
+		}
+	}
+	flush()
+
+	return edits
+}
+
+// commonAffixes returns the lengths of the common prefix and suffix of the texts,
+// which do not overlap, and do not split characters
+func commonAffixes(a, b string) (prefix int, suffix int) {
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	for (prefix < len(a) && !utf8.RuneStart(a[prefix])) || (prefix < len(b) && !utf8.RuneStart(b[prefix])) {
+		prefix--
+	}
+
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	for suffix > 0 && !utf8.RuneStart(a[len(a)-suffix]) {
+		suffix--
+	}
+	return prefix, suffix
+}
@@ -0,0 +1,78 @@
+//go:build !noui
+
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"strings"
+)
+
+// exampleFiles are canonical Cadence programs,
+// for exploring the formatter without pasting code
+//
+//go:embed examples/*.cdc
+var exampleFiles embed.FS
+
+// Example is a program of the example gallery
+type Example struct {
+	Name  string `json:"name"`
+	Title string `json:"title"`
+	Code  string `json:"code"`
+}
+
+// examples returns the examples, ordered by name.
+// The title is the name, e.g. "Fungible token" for fungible-token.cdc
+func examples() ([]Example, error) {
+	paths, err := fs.Glob(exampleFiles, "examples/*.cdc")
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Example, 0, len(paths))
+	for _, path := range paths {
+		code, err := exampleFiles.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(path, "examples/"), ".cdc")
+		title := strings.ReplaceAll(name, "-", " ")
+		result = append(result, Example{
+			Name:  name,
+			Title: strings.ToUpper(title[:1]) + title[1:],
+			Code:  string(code),
+		})
+	}
+	return result, nil
+}
+
+// handleExamples responds with the examples of the gallery
+func handleExamples(w http.ResponseWriter, r *http.Request) {
+	result, err := examples()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(result)
+}
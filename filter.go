@@ -0,0 +1,109 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+)
+
+// The filter protocol formats code sent over a Unix socket, without the overhead of HTTP and JSON.
+// A request is the length of the code, a 4 byte big endian number, followed by the code.
+// The response is a status byte, 0 for the formatted code and 1 for an error message,
+// followed by the length of the text, again 4 bytes big endian, and the text.
+// A connection can send any number of requests, which are answered in order
+const (
+	filterOK    byte = 0
+	filterError byte = 1
+)
+
+// serveFilter answers the requests of the filter protocol on the socket,
+// formatting the code with the options of the command line
+func serveFilter(path string, options cliOptions) error {
+	ln, err := listenUnix(path)
+	if err != nil {
+		return err
+	}
+	slog.Info("listening", "socket", ln.Addr().String())
+	closeOnSignal(ln)
+
+	for {
+		conn, err := ln.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			if err := filterConn(conn, options); err != nil {
+				slog.Warn("filter connection failed", "error", err)
+			}
+		}()
+	}
+}
+
+// filterConn answers the requests of the connection until it is closed
+func filterConn(conn net.Conn, options cliOptions) error {
+	reader := bufio.NewReader(conn)
+	writer := bufio.NewWriter(conn)
+	for {
+		var length uint32
+		if err := binary.Read(reader, binary.BigEndian, &length); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		//the code is not read, so the connection cannot be used for more requests
+		if options.maxSize > 0 && int64(length) > options.maxSize {
+			message := fmt.Sprintf("%d bytes is larger than -max-size of %d bytes", length, options.maxSize)
+			return writeFilterResponse(writer, filterError, message)
+		}
+
+		code := make([]byte, length)
+		if _, err := io.ReadFull(reader, code); err != nil {
+			return err
+		}
+
+		status := filterOK
+		result, err := formatSource(string(code), nil, options)
+		if err != nil {
+			status, result = filterError, err.Error()
+		}
+		if err := writeFilterResponse(writer, status, result); err != nil {
+			return err
+		}
+	}
+}
+
+// writeFilterResponse writes the status and the text, and sends them
+func writeFilterResponse(writer *bufio.Writer, status byte, text string) error {
+	_ = writer.WriteByte(status)
+	_ = binary.Write(writer, binary.BigEndian, uint32(len(text)))
+	_, _ = writer.WriteString(text)
+	return writer.Flush()
+}
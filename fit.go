@@ -0,0 +1,117 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// fitResult is the smallest width at which no line of a file overflows
+type fitResult struct {
+	Width int
+	// Fits is false if no width fits, e.g. because of long unwrappable tokens,
+	// Width is then the widest line
+	Fits bool
+	// TimedOut is true if the search was stopped early,
+	// Width is then the smallest width found so far
+	TimedOut bool
+}
+
+// fitWidth searches the smallest width, at least the minimum,
+// at which the formatted code has no lines wider than the width
+func fitWidth(code string, minimum int, options cliOptions, timeout time.Duration) (fitResult, error) {
+	deadline := time.Now().Add(timeout)
+
+	widest := func(width int) (int, error) {
+		options.MaxLineLength = width
+		formatted, err := formatSource(code, nil, options)
+		if err != nil {
+			return 0, err
+		}
+		return widestLine(formatted, options.widthExceptions), nil
+	}
+
+	//formatted as wide as needed, the widest line is an upper bound
+	high, err := widest(1 << 16)
+	if err != nil {
+		return fitResult{}, err
+	}
+	if high <= minimum {
+		return fitResult{Width: minimum, Fits: true}, nil
+	}
+	if w, err := widest(high); err != nil {
+		return fitResult{}, err
+	} else if w > high {
+		return fitResult{Width: w}, nil
+	}
+
+	low := minimum
+	for low < high {
+		if time.Now().After(deadline) {
+			return fitResult{Width: high, Fits: true, TimedOut: true}, nil
+		}
+
+		middle := low + (high-low)/2
+		w, err := widest(middle)
+		if err != nil {
+			return fitResult{}, err
+		}
+		if w <= middle {
+			high = middle
+		} else {
+			low = middle + 1
+		}
+	}
+
+	return fitResult{Width: high, Fits: true}, nil
+}
+
+// fitFiles reports the smallest width of each file, and of all files together
+func fitFiles(filenames []string, minimum int, options cliOptions, timeout time.Duration) error {
+	overall := minimum
+	for _, filename := range filenames {
+		code, err := os.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+
+		result, err := fitWidth(string(code), minimum, options, timeout)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", filename, firstLine(err.Error()))
+			continue
+		}
+
+		switch {
+		case !result.Fits:
+			fmt.Printf("%s: does not fit, widest line has %d columns\n", filename, result.Width)
+		case result.TimedOut:
+			fmt.Printf("%s: fits in %d columns (search timed out)\n", filename, result.Width)
+		default:
+			fmt.Printf("%s: fits in %d columns\n", filename, result.Width)
+		}
+		overall = max(overall, result.Width)
+	}
+
+	if len(filenames) > 1 {
+		fmt.Printf("all files fit in %d columns\n", overall)
+	}
+	return nil
+}
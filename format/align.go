@@ -0,0 +1,333 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/parser"
+	"github.com/onflow/cadence/runtime/parser/lexer"
+)
+
+// trailingComment is a line comment following code on its line
+type trailingComment struct {
+	line int
+	// offset is the offset of the comment in the code
+	offset int
+	// codeEnd is the offset after the code before the comment
+	codeEnd int
+	indent  string
+}
+
+// alignComments aligns the trailing line comments of consecutive lines
+// with the same indentation to a common column, one space after the longest code
+func alignComments(code string) string {
+	var edits []commentEdit
+	var group []trailingComment
+	flush := func() {
+		if len(group) > 1 {
+			edits = append(edits, alignGroup(code, group)...)
+		}
+		group = group[:0]
+	}
+
+	for _, c := range trailingComments(code) {
+		if len(group) > 0 {
+			last := group[len(group)-1]
+			if last.line+1 != c.line || last.indent != c.indent {
+				flush()
+			}
+		}
+		group = append(group, c)
+	}
+	flush()
+
+	//edit from the end, so the offsets of earlier comments stay valid
+	for i := len(edits) - 1; i >= 0; i-- {
+		edit := edits[i]
+		code = code[:edit.start] + edit.text + code[edit.end:]
+	}
+	return code
+}
+
+// alignGroup returns the edits replacing the space between the code and the comment
+// of each line of the group with the padding to the common column
+func alignGroup(code string, group []trailingComment) []commentEdit {
+	column := 0
+	for _, c := range group {
+		column = max(column, codeWidth(code, c.codeEnd))
+	}
+
+	edits := make([]commentEdit, 0, len(group))
+	for _, c := range group {
+		padding := column - codeWidth(code, c.codeEnd) + 1
+		edits = append(edits, commentEdit{
+			commentSpan{c.codeEnd, c.offset},
+			strings.Repeat(" ", padding),
+		})
+	}
+	return edits
+}
+
+// codeWidth is the number of characters on the line before the offset
+func codeWidth(code string, offset int) int {
+	lineStart := strings.LastIndex(code[:offset], "\n") + 1
+	return utf8.RuneCountInString(code[lineStart:offset])
+}
+
+// trailingComments returns the line comments of the code which follow code on their line,
+// in order
+func trailingComments(code string) []trailingComment {
+	tokens := lexer.Lex([]byte(code), nil)
+	defer tokens.Reclaim()
+
+	var result []trailingComment
+	for {
+		token := tokens.Next()
+		switch token.Type {
+		case lexer.TokenEOF:
+			return result
+
+		case lexer.TokenLineComment:
+			offset := token.StartPos.Offset
+			lineStart := strings.LastIndex(code[:offset], "\n") + 1
+			before := strings.TrimRight(code[lineStart:offset], " \t")
+			if strings.TrimSpace(before) == "" {
+				continue
+			}
+			result = append(result, trailingComment{
+				line:    token.StartPos.Line,
+				offset:  offset,
+				codeEnd: lineStart + len(before),
+				indent:  lineIndent(code, lineStart+len(before)),
+			})
+		}
+	}
+}
+
+// alignedLine is a line whose code after the offset moves to a common column
+type alignedLine struct {
+	// start and end are the offsets of the space replaced with the padding,
+	// after the code before it
+	start, end int
+	// separator is the text at the end of the space, e.g. ":" or "="
+	separator string
+}
+
+// alignValues aligns the values of dictionary literals which have an entry on each line,
+// and the values of consecutive assignments with =, after the longest key or target.
+// Runs of lines which would be longer than the width are kept
+func alignValues(code string, width int) (string, error) {
+	program, err := parser.ParseProgram(nil, []byte(code), parser.Config{})
+	if err != nil {
+		return "", err
+	}
+
+	var edits []commentEdit
+	//runs are the lines of consecutive entries or assignments,
+	//added if the text between the key and the value is only the separator
+	addRuns := func(elements [][2]ast.HasPosition, separator string, follows func(previous, next int) bool) {
+		var run []alignedLine
+		flush := func() {
+			if len(run) > 1 {
+				edits = append(edits, alignRun(code, run, width)...)
+			}
+			run = run[:0]
+		}
+		previous := -1
+		for _, element := range elements {
+			key, value := element[0], element[1]
+			if key == nil {
+				flush()
+				previous = -1
+				continue
+			}
+			line := key.StartPosition().Line
+			start := key.EndPosition(nil).Offset + 1
+			end := value.StartPosition().Offset
+			lineStart := strings.LastIndex(code[:key.StartPosition().Offset], "\n") + 1
+			if line != value.EndPosition(nil).Line ||
+				strings.TrimSpace(code[lineStart:key.StartPosition().Offset]) != "" ||
+				strings.TrimSpace(code[start:end]) != separator {
+
+				flush()
+				previous = -1
+				continue
+			}
+			if previous < 0 || !follows(previous, line) {
+				flush()
+			}
+			run = append(run, alignedLine{start, end, separator})
+			previous = line
+		}
+		flush()
+	}
+
+	seen := map[ast.Element]bool{}
+	var visit func(element ast.Element)
+	visit = func(element ast.Element) {
+		if element == nil || reflect.ValueOf(element).IsNil() || seen[element] {
+			return
+		}
+		seen[element] = true
+
+		switch element := element.(type) {
+		case *ast.DictionaryExpression:
+			entries := make([][2]ast.HasPosition, 0, len(element.Entries))
+			for _, entry := range element.Entries {
+				entries = append(entries, [2]ast.HasPosition{entry.Key, entry.Value})
+			}
+			//entries are on consecutive lines, or on their own lines
+			addRuns(entries, ":", func(previous, next int) bool { return next > previous })
+		case *ast.Block:
+			addRuns(assignments(element.Statements), "=", func(previous, next int) bool { return next == previous+1 })
+		case *ast.FunctionBlock:
+			visit(element.Block)
+		}
+		element.Walk(visit)
+	}
+	visit(program)
+
+	sort.Slice(edits, func(i, j int) bool {
+		return edits[i].start < edits[j].start
+	})
+	//edit from the end, so the offsets of earlier values stay valid
+	for i := len(edits) - 1; i >= 0; i-- {
+		edit := edits[i]
+		code = code[:edit.start] + edit.text + code[edit.end:]
+	}
+	return code, nil
+}
+
+// assignments returns the targets and values of the statements, for assignments with =,
+// and nil pairs for the other statements, which end runs of assignments
+func assignments(statements []ast.Statement) [][2]ast.HasPosition {
+	result := make([][2]ast.HasPosition, 0, len(statements))
+	for _, statement := range statements {
+		assignment, ok := statement.(*ast.AssignmentStatement)
+		if !ok || assignment.Transfer.Operation != ast.TransferOperationCopy {
+			result = append(result, [2]ast.HasPosition{})
+			continue
+		}
+		result = append(result, [2]ast.HasPosition{assignment.Target, assignment.Value})
+	}
+	return result
+}
+
+// alignRun returns the edits padding the lines of the run, so the separators are in a common column.
+// Dictionary entries keep the separator after the key and align the values,
+// assignments align the separators
+func alignRun(code string, run []alignedLine, width int) []commentEdit {
+	column := 0
+	for _, line := range run {
+		column = max(column, codeWidth(code, line.start))
+	}
+
+	edits := make([]commentEdit, 0, len(run))
+	for _, line := range run {
+		padding := strings.Repeat(" ", column-codeWidth(code, line.start))
+		text := " " + padding + line.separator + " "
+		if line.separator == ":" {
+			text = line.separator + " " + padding
+		}
+		lineEnd := strings.IndexByte(code[line.end:], '\n')
+		if lineEnd < 0 {
+			lineEnd = len(code) - line.end
+		}
+		if width > 0 && column+len(text)-len(padding)+utf8.RuneCountInString(code[line.end:line.end+lineEnd]) > width {
+			return nil
+		}
+		edits = append(edits, commentEdit{commentSpan{line.start, line.end}, text})
+	}
+	return edits
+}
+
+// alignParameters aligns the types of the parameters of broken parameter lists,
+// which have each parameter on its own line, after the longest name.
+// Lists whose lines would be longer than the width are kept
+func alignParameters(code string, width int) (string, error) {
+	program, err := parser.ParseProgram(nil, []byte(code), parser.Config{})
+	if err != nil {
+		return "", err
+	}
+
+	var edits []commentEdit
+	seen := map[ast.Element]bool{}
+	var visit func(element ast.Element)
+	visit = func(element ast.Element) {
+		if element == nil || reflect.ValueOf(element).IsNil() || seen[element] {
+			return
+		}
+		seen[element] = true
+
+		switch element := element.(type) {
+		case *ast.FunctionDeclaration:
+			edits = append(edits, parameterRun(code, element.ParameterList, width)...)
+		case *ast.SpecialFunctionDeclaration:
+			edits = append(edits, parameterRun(code, element.FunctionDeclaration.ParameterList, width)...)
+		case *ast.FunctionExpression:
+			edits = append(edits, parameterRun(code, element.ParameterList, width)...)
+		case *ast.TransactionDeclaration:
+			edits = append(edits, parameterRun(code, element.ParameterList, width)...)
+		}
+		element.Walk(visit)
+	}
+	visit(program)
+
+	sort.Slice(edits, func(i, j int) bool {
+		return edits[i].start < edits[j].start
+	})
+	//edit from the end, so the offsets of earlier parameters stay valid
+	for i := len(edits) - 1; i >= 0; i-- {
+		edit := edits[i]
+		code = code[:edit.start] + edit.text + code[edit.end:]
+	}
+	return code, nil
+}
+
+// parameterRun returns the edits aligning the types of the parameters of the list,
+// if each of them is on its own line
+func parameterRun(code string, list *ast.ParameterList, width int) []commentEdit {
+	if list == nil || len(list.Parameters) < 2 {
+		return nil
+	}
+
+	run := make([]alignedLine, 0, len(list.Parameters))
+	previous := list.StartPos.Line
+	for _, parameter := range list.Parameters {
+		start := parameter.StartPos.Offset
+		lineStart := strings.LastIndex(code[:start], "\n") + 1
+		nameEnd := parameter.Identifier.EndPosition(nil).Offset + 1
+		typeStart := parameter.TypeAnnotation.StartPos.Offset
+		if parameter.StartPos.Line <= previous ||
+			parameter.TypeAnnotation.EndPosition(nil).Line != parameter.StartPos.Line ||
+			strings.TrimSpace(code[lineStart:start]) != "" ||
+			strings.TrimSpace(code[nameEnd:typeStart]) != ":" {
+
+			return nil
+		}
+		run = append(run, alignedLine{nameEnd, typeStart, ":"})
+		previous = parameter.StartPos.Line
+	}
+	return alignRun(code, run, width)
+}
@@ -0,0 +1,69 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package format
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// largeContract returns a contract with the functions, which have comments and string literals like real contracts
+func largeContract(functions int) string {
+	var b strings.Builder
+	b.WriteString("// A large contract\npub contract Large {\n")
+	for i := 0; i < functions; i++ {
+		fmt.Fprintf(&b, "\n    /// f%d checks the amount\n", i)
+		fmt.Fprintf(&b, "    pub fun f%d(amount: UFix64, to: Address): String {\n", i)
+		b.WriteString("        pre { amount > 0.0: \"amount must be positive\" } // checked first\n")
+		b.WriteString("        let message = \"sent \\u{1F600}\".concat(amount.toString()) /* inline */\n")
+		b.WriteString("        emit Sent(amount: amount, to: to)\n")
+		b.WriteString("        return message\n    }\n")
+	}
+	b.WriteString("\n    pub event Sent(amount: UFix64, to: Address)\n}\n")
+	return b.String()
+}
+
+func BenchmarkSource(b *testing.B) {
+	examples, err := filepath.Glob(filepath.Join("..", "examples", "*.cdc"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	codes := map[string]string{"large": largeContract(200)}
+	for _, example := range examples {
+		code, err := os.ReadFile(example)
+		if err != nil {
+			b.Fatal(err)
+		}
+		codes[strings.TrimSuffix(filepath.Base(example), ".cdc")] = string(code)
+	}
+
+	for name, code := range codes {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(code)))
+			for i := 0; i < b.N; i++ {
+				if _, err := Source(code, DefaultOptions); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
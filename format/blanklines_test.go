@@ -0,0 +1,65 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"testing"
+)
+
+func TestSourceBlankLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		expected string
+	}{
+		{
+			name:     "separated members",
+			code:     "pub contract C {\n    pub let x: Int\n    pub fun f() {}\n    init() { self.x = 1 }\n}\n",
+			expected: "pub contract C {\n    pub let x: Int\n\n    pub fun f() {}\n\n    init() {\n        self.x = 1\n    }\n}",
+		},
+		{
+			name:     "kept blank lines",
+			code:     "pub fun f() {\n    let x = 1\n    \n    let y = 2\n}\n",
+			expected: "pub fun f() {\n    let x = 1\n\n    let y = 2\n}",
+		},
+		{
+			name:     "block comment",
+			code:     "pub fun f() {\n    /* a\n    \n       b */\n    return\n}\n",
+			expected: "pub fun f() {\n    /* a\n    \n       b */\n    return\n}",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			//aligning comments formats the whole code at once, instead of streaming it
+			for _, alignComments := range []bool{false, true} {
+				options := DefaultOptions
+				options.AlignComments = alignComments
+
+				formatted, err := Source(test.code, options)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if formatted != test.expected {
+					t.Errorf("expected %q, got %q", test.expected, formatted)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"testing"
+)
+
+func TestSourceBodies(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		style    EmptyBodyStyle
+		expected string
+	}{
+		{
+			name:     "interface requirements",
+			code:     "pub resource interface R {\n    pub fun f(): Int\n    init()\n}\n",
+			style:    EmptyBodiesCompact,
+			expected: "pub resource interface R {\n    pub fun f(): Int\n\n    init()\n}",
+		},
+		{
+			name:     "interface requirement with conditions",
+			code:     "pub resource interface R {\n    pub fun f() {\n        pre { true }\n    }\n}\n",
+			style:    EmptyBodiesCompact,
+			expected: "pub resource interface R {\n    pub fun f() {\n        pre { true }\n    }\n}",
+		},
+		{
+			name:     "compact",
+			code:     "pub resource S {\n    pub fun g() {   }\n    init() {\n    }\n}\n",
+			style:    EmptyBodiesCompact,
+			expected: "pub resource S {\n    pub fun g() {}\n\n    init() {}\n}",
+		},
+		{
+			name:     "spaced",
+			code:     "pub resource S {\n    pub fun g() {}\n    init() {\n    }\n}\n",
+			style:    EmptyBodiesSpaced,
+			expected: "pub resource S {\n    pub fun g() { }\n\n    init() { }\n}",
+		},
+		{
+			name:     "split",
+			code:     "pub resource S {\n    pub fun g() {}\n    init() { }\n}\n",
+			style:    EmptyBodiesSplit,
+			expected: "pub resource S {\n    pub fun g() {\n    }\n\n    init() {\n    }\n}",
+		},
+		{
+			name:     "empty blocks",
+			code:     "pub fun h() {\n    if true {  }\n    while false {\n    }\n}\n",
+			style:    EmptyBodiesSpaced,
+			expected: "pub fun h() {\n    if true {}\n    while false {}\n}",
+		},
+		{
+			name:     "empty composite",
+			code:     "pub struct E {  \n}\n",
+			style:    EmptyBodiesSplit,
+			expected: "pub struct E {}",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			options := DefaultOptions
+			options.EmptyBodies = test.style
+
+			formatted, err := Source(test.code, options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if formatted != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, formatted)
+			}
+			if err := Verify(test.code, formatted, options); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"reflect"
+
+	"github.com/turbolent/prettier"
+	"golang.org/x/exp/slices"
+
+	"github.com/onflow/cadence/runtime/ast"
+)
+
+// borrowFallback is a chain of calls ending with a call of borrow and its ?? fallback,
+// e.g. `getAccount(a).getCapability(p).borrow<&R>() ?? panic("...")`,
+// which breaks the chain before each call and puts the fallback on its own line when it does not fit
+type borrowFallback struct {
+	*ast.BinaryExpression
+}
+
+// borrowed returns the binary expression printing it with its borrow chain,
+// if it is a ?? fallback of one and such chains are broken, or nil
+func (p printer) borrowed(expression *ast.BinaryExpression, visited map[uintptr]bool) *borrowFallback {
+	if !p.options.enabled(RuleBreakBorrowChains) || expression.Operation != ast.OperationNilCoalesce {
+		return nil
+	}
+	p.replace(reflect.ValueOf(expression).Elem().FieldByName("Left"), visited)
+	chained, ok := expression.Left.(*chainedInvocation)
+	if !ok {
+		return nil
+	}
+	member, ok := chained.InvokedExpression.(*ast.MemberExpression)
+	if !ok || member.Identifier.Identifier != "borrow" {
+		return nil
+	}
+	return &borrowFallback{expression}
+}
+
+func (e *borrowFallback) Doc() prettier.Doc {
+	//the AST prints the operands in groups, the chain in its own one,
+	//which are removed so the chain and the operator break together
+	doc := slices.Clone(e.BinaryExpression.Doc().(prettier.Group).Doc.(prettier.Concat))
+	left := doc[0].(prettier.Group).Doc
+	if group, ok := left.(prettier.Group); ok {
+		left = group.Doc
+	}
+	doc[0] = left
+	return prettier.Group{Doc: doc}
+}
@@ -0,0 +1,305 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"reflect"
+
+	"github.com/turbolent/prettier"
+	"golang.org/x/exp/slices"
+
+	"github.com/onflow/cadence/runtime/ast"
+)
+
+// chain is a chain of member accesses and calls, e.g. `a.b().c()`,
+// which breaks before each call of a member when it does not fit,
+// instead of inside of its arguments
+type chain struct {
+	printer printer
+	// links are the member accesses, calls, force unwraps and index accesses of the chain,
+	// from the innermost one
+	links []ast.Expression
+	// commented are the calls which must print their arguments broken, by link
+	commented map[int]*commentedInvocation
+}
+
+// chainCalls is the number of calls of members a chain must have to be broken before them
+const chainCalls = 2
+
+// chainedInvocation, chainedMember, chainedForce and chainedIndex print the outermost link of a chain,
+// and with it the chain
+type chainedInvocation struct {
+	*ast.InvocationExpression
+	chain *chain
+}
+
+func (e *chainedInvocation) Doc() prettier.Doc {
+	return e.chain.doc()
+}
+
+type chainedMember struct {
+	*ast.MemberExpression
+	chain *chain
+}
+
+func (e *chainedMember) Doc() prettier.Doc {
+	return e.chain.doc()
+}
+
+type chainedForce struct {
+	*ast.ForceExpression
+	chain *chain
+}
+
+func (e *chainedForce) Doc() prettier.Doc {
+	return e.chain.doc()
+}
+
+type chainedIndex struct {
+	*ast.IndexExpression
+	chain *chain
+}
+
+func (e *chainedIndex) Doc() prettier.Doc {
+	return e.chain.doc()
+}
+
+// chained returns the expression printing it as a chain, if it is one and chains are wrapped, or nil.
+// The links are printed by the chain, so only the expressions in them which are no links are replaced
+func (p printer) chained(expression ast.Expression, visited map[uintptr]bool) ast.Expression {
+	if !p.options.enabled(RuleWrapChains) {
+		return nil
+	}
+	chain := p.chain(expression)
+	if chain == nil {
+		return nil
+	}
+
+	for _, link := range chain.links {
+		visited[reflect.ValueOf(link).Pointer()] = true
+	}
+	for i, link := range chain.links {
+		value := reflect.ValueOf(link).Elem()
+		switch link := link.(type) {
+		case *ast.InvocationExpression:
+			if commented := p.commentedInvocation(link); commented != nil {
+				chain.commented[i] = commented
+			}
+			p.replaceCommented(value.FieldByName("TypeArguments"), visited)
+			p.replaceCommented(value.FieldByName("Arguments"), visited)
+		case *ast.IndexExpression:
+			p.replace(value.FieldByName("IndexingExpression"), visited)
+		}
+	}
+	//the expression the innermost link applies to
+	innermost := reflect.ValueOf(chain.links[0]).Elem()
+	switch chain.links[0].(type) {
+	case *ast.InvocationExpression:
+		p.replace(innermost.FieldByName("InvokedExpression"), visited)
+	case *ast.IndexExpression:
+		p.replace(innermost.FieldByName("TargetExpression"), visited)
+	default:
+		p.replace(innermost.FieldByName("Expression"), visited)
+	}
+
+	switch e := expression.(type) {
+	case *ast.InvocationExpression:
+		return &chainedInvocation{e, chain}
+	case *ast.MemberExpression:
+		return &chainedMember{e, chain}
+	case *ast.ForceExpression:
+		return &chainedForce{e, chain}
+	case *ast.IndexExpression:
+		return &chainedIndex{e, chain}
+	}
+	return nil
+}
+
+// chain returns the expression as a chain, if it is one with enough calls of members, or nil
+func (p printer) chain(expression ast.Expression) *chain {
+	var links []ast.Expression
+	calls := 0
+	link := expression
+	for {
+		var inner ast.Expression
+		switch e := link.(type) {
+		case *ast.MemberExpression:
+			inner = e.Expression
+		case *ast.InvocationExpression:
+			if _, ok := e.InvokedExpression.(*ast.MemberExpression); ok {
+				calls++
+			}
+			inner = e.InvokedExpression
+		case *ast.ForceExpression:
+			inner = e.Expression
+		case *ast.IndexExpression:
+			inner = e.TargetExpression
+		default:
+			if calls < chainCalls {
+				return nil
+			}
+			slices.Reverse(links)
+			return &chain{
+				printer:   p,
+				links:     links,
+				commented: map[int]*commentedInvocation{},
+			}
+		}
+		links = append(links, link)
+		link = inner
+	}
+}
+
+func (c *chain) doc() prettier.Doc {
+	//the AST prints the expression of the innermost link first, in parentheses if needed
+	head := prettier.Concat{c.links[0].Doc().(prettier.Concat)[0]}
+
+	//each call of a member starts a segment, and the member accesses after the first one,
+	//the ones before it stay with the head
+	var segments []prettier.Concat
+	for i, link := range c.links {
+		if _, ok := link.(*ast.MemberExpression); ok && (len(segments) > 0 || c.calledMember(i)) {
+			segments = append(segments, nil)
+		}
+		if len(segments) == 0 {
+			head = append(head, c.linkDoc(i))
+		} else {
+			segments[len(segments)-1] = append(segments[len(segments)-1], c.linkDoc(i))
+		}
+	}
+
+	var rest prettier.Concat
+	for _, segment := range segments {
+		rest = append(rest, prettier.SoftLine{}, segment)
+	}
+	return prettier.Group{
+		Doc: prettier.Concat{
+			head,
+			prettier.Indent{Doc: rest},
+		},
+	}
+}
+
+// calledMember reports if the link at the index is a member access which is called
+func (c *chain) calledMember(index int) bool {
+	if index+1 >= len(c.links) {
+		return false
+	}
+	_, ok := c.links[index+1].(*ast.InvocationExpression)
+	return ok
+}
+
+// linkDoc returns the document of the link at the index, without the expression it applies to
+func (c *chain) linkDoc(index int) prettier.Doc {
+	switch link := c.links[index].(type) {
+	case *ast.MemberExpression:
+		separator := "."
+		if link.Optional {
+			separator = "?."
+		}
+		return prettier.Text(separator + link.Identifier.Identifier)
+
+	case *ast.InvocationExpression:
+		var doc prettier.Concat
+		if len(link.TypeArguments) > 0 {
+			typeArguments := make([]prettier.Doc, len(link.TypeArguments))
+			for i, typeArgument := range link.TypeArguments {
+				typeArguments[i] = typeArgument.Doc()
+			}
+			doc = append(
+				doc,
+				prettier.Wrap(
+					prettier.Text("<"),
+					prettier.Join(prettier.Concat{prettier.Text(","), prettier.Line{}}, typeArguments...),
+					prettier.Text(">"),
+					prettier.SoftLine{},
+				),
+			)
+		}
+		//comments between the arguments may require them to be broken
+		if commented := c.commented[index]; commented != nil {
+			arguments := make([]prettier.Doc, len(link.Arguments))
+			for i, argument := range link.Arguments {
+				arguments[i] = argument.Doc()
+			}
+			return append(doc, c.printer.brokenList(arguments, commented.comments))
+		}
+		return append(doc, link.Arguments.Doc())
+
+	case *ast.ForceExpression:
+		return prettier.Text("!")
+
+	case *ast.IndexExpression:
+		return prettier.WrapBrackets(link.IndexingExpression.Doc(), prettier.SoftLine{})
+	}
+	return nil
+}
+
+// forcedMember, forcedIndex and forcedInvocation print the expression like the AST,
+// but without the parentheses the AST puts around the forced expression they access, e.g. r[A]!.x
+type forcedMember struct {
+	*ast.MemberExpression
+}
+
+func (e *forcedMember) Doc() prettier.Doc {
+	return withoutParentheses(e.MemberExpression.Doc(), e.Expression)
+}
+
+type forcedIndex struct {
+	*ast.IndexExpression
+}
+
+func (e *forcedIndex) Doc() prettier.Doc {
+	return withoutParentheses(e.IndexExpression.Doc(), e.TargetExpression)
+}
+
+type forcedInvocation struct {
+	*ast.InvocationExpression
+}
+
+func (e *forcedInvocation) Doc() prettier.Doc {
+	return withoutParentheses(e.InvocationExpression.Doc(), e.InvokedExpression)
+}
+
+// forced returns the expression printed without parentheses around the forced expression it accesses,
+// or nil if it does not access one
+func forced(expression ast.Expression) ast.Expression {
+	switch expression := expression.(type) {
+	case *ast.MemberExpression:
+		if _, ok := expression.Expression.(*ast.ForceExpression); ok {
+			return &forcedMember{expression}
+		}
+	case *ast.IndexExpression:
+		if _, ok := expression.TargetExpression.(*ast.ForceExpression); ok {
+			return &forcedIndex{expression}
+		}
+	case *ast.InvocationExpression:
+		if _, ok := expression.InvokedExpression.(*ast.ForceExpression); ok {
+			return &forcedInvocation{expression}
+		}
+	}
+	return nil
+}
+
+// withoutParentheses replaces the first document of the postfix expression,
+// the parenthesized expression it accesses, with the document of that expression
+func withoutParentheses(doc prettier.Doc, inner ast.Expression) prettier.Doc {
+	concat := doc.(prettier.Concat)
+	return append(prettier.Concat{inner.Doc()}, concat[1:]...)
+}
@@ -0,0 +1,197 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"strings"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/parser"
+	"github.com/onflow/cadence/runtime/parser/lexer"
+)
+
+// cleanImports removes the imports which repeat an earlier import of the code,
+// and the imports of names the rest of the code never references.
+// Imports sharing their lines with other code or comments, or in off regions, are kept.
+// The result is only used if its program is the program of the code without the removed imports.
+// Code with broken declarations, which may reference the imports, is kept
+func cleanImports(code string) (string, error) {
+	if len(brokenMarkers(code)) > 0 {
+		return code, nil
+	}
+	program, err := parser.ParseProgram(nil, []byte(code), parser.Config{})
+	if err != nil {
+		return "", err
+	}
+	if len(program.ImportDeclarations()) == 0 {
+		return code, nil
+	}
+
+	used := referencedNames(code, program)
+	regions := offRegions(code)
+	seen := map[string]bool{}
+	var edits []commentEdit
+	var removed []int
+	for i, declaration := range program.Declarations() {
+		importDeclaration, ok := declaration.(*ast.ImportDeclaration)
+		if !ok {
+			continue
+		}
+		start := importDeclaration.StartPosition().Offset
+		end := importDeclaration.EndPosition(nil).Offset + 1
+		key := strings.Join(strings.Fields(code[start:end]), " ")
+		duplicate := seen[key]
+		seen[key] = true
+		if !duplicate && !unusedImport(importDeclaration, used) {
+			continue
+		}
+
+		lineStart := strings.LastIndex(code[:start], "\n") + 1
+		lineEnd := len(code)
+		if newline := strings.IndexByte(code[end:], '\n'); newline >= 0 {
+			lineEnd = end + newline + 1
+		}
+		if strings.TrimSpace(code[lineStart:start]) != "" || strings.TrimSpace(code[end:lineEnd]) != "" {
+			continue
+		}
+		s := commentSpan{lineStart, lineEnd}
+		if len(outsideRegions([]commentEdit{{s, ""}}, regions)) == 0 {
+			continue
+		}
+		edits = append(edits, commentEdit{s, ""})
+		removed = append(removed, i)
+	}
+	if len(edits) == 0 {
+		return code, nil
+	}
+
+	cleaned := applyEdits(code, edits)
+	if !sameProgramWithout(code, cleaned, removed) {
+		return code, nil
+	}
+	return cleaned, nil
+}
+
+// referencedNames are the identifiers of the code outside of its imports,
+// and the words of strings with templates, which may reference imports too
+func referencedNames(code string, program *ast.Program) map[string]bool {
+	var spans []commentSpan
+	for _, declaration := range program.ImportDeclarations() {
+		spans = append(spans, commentSpan{declaration.StartPosition().Offset, declaration.EndPosition(nil).Offset + 1})
+	}
+	inImport := func(offset int) bool {
+		for _, s := range spans {
+			if s.start <= offset && offset < s.end {
+				return true
+			}
+		}
+		return false
+	}
+
+	tokens := lexer.Lex([]byte(code), nil)
+	defer tokens.Reclaim()
+
+	names := map[string]bool{}
+	for {
+		token := tokens.Next()
+		switch {
+		case token.Is(lexer.TokenEOF):
+			return names
+
+		case inImport(token.StartPos.Offset):
+			continue
+
+		case token.Is(lexer.TokenIdentifier):
+			names[extractTokenText(code, token)] = true
+
+		case token.Is(lexer.TokenString):
+			text := extractTokenText(code, token)
+			if !strings.Contains(text, `\(`) {
+				continue
+			}
+			isWordRune := func(r rune) bool {
+				return r == '_' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+			}
+			for _, word := range strings.FieldsFunc(text, func(r rune) bool { return !isWordRune(r) }) {
+				names[word] = true
+			}
+		}
+	}
+}
+
+// unusedImport reports if none of the names the declaration imports are referenced.
+// Imports of whole accounts and of paths, which do not name what they import, are used
+func unusedImport(declaration *ast.ImportDeclaration, used map[string]bool) bool {
+	var names []string
+	for _, identifier := range declaration.Identifiers {
+		names = append(names, identifier.Identifier)
+	}
+	if len(names) == 0 {
+		switch location := declaration.Location.(type) {
+		case common.IdentifierLocation:
+			names = append(names, string(location))
+		case common.StringLocation:
+			if strings.ContainsAny(string(location), "/.") {
+				return false
+			}
+			names = append(names, string(location))
+		default:
+			return false
+		}
+	}
+	for _, name := range names {
+		if used[name] {
+			return false
+		}
+	}
+	return true
+}
+
+// sameProgramWithout reports if the program of the cleaned code is the program of the code
+// without the declarations at the removed indices
+func sameProgramWithout(code string, cleaned string, removed []int) bool {
+	expected, err := programTree(code)
+	if err != nil {
+		return false
+	}
+	actual, err := programTree(cleaned)
+	if err != nil {
+		return false
+	}
+	tree, ok := expected.(map[string]any)
+	if !ok {
+		return false
+	}
+	declarations, ok := tree["Declarations"].([]any)
+	if !ok {
+		return false
+	}
+	kept := make([]any, 0, len(declarations))
+	for i, declaration := range declarations {
+		if len(removed) > 0 && removed[0] == i {
+			removed = removed[1:]
+			continue
+		}
+		kept = append(kept, declaration)
+	}
+	tree["Declarations"] = kept
+	_, same := firstTreeDifference("program", tree, actual)
+	return same
+}
@@ -0,0 +1,99 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/turbolent/prettier"
+
+	"github.com/onflow/cadence/runtime/ast"
+)
+
+// brokenArray and brokenDictionary are array and dictionary literals which print their elements one per line,
+// as they are over the thresholds of the options
+type brokenArray struct {
+	*ast.ArrayExpression
+}
+
+func (e *brokenArray) Doc() prettier.Doc {
+	docs := make([]prettier.Doc, len(e.Values))
+	for i, value := range e.Values {
+		docs[i] = value.Doc()
+	}
+	return brokenCollection("[", docs, "]")
+}
+
+type brokenDictionary struct {
+	*ast.DictionaryExpression
+}
+
+func (e *brokenDictionary) Doc() prettier.Doc {
+	docs := make([]prettier.Doc, len(e.Entries))
+	for i, entry := range e.Entries {
+		docs[i] = entry.Doc()
+	}
+	return brokenCollection("{", docs, "}")
+}
+
+func brokenCollection(open string, elements []prettier.Doc, close string) prettier.Doc {
+	return prettier.Concat{
+		prettier.Text(open),
+		prettier.Indent{
+			Doc: prettier.Concat{
+				prettier.HardLine{},
+				prettier.Join(
+					prettier.Concat{prettier.Text(","), prettier.HardLine{}},
+					elements...,
+				),
+			},
+		},
+		prettier.HardLine{},
+		prettier.Text(close),
+	}
+}
+
+// breaksCollection reports if the array or dictionary literal with the number of elements
+// has more of them than Options.CollectionElements, or is wider than Options.CollectionWidth on one line
+func (p printer) breaksCollection(expression ast.Expression, count int) bool {
+	if count == 0 {
+		return false
+	}
+	if p.options.CollectionElements > 0 && count > p.options.CollectionElements {
+		return true
+	}
+	if p.options.CollectionWidth > 0 {
+		width, ok := flatWidth(expression.Doc())
+		return ok && width > p.options.CollectionWidth
+	}
+	return false
+}
+
+// flatWidth returns the width of the document printed on one line,
+// or false if it always has line breaks, e.g. a function body
+func flatWidth(doc prettier.Doc) (int, bool) {
+	var b strings.Builder
+	prettier.Prettier(&b, doc, math.MaxInt32, "")
+	if strings.Contains(b.String(), "\n") {
+		return 0, false
+	}
+	return utf8.RuneCountInString(b.String()), true
+}
@@ -0,0 +1,88 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/parser"
+)
+
+// trailingCommas adds a comma after the last element of the parameter lists and call arguments
+// which the layout broke, i.e. whose closing parenthesis is on its own line.
+// Lists on one line never have one, as the parser does not keep them.
+// Array and dictionary literals are not changed, Cadence does not allow trailing commas in them
+func trailingCommas(code string) (string, error) {
+	program, err := parser.ParseProgram(nil, []byte(code), parser.Config{})
+	if err != nil {
+		return "", err
+	}
+
+	//the offsets after the last elements of the broken lists
+	var offsets []int
+	addList := func(last ast.HasPosition, close ast.Position) {
+		end := last.EndPosition(nil).Offset + 1
+		between := code[end:close.Offset]
+		if strings.Contains(between, "\n") && !strings.HasPrefix(strings.TrimSpace(between), ",") {
+			offsets = append(offsets, end)
+		}
+	}
+	addParameters := func(list *ast.ParameterList) {
+		if list != nil && len(list.Parameters) > 0 {
+			addList(list.Parameters[len(list.Parameters)-1].TypeAnnotation, list.EndPos)
+		}
+	}
+
+	seen := map[ast.Element]bool{}
+	var visit func(element ast.Element)
+	visit = func(element ast.Element) {
+		if element == nil || reflect.ValueOf(element).IsNil() || seen[element] {
+			return
+		}
+		seen[element] = true
+
+		switch element := element.(type) {
+		case *ast.FunctionDeclaration:
+			addParameters(element.ParameterList)
+		case *ast.SpecialFunctionDeclaration:
+			visit(element.FunctionDeclaration)
+		case *ast.FunctionExpression:
+			addParameters(element.ParameterList)
+		case *ast.TransactionDeclaration:
+			addParameters(element.ParameterList)
+			visit(element.Prepare)
+			visit(element.Execute)
+		case *ast.InvocationExpression:
+			if len(element.Arguments) > 0 {
+				addList(element.Arguments[len(element.Arguments)-1].Expression, element.EndPos)
+			}
+		}
+		element.Walk(visit)
+	}
+	visit(program)
+
+	sort.Sort(sort.Reverse(sort.IntSlice(offsets)))
+	for _, offset := range offsets {
+		code = code[:offset] + "," + code[offset:]
+	}
+	return code, nil
+}
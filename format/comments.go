@@ -0,0 +1,352 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/parser"
+	"github.com/onflow/cadence/runtime/parser/lexer"
+)
+
+// commentEdit replaces a comment with the text in the other style
+type commentEdit struct {
+	commentSpan
+	text string
+}
+
+// convertComments converts the comments of the code to the comment style.
+// The text of the comments is kept, only their markers change,
+// unless doc comments are reflowed. Comments in off regions, the header comment,
+// and the markers of broken declarations are kept as written
+func convertComments(code string, options Options) (string, error) {
+	var edits []commentEdit
+	switch options.CommentStyle {
+	case CommentStyleLine:
+		edits = lineCommentEdits(code)
+	case CommentStyleDoc:
+		program, err := parser.ParseProgram(nil, []byte(code), parser.Config{})
+		if err != nil {
+			return "", err
+		}
+		edits = docCommentEdits(code, program)
+	case CommentStyleDocBlock:
+		program, err := parser.ParseProgram(nil, []byte(code), parser.Config{})
+		if err != nil {
+			return "", err
+		}
+		indent := "    "
+		if options.Tabs {
+			indent = "\t"
+		}
+		edits = docBlockEdits(code, program, indent)
+	}
+	//the comments which are kept as written are only lexed if there are edits
+	if len(edits) > 0 {
+		code = applyEdits(code, outsideRegions(edits, keptComments(code)))
+	}
+
+	if options.ReflowDocs {
+		program, err := parser.ParseProgram(nil, []byte(code), parser.Config{})
+		if err != nil {
+			return "", err
+		}
+		code = applyEdits(code, outsideRegions(reflowDocEdits(code, program, options.MaxLineLength), keptComments(code)))
+	}
+	return code, nil
+}
+
+// applyEdits replaces the comments of the edits
+func applyEdits(code string, edits []commentEdit) string {
+	//edit from the end, so the offsets of earlier comments stay valid
+	sort.Slice(edits, func(i, j int) bool {
+		return edits[i].start > edits[j].start
+	})
+	for _, edit := range edits {
+		code = code[:edit.start] + edit.text + code[edit.end:]
+	}
+	return code
+}
+
+// lineCommentEdits converts the block comments on a single line
+// which end their line, so the rest of the line does not become part of the comment
+func lineCommentEdits(code string) []commentEdit {
+	var edits []commentEdit
+
+	tokens := lexer.Lex([]byte(code), nil)
+	defer tokens.Reclaim()
+
+	depth := 0
+	nested := false
+	var start lexer.Token
+	for {
+		token := tokens.Next()
+		switch token.Type {
+		case lexer.TokenEOF:
+			return edits
+
+		case lexer.TokenBlockCommentStart:
+			if depth == 0 {
+				start = token
+				nested = false
+			} else {
+				nested = true
+			}
+			depth++
+
+		case lexer.TokenBlockCommentEnd:
+			depth--
+			if depth > 0 || nested || start.StartPos.Line != token.EndPos.Line {
+				continue
+			}
+			s := commentSpan{start.StartPos.Offset, token.EndPos.Offset + 1}
+			if !endsLine(code, s.end) {
+				continue
+			}
+			text := code[s.start+2 : s.end-2]
+			//doc comments stay doc comments
+			marker := "//"
+			if strings.HasPrefix(text, "*") && text != "*" {
+				marker = "///"
+				text = text[1:]
+			}
+			edits = append(edits, commentEdit{s, lineComment(marker, strings.TrimSpace(text))})
+		}
+	}
+}
+
+// docCommentEdits converts the comments documenting declarations to /// comments
+func docCommentEdits(code string, program *ast.Program) []commentEdit {
+	leading := leadingComments(code)
+
+	var edits []commentEdit
+	var visit func(declarations []ast.Declaration)
+	visit = func(declarations []ast.Declaration) {
+		for _, declaration := range declarations {
+			if declarationKind(declaration) == "" {
+				continue
+			}
+			for _, s := range leading[declaration.StartPosition().Offset] {
+				comment := code[s.start:s.end]
+				if strings.HasPrefix(comment, "///") || !endsLine(code, s.end) {
+					continue
+				}
+
+				var lines []string
+				for _, line := range commentLines(comment) {
+					lines = append(lines, lineComment("///", line))
+				}
+				//block comments often start and end with empty lines
+				for len(lines) > 1 && lines[0] == "///" {
+					lines = lines[1:]
+				}
+				for len(lines) > 1 && lines[len(lines)-1] == "///" {
+					lines = lines[:len(lines)-1]
+				}
+				edits = append(edits, commentEdit{s, strings.Join(lines, "\n"+lineIndent(code, s.start))})
+			}
+			if members := declaration.DeclarationMembers(); members != nil {
+				visit(members.Declarations())
+			}
+		}
+	}
+	visit(program.Declarations())
+
+	return edits
+}
+
+// docBlockEdits converts the line comments documenting declarations to /** */ comments.
+// The lines of a block are indented for the nesting of the declaration,
+// as the formatter keeps them as written
+func docBlockEdits(code string, program *ast.Program, indent string) []commentEdit {
+	leading := leadingComments(code)
+
+	var edits []commentEdit
+	var visit func(declarations []ast.Declaration, depth int)
+	visit = func(declarations []ast.Declaration, depth int) {
+		for _, declaration := range declarations {
+			if declarationKind(declaration) == "" {
+				continue
+			}
+
+			var run []commentSpan
+			flush := func() {
+				if text, ok := blockComment(code, run, strings.Repeat(indent, depth)); ok {
+					edits = append(edits, commentEdit{commentSpan{run[0].start, run[len(run)-1].end}, text})
+				}
+				run = nil
+			}
+			for _, s := range leading[declaration.StartPosition().Offset] {
+				//the marker of a broken declaration ends the comments before it
+				comment := code[s.start:s.end]
+				if !strings.HasPrefix(comment, "//") || strings.HasPrefix(comment, brokenMarker) || !endsLine(code, s.end) {
+					flush()
+					continue
+				}
+				run = append(run, s)
+			}
+			flush()
+
+			if members := declaration.DeclarationMembers(); members != nil {
+				visit(members.Declarations(), depth+1)
+			}
+		}
+	}
+	visit(program.Declarations(), 0)
+
+	return edits
+}
+
+// blockComment is the /** */ comment with the text of the consecutive line comments.
+// Comments containing block comment markers are not converted, as they would end or nest the block
+func blockComment(code string, comments []commentSpan, indent string) (string, bool) {
+	var lines []string
+	for _, s := range comments {
+		line := strings.TrimRight(commentLines(code[s.start:s.end])[0], " \t")
+		if strings.Contains(line, "/*") || strings.Contains(line, "*/") {
+			return "", false
+		}
+		lines = append(lines, line)
+	}
+	for len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	switch len(lines) {
+	case 0:
+		return "", false
+	case 1:
+		return "/** " + lines[0] + " */", true
+	}
+
+	result := []string{"/**"}
+	for _, line := range lines {
+		result = append(result, strings.TrimRight(indent+" * "+line, " "))
+	}
+	result = append(result, indent+" */")
+	return strings.Join(result, "\n"), true
+}
+
+// keptComments are the spans of the code in which comments are not converted
+func keptComments(code string) []commentSpan {
+	spans := append(offRegions(code), brokenMarkers(code)...)
+	if _, s, ok := header(code); ok {
+		spans = append(spans, s)
+	}
+	return spans
+}
+
+// lineComment is a line comment with the text
+func lineComment(marker string, text string) string {
+	if text == "" {
+		return marker
+	}
+	return marker + " " + text
+}
+
+// endsLine reports if only whitespace follows the offset on its line
+func endsLine(code string, offset int) bool {
+	rest, _, _ := strings.Cut(code[offset:], "\n")
+	return strings.TrimSpace(rest) == ""
+}
+
+// lineIndent is the indentation of the line of the offset
+func lineIndent(code string, offset int) string {
+	lineStart := strings.LastIndex(code[:offset], "\n") + 1
+	line := code[lineStart:offset]
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
+
+// declarationHeaders are the spans of the declarations and their members
+// from their start, with the modifiers, to their name
+func declarationHeaders(program *ast.Program) []commentSpan {
+	var headers []commentSpan
+	var visit func(declarations []ast.Declaration)
+	visit = func(declarations []ast.Declaration) {
+		for _, declaration := range declarations {
+			if identifier := declaration.DeclarationIdentifier(); identifier != nil && identifier.Identifier != "" {
+				start := declaration.StartPosition().Offset
+				if end := identifier.Pos.Offset; end > start {
+					headers = append(headers, commentSpan{start: start, end: end})
+				}
+			}
+			if members := declaration.DeclarationMembers(); members != nil {
+				visit(members.Declarations())
+			}
+		}
+	}
+	visit(program.Declarations())
+	return headers
+}
+
+// inSpans reports if the offset is inside one of the spans
+func inSpans(spans []commentSpan, offset int) bool {
+	for _, span := range spans {
+		if span.start <= offset && offset < span.end {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutDeclarations lays out code without declarations, with only comments and pragmas:
+// the lines start at the first column and have no trailing whitespace,
+// and the blank lines are limited to the maximum, without any at the start and the end.
+// The lines of block comments are kept as written
+func withoutDeclarations(code string, t *trivia, maxBlankLines int) string {
+	inside := func(offset int) bool {
+		i := t.at(offset)
+		return i >= 0 && t.comments[i].start < offset
+	}
+
+	var b strings.Builder
+	blankLines := 0
+	for start := 0; start < len(code); {
+		end := strings.IndexByte(code[start:], '\n')
+		if end < 0 {
+			end = len(code)
+		} else {
+			end += start
+		}
+		line := code[start:end]
+		if !inside(start) {
+			line = strings.TrimLeft(line, " \t")
+		}
+		if !inside(end) {
+			line = strings.TrimRight(line, " \t\r")
+		}
+		start = end + 1
+
+		if line == "" && !inside(end) {
+			blankLines++
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString(strings.Repeat("\n", min(blankLines, max(maxBlankLines, 0))+1))
+		}
+		b.WriteString(line)
+		blankLines = 0
+	}
+	return b.String()
+}
@@ -0,0 +1,170 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"strings"
+
+	"github.com/onflow/cadence/runtime/parser"
+	"github.com/onflow/cadence/runtime/parser/lexer"
+)
+
+// Compact returns the code with as little whitespace as possible and without comments,
+// e.g. to embed it where size matters, or to compare code regardless of its formatting.
+// Line breaks end statements, so they are kept, except after opening brackets and commas
+// and before closing brackets and member accesses. Spaces are only kept between tokens
+// which would otherwise merge, or where they change the meaning.
+// The result has the syntax tree of the code, or it is a SafetyError
+func Compact(code string) (string, error) {
+	code, _, err := decode(code)
+	if err != nil {
+		return "", err
+	}
+	directives, rest := splitDirectives(code)
+	rest = strings.ReplaceAll(rest, "\r\n", "\n")
+	if _, err := parser.ParseProgram(nil, []byte(rest), parser.Config{}); err != nil {
+		return "", err
+	}
+
+	tokens := lexer.Lex([]byte(rest), nil)
+	defer tokens.Reclaim()
+
+	var result strings.Builder
+	var previous lexer.Token
+	lineBreak, space := false, false
+	for {
+		token := tokens.Next()
+		switch token.Type {
+		case lexer.TokenEOF:
+			compacted := directives + result.String()
+			if err := sameProgram(rest, result.String()); err != nil {
+				return "", err
+			}
+			return compacted, nil
+
+		case lexer.TokenSpace:
+			text := extractTokenText(rest, token)
+			lineBreak = lineBreak || strings.Contains(text, "\n")
+			space = true
+			continue
+
+		case lexer.TokenLineComment:
+			space = true
+			continue
+
+		case lexer.TokenBlockCommentStart, lexer.TokenBlockCommentContent, lexer.TokenBlockCommentEnd:
+			//a comment spanning lines separates like a line break
+			lineBreak = lineBreak || token.StartPos.Line != token.EndPos.Line
+			space = true
+			continue
+		}
+
+		if result.Len() > 0 {
+			text := extractTokenText(rest, token)
+			switch {
+			case lineBreak && !openingToken(previous.Type) && !closingToken(token.Type):
+				result.WriteByte('\n')
+			case space && (mergingTokens(result.String(), text) || spacedToken(previous.Type, token.Type)):
+				result.WriteByte(' ')
+			}
+		}
+		result.WriteString(extractTokenText(rest, token))
+		previous = token
+		lineBreak, space = false, false
+	}
+}
+
+// openingToken reports if a line break after the token never ends a statement
+func openingToken(tokenType lexer.TokenType) bool {
+	switch tokenType {
+	case lexer.TokenParenOpen, lexer.TokenBracketOpen, lexer.TokenBraceOpen, lexer.TokenComma, lexer.TokenColon:
+		return true
+	}
+	return false
+}
+
+// closingToken reports if a line break before the token never ends a statement
+func closingToken(tokenType lexer.TokenType) bool {
+	switch tokenType {
+	case lexer.TokenParenClose,
+		lexer.TokenBracketClose,
+		lexer.TokenBraceClose,
+		lexer.TokenComma,
+		lexer.TokenDot,
+		lexer.TokenQuestionMarkDot:
+		return true
+	}
+	return false
+}
+
+// spacedToken reports if a space between the tokens changes the meaning of the code,
+// e.g. Int{I} is a restricted type, but the { of Int { starts a body,
+// and f<T>() passes a type argument, but f < T is a comparison
+func spacedToken(previous lexer.TokenType, next lexer.TokenType) bool {
+	switch next {
+	case lexer.TokenBraceOpen, lexer.TokenLess, lexer.TokenParenOpen, lexer.TokenBracketOpen:
+	default:
+		return false
+	}
+	switch previous {
+	case lexer.TokenIdentifier,
+		lexer.TokenParenClose,
+		lexer.TokenBracketClose,
+		lexer.TokenBraceClose,
+		lexer.TokenGreater,
+		lexer.TokenQuestionMark,
+		lexer.TokenExclamationMark:
+		return true
+	}
+	return false
+}
+
+// mergingTokens reports if the text would lex differently after the code without a space between them:
+// words and numbers merge, and so do operators
+func mergingTokens(code string, text string) bool {
+	last, first := code[len(code)-1], text[0]
+	if wordByte(last) && wordByte(first) {
+		return true
+	}
+	return operatorByte(last) && operatorByte(first)
+}
+
+func wordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+func operatorByte(c byte) bool {
+	return strings.IndexByte("+-*/%<>=!&|^?~@#.", c) >= 0
+}
+
+// sameProgram checks that the compacted code has the program of the code
+func sameProgram(code string, compacted string) error {
+	expected, err := programTree(code)
+	if err != nil {
+		return &SafetyError{Check: "ast", Message: err.Error()}
+	}
+	actual, err := programTree(compacted)
+	if err != nil {
+		return &SafetyError{Check: "ast", Message: err.Error()}
+	}
+	if path, same := firstTreeDifference("program", expected, actual); !same {
+		return &SafetyError{Check: "ast", Message: path + " differs"}
+	}
+	return nil
+}
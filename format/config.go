@@ -0,0 +1,192 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Config are the formatting options of configuration files and API requests,
+// by the names of their JSON fields
+type Config struct {
+	MaxLineLength    int    `json:"maxLineLength,omitempty"`
+	Tabs             bool   `json:"tabs,omitempty"`
+	Comments         string `json:"comments,omitempty"`
+	EmptyBodies      string `json:"emptyBodies,omitempty"`
+	CommentStyle     string `json:"commentStyle,omitempty"`
+	ParameterWrap    string `json:"parameterWrap,omitempty"`
+	ReturnType       string `json:"returnType,omitempty"`
+	EnumCases        string `json:"enumCases,omitempty"`
+	OperatorPosition string `json:"operatorPosition,omitempty"`
+	Braces           string `json:"braces,omitempty"`
+	LineEndings      string `json:"lineEndings,omitempty"`
+	GroupFields      bool   `json:"groupFields,omitempty"`
+	AlignComments    bool   `json:"alignComments,omitempty"`
+	AlignValues      bool   `json:"alignValues,omitempty"`
+	AlignParameters  bool   `json:"alignParameters,omitempty"`
+	ReflowDocs       bool   `json:"reflowDocs,omitempty"`
+	// Rules enable or disable layout rules by name, see Rules
+	Rules map[string]bool `json:"rules,omitempty"`
+	// Style enables or disables style rules by name, which are disabled by default, see StyleRules
+	Style map[string]bool `json:"style,omitempty"`
+	// MaxBlankLines is the maximum of consecutive blank lines kept, 1 if not given
+	MaxBlankLines *int `json:"maxBlankLines,omitempty"`
+	SortImports   bool `json:"sortImports,omitempty"`
+	// CleanImports removes duplicate imports, and imports which are never referenced
+	CleanImports bool `json:"cleanImports,omitempty"`
+	// NormalizeEscapes writes the \u{...} escapes of strings in one form
+	NormalizeEscapes bool `json:"normalizeEscapes,omitempty"`
+	TrailingCommas   bool `json:"trailingCommas,omitempty"`
+	// OneLineFunctions keeps functions with a single simple statement on one line if they fit
+	OneLineFunctions bool `json:"oneLineFunctions,omitempty"`
+	// OneLineCases keeps switch cases with a single simple statement on one line if they fit, aligned
+	OneLineCases bool `json:"oneLineCases,omitempty"`
+	// ExactBlankLines separates declarations with exactly one blank line
+	ExactBlankLines bool `json:"exactBlankLines,omitempty"`
+	ReorderMembers  bool `json:"reorderMembers,omitempty"`
+	// KeepBOM keeps the byte order mark at the start of files, which is removed otherwise
+	KeepBOM bool `json:"keepBOM,omitempty"`
+	// CollectionElements and CollectionWidth are the number of elements and the width on one line
+	// above which array and dictionary literals are broken, 0 or not given for no limit
+	CollectionElements int `json:"collectionElements,omitempty"`
+	CollectionWidth    int `json:"collectionWidth,omitempty"`
+	// ImportGroups is the order of the import groups, e.g. ["core", "address", "string"]
+	ImportGroups []string `json:"importGroups,omitempty"`
+	// CoreContracts replace the names of the contracts in the core import group
+	CoreContracts []string `json:"coreContracts,omitempty"`
+}
+
+// Options returns the formatting options of the configuration,
+// with defaults for the ones not given
+func (c Config) Options() (Options, error) {
+	options := DefaultOptions
+	if c.MaxLineLength > 0 {
+		options.MaxLineLength = c.MaxLineLength
+	}
+	options.Tabs = c.Tabs
+	options.GroupFields = c.GroupFields
+	options.AlignComments = c.AlignComments
+	options.AlignValues = c.AlignValues
+	options.AlignParameters = c.AlignParameters
+	options.ReflowDocs = c.ReflowDocs
+	options.SortImports = c.SortImports
+	options.CleanImports = c.CleanImports
+	options.NormalizeEscapes = c.NormalizeEscapes
+	options.TrailingCommas = c.TrailingCommas
+	options.OneLineFunctions = c.OneLineFunctions
+	options.OneLineCases = c.OneLineCases
+	options.ExactBlankLines = c.ExactBlankLines
+	options.ReorderMembers = c.ReorderMembers
+	options.KeepByteOrderMark = c.KeepBOM
+	if c.CollectionElements < 0 || c.CollectionWidth < 0 {
+		return Options{}, fmt.Errorf("invalid collection limits %d and %d", c.CollectionElements, c.CollectionWidth)
+	}
+	options.CollectionElements = c.CollectionElements
+	options.CollectionWidth = c.CollectionWidth
+	if c.MaxBlankLines != nil {
+		if *c.MaxBlankLines < 0 {
+			return Options{}, fmt.Errorf("invalid maximum of blank lines %d", *c.MaxBlankLines)
+		}
+		options.MaxBlankLines = *c.MaxBlankLines
+	}
+
+	var err error
+	options.Comments, err = ParseCommentStrategy(c.Comments)
+	if err != nil {
+		return Options{}, err
+	}
+	options.EmptyBodies, err = ParseEmptyBodyStyle(c.EmptyBodies)
+	if err != nil {
+		return Options{}, err
+	}
+	options.CommentStyle, err = ParseCommentStyle(c.CommentStyle)
+	if err != nil {
+		return Options{}, err
+	}
+	options.ParameterWrap, err = ParseParameterWrapStyle(c.ParameterWrap)
+	if err != nil {
+		return Options{}, err
+	}
+	options.ReturnType, err = ParseReturnTypeStyle(c.ReturnType)
+	if err != nil {
+		return Options{}, err
+	}
+	options.EnumCases, err = ParseEnumCaseStyle(c.EnumCases)
+	if err != nil {
+		return Options{}, err
+	}
+	options.OperatorPosition, err = ParseOperatorPosition(c.OperatorPosition)
+	if err != nil {
+		return Options{}, err
+	}
+	options.Braces, err = ParseBraceStyle(c.Braces)
+	if err != nil {
+		return Options{}, err
+	}
+	options.LineEndings, err = ParseLineEndings(c.LineEndings)
+	if err != nil {
+		return Options{}, err
+	}
+	options.ImportGroups, err = ParseImportGroups(strings.Join(c.ImportGroups, ","))
+	if err != nil {
+		return Options{}, err
+	}
+	options.CoreContracts = strings.Join(c.CoreContracts, ",")
+	options.DisabledRules, err = disabledRules(c.Rules)
+	if err != nil {
+		return Options{}, err
+	}
+	options.StyleRules, err = styleRules(c.Style)
+	if err != nil {
+		return Options{}, err
+	}
+
+	return options, nil
+}
+
+// disabledRules returns the set of rules which are switched off
+func disabledRules(rules map[string]bool) (Rule, error) {
+	var disabled Rule
+	for name, enabled := range rules {
+		rule, err := ParseRule(name)
+		if err != nil {
+			return 0, err
+		}
+		if !enabled {
+			disabled |= rule
+		}
+	}
+	return disabled, nil
+}
+
+// styleRules returns the set of style rules which are switched on
+func styleRules(rules map[string]bool) (StyleRule, error) {
+	var enabled StyleRule
+	for name, on := range rules {
+		rule, err := ParseStyleRule(name)
+		if err != nil {
+			return 0, err
+		}
+		if on {
+			enabled |= rule
+		}
+	}
+	return enabled, nil
+}
@@ -0,0 +1,55 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestConfigOptions(t *testing.T) {
+	var config Config
+	data := `{"maxLineLength": 40, "tabs": true, "rules": {"wrapConformances": false}, "style": {"spaceAfterComment": true}}`
+	if err := json.Unmarshal([]byte(data), &config); err != nil {
+		t.Fatal(err)
+	}
+	options, err := config.Options()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if options.MaxLineLength != 40 || !options.Tabs {
+		t.Errorf("expected 40 columns and tabs, got %d and %v", options.MaxLineLength, options.Tabs)
+	}
+	if options.DisabledRules&RuleWrapConformances == 0 {
+		t.Error("expected wrapConformances to be disabled")
+	}
+	if options.StyleRules&StyleSpaceAfterComment == 0 {
+		t.Error("expected the spaceAfterComment style rule to be enabled")
+	}
+
+	for _, data := range []string{`{"comments": "nope"}`, `{"rules": {"nope": true}}`, `{"style": {"nope": true}}`, `{"collectionWidth": -1}`} {
+		var config Config
+		if err := json.Unmarshal([]byte(data), &config); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := config.Options(); err == nil {
+			t.Errorf("%s: expected an error", data)
+		}
+	}
+}
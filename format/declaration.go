@@ -0,0 +1,102 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"strings"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/turbolent/prettier"
+)
+
+// FormatDeclaration formats a declaration, e.g. one a code generator built or parsed,
+// to be inserted into a file at the indentation level, which is the number of enclosing declarations.
+// Every line but the first starts with the indentation, and the width of the lines includes it.
+// Members like fields and initializers are formatted in a composite, as they are not programs on their own
+func FormatDeclaration(declaration ast.Declaration, level int, options Options) (string, error) {
+	var code strings.Builder
+	if docString := declaration.DeclarationDocString(); docString != "" {
+		for _, line := range strings.Split(docString, "\n") {
+			code.WriteString("///" + line + "\n")
+		}
+	}
+	prettier.Prettier(&code, declaration.Doc(), options.MaxLineLength, "    ")
+
+	wrapperOptions := options
+	wrapperOptions.MaxLineLength -= 4 * max(level, 0)
+
+	header := memberWrapperHeader(declaration)
+	if header == "" {
+		formatted, err := Source(code.String(), wrapperOptions)
+		if err != nil {
+			return "", err
+		}
+		return indentLines(strings.TrimRight(formatted, "\n"), level, options), nil
+	}
+
+	//the member is indented once in the wrapper
+	wrapperOptions.MaxLineLength += 4
+	formatted, err := Source(header+" {\n"+code.String()+"\n}", wrapperOptions)
+	if err != nil {
+		return "", err
+	}
+	unit := indentUnit(options)
+	lines := strings.Split(strings.TrimRight(formatted, "\n"), "\n")
+	//drop the wrapper's header and closing brace
+	lines = lines[1 : len(lines)-1]
+	for i, line := range lines {
+		lines[i] = strings.TrimPrefix(line, unit)
+	}
+	return indentLines(strings.Join(lines, "\n"), level, options), nil
+}
+
+// memberWrapperHeader is the header of the composite the declaration is formatted in,
+// or empty for declarations which are programs on their own
+func memberWrapperHeader(declaration ast.Declaration) string {
+	switch declaration.(type) {
+	case *ast.FieldDeclaration, *ast.SpecialFunctionDeclaration:
+		return "contract _"
+	case *ast.EnumCaseDeclaration:
+		return "enum _: UInt8"
+	}
+	return ""
+}
+
+// indentLines indents the lines but the first by the level, leaving blank lines empty
+func indentLines(code string, level int, options Options) string {
+	if level <= 0 {
+		return code
+	}
+	indentation := strings.Repeat(indentUnit(options), level)
+	lines := strings.Split(code, "\n")
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) != "" {
+			lines[i] = indentation + lines[i]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// indentUnit is the indentation of one level
+func indentUnit(options Options) string {
+	if options.Tabs {
+		return "\t"
+	}
+	return "    "
+}
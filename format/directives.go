@@ -0,0 +1,77 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"strings"
+)
+
+// directivePrefix starts the lines at the start of a file which other tools read,
+// like the shebang line #!/usr/bin/env flow, and which do not parse as Cadence
+const directivePrefix = "#!"
+
+// splitDirectives returns the directive lines at the start of the code, and the rest of the code
+func splitDirectives(code string) (string, string) {
+	rest := code
+	for strings.HasPrefix(rest, directivePrefix) {
+		end := strings.IndexByte(rest, '\n') + 1
+		if end == 0 {
+			end = len(rest)
+		}
+		rest = rest[end:]
+	}
+	return code[:len(code)-len(rest)], rest
+}
+
+// blankDirectives returns the code in which the directive lines are blank,
+// so the positions of the rest of the code stay the same
+func blankDirectives(directives string, rest string) string {
+	return strings.Repeat("\n", strings.Count(directives, "\n")) + rest
+}
+
+// withDirectives returns the formatted code after the directive lines,
+// separated by a blank line if they were in the rest of the code
+func withDirectives(directives string, rest string, formatted string) string {
+	if directives == "" {
+		return formatted
+	}
+	//the blank directive lines may be kept before comments
+	formatted = strings.TrimLeft(formatted, "\n")
+	if formatted == "" {
+		return strings.TrimRight(directives, "\r\n")
+	}
+	return directivesBefore(directives, rest) + formatted
+}
+
+// directivesBefore returns the directive lines to write before the formatted code
+func directivesBefore(directives string, rest string) string {
+	if !strings.HasSuffix(directives, "\n") {
+		directives += "\n"
+	}
+	if line, _, _ := strings.Cut(rest, "\n"); strings.TrimSpace(line) == "" {
+		directives += "\n"
+	}
+	return directives
+}
+
+// withoutPreamble returns the code without its byte order mark, and with blank directive lines,
+// which the checks ignore
+func withoutPreamble(code string) string {
+	return blankDirectives(splitDirectives(strings.TrimPrefix(code, byteOrderMark)))
+}
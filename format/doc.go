@@ -0,0 +1,902 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"bytes"
+	"regexp"
+
+	"github.com/turbolent/prettier"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/common"
+)
+
+// printer builds the layout document of a program.
+// It follows the documents of the Cadence AST, but prints declarations itself,
+// so their layout can be configured.
+// Everything else, e.g. statements and expressions, is printed by the AST.
+//
+// The comments before declarations and closing braces are attached to the declarations,
+// if the printer has the trivia of the code.
+// Trailing comments and the comments inside of statements are kept by the token pass of Source
+type printer struct {
+	options Options
+	code    []byte
+	trivia  *trivia
+}
+
+var programSeparatorDoc = prettier.Concat{
+	prettier.HardLine{},
+	prettier.HardLine{},
+}
+
+func (p printer) program(program *ast.Program) prettier.Doc {
+	declarations := program.Declarations()
+
+	from := 0
+	docs := make([]prettier.Doc, 0, len(declarations))
+	for i, declaration := range declarations {
+		start := declaration.StartPosition().Offset
+		comments := p.trivia.take(from, start)
+		var separator prettier.Doc
+		if i > 0 {
+			//imports of the same group and consecutive pragmas are not separated
+			separator = prettier.HardLine{}
+			if !p.sameImportGroup(declarations[i-1], declaration) && !p.pragmaRun(declarations[i-1], declaration) {
+				//the separator is the first blank line
+				separator = prettier.Concat{
+					programSeparatorDoc,
+					p.separatorBlankLines(from, comments, start),
+				}
+			}
+		}
+		docs = append(
+			docs,
+			prettier.Concat{
+				separator,
+				p.leadingComments(comments, start),
+				p.declaration(declaration),
+			},
+		)
+		from = declaration.EndPosition(nil).Offset + 1
+	}
+
+	return prettier.Concat{
+		prettier.Concat(docs),
+		p.danglingComments(p.trivia.take(from, len(p.code)), from),
+	}
+}
+
+// sameImportGroup reports if both declarations are imports of the same group,
+// when imports are grouped
+func (p printer) sameImportGroup(previous, next ast.Declaration) bool {
+	if p.options.ImportGroups == "" {
+		return false
+	}
+	previousImport, ok := previous.(*ast.ImportDeclaration)
+	if !ok {
+		return false
+	}
+	nextImport, ok := next.(*ast.ImportDeclaration)
+	if !ok {
+		return false
+	}
+	return p.options.importGroup(previousImport) == p.options.importGroup(nextImport)
+}
+
+// pragmaRun reports if both declarations are pragmas which the code does not separate with a blank line
+func (p printer) pragmaRun(previous, next ast.Declaration) bool {
+	_, ok := previous.(*ast.PragmaDeclaration)
+	if !ok {
+		return false
+	}
+	_, ok = next.(*ast.PragmaDeclaration)
+	if !ok {
+		return false
+	}
+	return !p.keepsBlankLine(p.code[previous.EndPosition(nil).Offset+1 : next.StartPosition().Offset])
+}
+
+// leadingComments prints the comments before the code at the offset,
+// each on its own line, except block comments on the line of the code.
+// Blank lines after the comments are kept
+func (p printer) leadingComments(comments []comment, next int) prettier.Doc {
+	var doc prettier.Concat
+	for i, c := range comments {
+		end := next
+		if i+1 < len(comments) {
+			end = comments[i+1].start
+		}
+		between := p.code[c.end:end]
+
+		doc = append(doc, prettier.Text(c.text))
+		switch {
+		case !bytes.Contains(between, []byte("\n")):
+			doc = append(doc, prettier.Space)
+		case p.keepsBlankLine(between):
+			doc = append(doc, prettier.HardLine{}, prettier.HardLine{})
+			doc = append(doc, p.blankLines(c.end, nil, end, 1))
+		default:
+			doc = append(doc, prettier.HardLine{})
+		}
+	}
+	return doc
+}
+
+// danglingComments prints the comments after the last declaration of a body or the program,
+// each on its own line. A blank line before a comment is kept
+func (p printer) danglingComments(comments []comment, from int) prettier.Doc {
+	var doc prettier.Concat
+	for _, c := range comments {
+		doc = append(doc, p.blankLines(from, nil, c.start, 0))
+		doc = append(doc, prettier.HardLine{}, prettier.Text(c.text))
+		from = c.end
+	}
+	return doc
+}
+
+func (p printer) declaration(declaration ast.Declaration) prettier.Doc {
+	return p.writtenAccess(declaration, p.declarationDoc(declaration))
+}
+
+// writtenAccessPattern matches the access modifiers which have a keyword of their own,
+// at the start of a declaration
+var writtenAccessPattern = regexp.MustCompile(`^access\s*\(\s*(all|self)\s*\)`)
+
+// writtenAccess prints access(all) and access(self) as written, instead of their keywords pub and priv,
+// so formatting keeps code migrated to Cadence 1.0
+func (p printer) writtenAccess(declaration ast.Declaration, doc prettier.Doc) prettier.Doc {
+	access := declaration.DeclarationAccess()
+	if access != ast.AccessPublic && access != ast.AccessPrivate {
+		return doc
+	}
+	match := writtenAccessPattern.FindSubmatch(p.code[declaration.StartPosition().Offset:])
+	if match == nil {
+		return doc
+	}
+	doc, _ = replaceText(doc, access.Keyword(), "access("+string(match[1])+")")
+	return doc
+}
+
+// replaceText replaces the first text of the document which is old
+func replaceText(doc prettier.Doc, old string, new string) (prettier.Doc, bool) {
+	switch doc := doc.(type) {
+	case prettier.Text:
+		if string(doc) == old {
+			return prettier.Text(new), true
+		}
+	case prettier.Concat:
+		for i, child := range doc {
+			if replaced, ok := replaceText(child, old, new); ok {
+				concat := make(prettier.Concat, len(doc))
+				copy(concat, doc)
+				concat[i] = replaced
+				return concat, true
+			}
+		}
+	case prettier.Group:
+		if replaced, ok := replaceText(doc.Doc, old, new); ok {
+			doc.Doc = replaced
+			return doc, true
+		}
+	case prettier.Indent:
+		if replaced, ok := replaceText(doc.Doc, old, new); ok {
+			doc.Doc = replaced
+			return doc, true
+		}
+	}
+	return doc, false
+}
+
+func (p printer) declarationDoc(declaration ast.Declaration) prettier.Doc {
+	switch declaration := declaration.(type) {
+	case *ast.CompositeDeclaration:
+		if declaration.CompositeKind == common.CompositeKindEvent {
+			return p.event(declaration)
+		}
+		header := ast.HasPosition(declaration.Identifier)
+		if len(declaration.Conformances) > 0 {
+			header = declaration.Conformances[len(declaration.Conformances)-1]
+		}
+		return p.composite(
+			declaration.Access,
+			declaration.CompositeKind,
+			false,
+			declaration.Identifier.Identifier,
+			declaration.Conformances,
+			declaration.Members,
+			p.braces(header, declaration),
+		)
+
+	case *ast.InterfaceDeclaration:
+		return p.composite(
+			declaration.Access,
+			declaration.CompositeKind,
+			true,
+			declaration.Identifier.Identifier,
+			nil,
+			declaration.Members,
+			p.braces(declaration.Identifier, declaration),
+		)
+
+	case *ast.AttachmentDeclaration:
+		return p.attachment(declaration)
+
+	case *ast.FunctionDeclaration:
+		return p.function(declaration, true, declaration.Identifier.Identifier)
+
+	case *ast.SpecialFunctionDeclaration:
+		return p.function(declaration.FunctionDeclaration, false, declaration.Kind.Keywords())
+
+	case *ast.TransactionDeclaration:
+		return p.transaction(declaration)
+
+	case *ast.VariableDeclaration:
+		//declarations are not replaced like statements
+		if declaration.SecondValue == nil && hasBrokenArguments(declaration.Value) {
+			return variableWithValue(declaration)
+		}
+	}
+
+	return declaration.Doc()
+}
+
+var conformanceSeparatorDoc prettier.Doc = prettier.Concat{
+	prettier.Text(","),
+	prettier.Line{},
+}
+
+// conformances prints the conformances and the members,
+// which move to the next line if the conformances are wrapped
+func (p printer) conformances(conformances []*ast.NominalType, members *ast.Members, body braces) prettier.Doc {
+	beforeBrace := p.beforeBrace(len(members.Declarations()) == 0)
+	if len(conformances) == 0 {
+		return prettier.Concat{
+			beforeBrace,
+			p.members(members, body),
+		}
+	}
+
+	if !p.options.enabled(RuleWrapConformances) {
+		doc := prettier.Concat{
+			prettier.Text(": "),
+		}
+		for i, conformance := range conformances {
+			if i > 0 {
+				doc = append(doc, prettier.Text(", "))
+			}
+			doc = append(doc, conformance.Doc())
+		}
+		return append(doc, beforeBrace, p.members(members, body))
+	}
+
+	conformancesDoc := prettier.Concat{
+		prettier.Line{},
+	}
+	for i, conformance := range conformances {
+		if i > 0 {
+			conformancesDoc = append(conformancesDoc, conformanceSeparatorDoc)
+		}
+		conformancesDoc = append(conformancesDoc, conformance.Doc())
+	}
+	//the members move to the next line if the conformances are wrapped, or always in the next-line style
+	var line prettier.Doc = prettier.Line{}
+	if _, ok := beforeBrace.(prettier.HardLine); ok {
+		line = beforeBrace
+	}
+	conformancesDoc = append(
+		conformancesDoc,
+		prettier.Dedent{
+			Doc: line,
+		},
+	)
+
+	//the members are outside of the group, so it does not flatten their groups
+	return prettier.Concat{
+		prettier.Text(":"),
+		prettier.Group{
+			Doc: prettier.Indent{
+				Doc: conformancesDoc,
+			},
+		},
+		p.members(members, body),
+	}
+}
+
+// event prints the parameters of the event like the ones of function signatures
+func (p printer) event(declaration *ast.CompositeDeclaration) prettier.Doc {
+	initializers := declaration.Members.Initializers()
+	if len(initializers) != 1 {
+		return declaration.Doc()
+	}
+	parameters := initializers[0].FunctionDeclaration.ParameterList
+
+	var doc prettier.Concat
+	if declaration.Access != ast.AccessNotSpecified {
+		doc = append(doc, prettier.Text(declaration.Access.Keyword()), prettier.Space)
+	}
+	doc = append(
+		doc,
+		prettier.Text(declaration.CompositeKind.Keyword()),
+		prettier.Space,
+		prettier.Text(declaration.Identifier.Identifier),
+	)
+
+	if commented := p.commentedParameters(parameters); commented != nil {
+		return append(doc, commented)
+	}
+	if packed := p.packedParameters(parameters); packed != nil {
+		return append(doc, packed)
+	}
+	return append(doc, parameters.Doc())
+}
+
+// beforeBrace returns the separator between a header and the opening brace of its body,
+// which is a line break in the next-line brace style, unless the body is empty
+func (p printer) beforeBrace(empty bool) prettier.Doc {
+	if p.options.Braces == BracesNextLine && !empty {
+		return prettier.HardLine{}
+	}
+	return prettier.Space
+}
+
+func (p printer) composite(
+	access ast.Access,
+	kind common.CompositeKind,
+	isInterface bool,
+	identifier string,
+	conformances []*ast.NominalType,
+	members *ast.Members,
+	body braces,
+) prettier.Doc {
+
+	var doc prettier.Concat
+
+	if access != ast.AccessNotSpecified {
+		doc = append(doc, prettier.Text(access.Keyword()), prettier.Space)
+	}
+
+	doc = append(doc, prettier.Text(kind.Keyword()), prettier.Space)
+
+	if isInterface {
+		doc = append(doc, prettier.Text("interface "))
+	}
+
+	return append(
+		doc,
+		prettier.Text(identifier),
+		p.conformances(conformances, members, body),
+	)
+}
+
+func (p printer) attachment(declaration *ast.AttachmentDeclaration) prettier.Doc {
+	var doc prettier.Concat
+
+	header := ast.HasPosition(declaration.BaseType)
+	if len(declaration.Conformances) > 0 {
+		header = declaration.Conformances[len(declaration.Conformances)-1]
+	}
+
+	if declaration.Access != ast.AccessNotSpecified {
+		doc = append(doc, prettier.Text(declaration.Access.Keyword()), prettier.Space)
+	}
+
+	return append(
+		doc,
+		prettier.Text("attachment "),
+		prettier.Text(declaration.Identifier.Identifier),
+		prettier.Text(" for "),
+		declaration.BaseType.Doc(),
+		p.conformances(declaration.Conformances, declaration.Members, p.braces(header, declaration)),
+	)
+}
+
+// braces are the offsets of the braces of a body,
+// or -1 if the printer has no trivia to find them
+type braces struct {
+	open  int
+	close int
+}
+
+// braces returns the braces of the body of the declaration, which follows the header
+func (p printer) braces(header ast.HasPosition, declaration ast.Declaration) braces {
+	return braces{
+		open:  p.trivia.openBrace(header.EndPosition(nil).Offset + 1),
+		close: declaration.EndPosition(nil).Offset,
+	}
+}
+
+func (p printer) members(members *ast.Members, body braces) prettier.Doc {
+	declarations := members.Declarations()
+
+	from := body.open
+	if from >= 0 {
+		from++
+	}
+
+	var doc prettier.Concat
+	for i, declaration := range declarations {
+		start := declaration.StartPosition().Offset
+		comments := p.trivia.take(from, start)
+		//members are separated by blank lines, except fields of the same group and enum cases
+		separated := i > 0 && p.separated(declarations[i-1], declaration)
+		if separated {
+			doc = append(
+				doc,
+				prettier.HardLine{},
+				p.separatorBlankLines(declarations[i-1].EndPosition(nil).Offset+1, comments, start),
+			)
+		}
+		if i > 0 && !separated && p.packsEnumCases(declarations[i-1], declaration) {
+			//each separator breaks on its own
+			doc = append(doc, prettier.Text(";"), prettier.Group{Doc: prettier.Line{}}, p.declaration(declaration))
+		} else {
+			doc = append(
+				doc,
+				prettier.HardLine{},
+				p.leadingComments(comments, start),
+				p.declaration(declaration),
+			)
+		}
+		if from >= 0 {
+			from = declaration.EndPosition(nil).Offset + 1
+		}
+	}
+
+	dangling := p.trivia.take(from, body.close)
+	if len(declarations) == 0 {
+		if len(dangling) == 0 {
+			return prettier.Text("{}")
+		}
+		//bodies with only comments on the line of the braces stay on that line
+		if !bytes.Contains(p.code[body.open:body.close], []byte("\n")) {
+			inline := prettier.Concat{prettier.Text("{")}
+			for _, c := range dangling {
+				inline = append(inline, prettier.Space, prettier.Text(c.text))
+			}
+			return append(inline, prettier.Text(" }"))
+		}
+		//no blank line at the start of the body
+		from = dangling[0].start
+	}
+	doc = append(doc, p.danglingComments(dangling, from))
+
+	return prettier.Concat{
+		prettier.Text("{"),
+		prettier.Indent{
+			Doc: doc,
+		},
+		prettier.HardLine{},
+		prettier.Text("}"),
+	}
+}
+
+// separated reports if a blank line separates the members
+func (p printer) separated(previous, next ast.Declaration) bool {
+	//cases of enums stay together, unless the code separates them
+	if isEnumCase(previous) && isEnumCase(next) {
+		between := p.code[previous.EndPosition(nil).Offset+1 : next.StartPosition().Offset]
+		return !p.options.ExactBlankLines && p.keepsBlankLine(between)
+	}
+	if !p.options.enabled(RuleSeparateMembers) && !p.options.ExactBlankLines {
+		between := p.code[previous.EndPosition(nil).Offset+1 : next.StartPosition().Offset]
+		return p.keepsBlankLine(between)
+	}
+	return !p.sameFieldGroup(previous, next)
+}
+
+var blankLinePattern = regexp.MustCompile(`\n[ \t\r]*\n`)
+
+// blankLines returns the blank lines of the code from the offset to the first comment or the next code,
+// up to the maximum which is kept, without the ones the layout already has
+func (p printer) blankLines(from int, comments []comment, next int, layout int) prettier.Doc {
+	if from < 0 {
+		return nil
+	}
+	if len(comments) > 0 {
+		next = comments[0].start
+	}
+	var doc prettier.Concat
+	for i := layout; i < min(trailingBlankLines(string(p.code[from:next])), p.options.MaxBlankLines); i++ {
+		doc = append(doc, prettier.HardLine{})
+	}
+	return doc
+}
+
+// separatorBlankLines returns the blank lines of the code between declarations,
+// after the one the layout already has, which are not kept with exact blank lines
+func (p printer) separatorBlankLines(from int, comments []comment, next int) prettier.Doc {
+	if p.options.ExactBlankLines {
+		return prettier.Concat{}
+	}
+	return p.blankLines(from, comments, next, 1)
+}
+
+// keepsBlankLine reports if the code has a blank line which is kept
+func (p printer) keepsBlankLine(code []byte) bool {
+	return p.options.MaxBlankLines > 0 && blankLinePattern.Match(code)
+}
+
+// sameFieldGroup reports if both declarations are fields of the same access group,
+// when fields are grouped
+func (p printer) sameFieldGroup(previous, next ast.Declaration) bool {
+	if !p.options.GroupFields {
+		return false
+	}
+	previousField, ok := previous.(*ast.FieldDeclaration)
+	if !ok {
+		return false
+	}
+	nextField, ok := next.(*ast.FieldDeclaration)
+	if !ok {
+		return false
+	}
+	return accessGroup(previousField.Access) == accessGroup(nextField.Access)
+}
+
+// accessGroup groups the access levels into public, contract, and self access
+func accessGroup(access ast.Access) int {
+	switch access {
+	case ast.AccessPublic, ast.AccessPublicSettable:
+		return 0
+	case ast.AccessContract, ast.AccessAccount:
+		return 1
+	case ast.AccessPrivate:
+		return 2
+	}
+	return 3
+}
+
+func (p printer) function(declaration *ast.FunctionDeclaration, includeKeyword bool, identifier string) prettier.Doc {
+	doc := ast.FunctionDocument(
+		declaration.Access,
+		declaration.IsStatic(),
+		declaration.IsNative(),
+		includeKeyword,
+		identifier,
+		declaration.TypeParameterList,
+		declaration.ParameterList,
+		declaration.ReturnTypeAnnotation,
+		declaration.FunctionBlock,
+	)
+	concat := doc.(prettier.Concat)
+
+	commented := p.commentedParameters(declaration.ParameterList)
+	if commented != nil {
+		concat = withParameters(concat, commented, declaration.TypeParameterList, declaration.FunctionBlock)
+	} else if packed := p.packedParameters(declaration.ParameterList); packed != nil {
+		concat = withParameters(concat, packed, declaration.TypeParameterList, declaration.FunctionBlock)
+	}
+
+	returnType := declaration.ReturnTypeAnnotation
+	if p.options.ReturnType == ReturnTypeOwnLine && returnType != nil && !ast.IsEmptyType(returnType.Type) {
+		concat = withReturnTypeLine(concat, declaration.FunctionBlock, commented != nil)
+	}
+
+	if !declaration.FunctionBlock.IsEmpty() {
+		//the AST prints the body last, after a space
+		concat[len(concat)-2] = p.beforeBrace(false)
+		concat[len(concat)-1] = p.functionBlock(declaration.FunctionBlock)
+		if p.options.OneLineFunctions {
+			if doc := p.oneLineBody(declaration.FunctionBlock); doc != nil {
+				concat[len(concat)-2] = prettier.Space
+				concat[len(concat)-1] = doc
+			}
+		}
+		return concat
+	}
+
+	//the AST prints " {}" for empty bodies, and also for missing ones
+	concat = concat[:len(concat)-1]
+
+	//functions without a body, e.g. interface requirements, must stay without one
+	if declaration.FunctionBlock == nil {
+		return concat
+	}
+
+	return append(concat, prettier.Space, p.emptyBody(declaration.FunctionBlock))
+}
+
+// functionBlock prints the block like the AST, but with its conditions broken independently
+func (p printer) functionBlock(block *ast.FunctionBlock) prettier.Doc {
+	var body prettier.Concat
+	if doc := p.conditions(block.PreConditions, "pre"); doc != nil {
+		body = append(body, prettier.HardLine{}, doc)
+	}
+	if doc := p.conditions(block.PostConditions, "post"); doc != nil {
+		body = append(body, prettier.HardLine{}, doc)
+	}
+	return prettier.Concat{
+		prettier.Text("{"),
+		prettier.Indent{
+			Doc: append(body, ast.StatementsDoc(block.Block.Statements)),
+		},
+		prettier.HardLine{},
+		prettier.Text("}"),
+	}
+}
+
+// oneLineBody prints a body of a single simple statement, without conditions and comments,
+// on the line of the signature if it fits, or returns nil for other bodies
+func (p printer) oneLineBody(block *ast.FunctionBlock) prettier.Doc {
+	if block.PreConditions != nil && len(*block.PreConditions) > 0 ||
+		block.PostConditions != nil && len(*block.PostConditions) > 0 {
+		return nil
+	}
+	statements := block.Block.Statements
+	if len(statements) != 1 {
+		return nil
+	}
+
+	if p.trivia.contains(block.StartPosition().Offset, block.EndPosition(nil).Offset) {
+		return nil
+	}
+
+	statement := statements[0].Doc()
+	//statements with blocks, e.g. if statements, always have line breaks
+	if _, ok := flatWidth(statement); !ok {
+		return nil
+	}
+
+	return prettier.Group{
+		Doc: prettier.Concat{
+			prettier.Text("{"),
+			prettier.Indent{
+				Doc: prettier.Concat{
+					prettier.Line{},
+					statement,
+				},
+			},
+			prettier.Line{},
+			prettier.Text("}"),
+		},
+	}
+}
+
+// conditions prints each condition on its own line, with its message after it if it fits,
+// or else on the next line. A single condition without comments stays on the line of the keyword if it fits
+func (p printer) conditions(conditions *ast.Conditions, keyword string) prettier.Doc {
+	if conditions.IsEmpty() {
+		return nil
+	}
+
+	docs := make([]prettier.Doc, 0, len(*conditions))
+	for _, c := range *conditions {
+		docs = append(docs, condition(c))
+	}
+
+	if len(docs) == 1 && p.compactCondition((*conditions)[0]) {
+		return prettier.Group{
+			Doc: prettier.Concat{
+				prettier.Text(keyword + " {"),
+				prettier.Indent{
+					Doc: prettier.Concat{
+						prettier.Line{},
+						docs[0],
+					},
+				},
+				prettier.Line{},
+				prettier.Text("}"),
+			},
+		}
+	}
+
+	//not in a group, which would print each condition on one line, however long,
+	//as the group always fits up to its first line break
+	return prettier.Concat{
+		prettier.Text(keyword + " {"),
+		prettier.Indent{
+			Doc: prettier.Concat{
+				prettier.HardLine{},
+				prettier.Join(prettier.HardLine{}, docs...),
+			},
+		},
+		prettier.HardLine{},
+		prettier.Text("}"),
+	}
+}
+
+// condition prints the test and the message of the condition, which moves to the next line if it does not fit
+func condition(condition *ast.Condition) prettier.Doc {
+	doc := condition.Test.Doc()
+	if condition.Message == nil {
+		return doc
+	}
+	return prettier.Group{
+		Doc: prettier.Concat{
+			doc,
+			prettier.Text(":"),
+			prettier.Indent{
+				Doc: prettier.Concat{
+					prettier.Line{},
+					condition.Message.Doc(),
+				},
+			},
+		},
+	}
+}
+
+// compactCondition reports if the block of the condition may be printed on one line:
+// the condition always prints on one line, and there are no comments in the block
+func (p printer) compactCondition(condition *ast.Condition) bool {
+	doc := condition.Test.Doc()
+	end := condition.Test.EndPosition(nil).Offset + 1
+	if condition.Message != nil {
+		doc = prettier.Concat{doc, condition.Message.Doc()}
+		end = condition.Message.EndPosition(nil).Offset + 1
+	}
+	if _, ok := flatWidth(doc); !ok {
+		return false
+	}
+
+	start := condition.Test.StartPosition().Offset
+	open := bytes.LastIndexByte(p.code[:start], '{')
+	close := bytes.IndexByte(p.code[end:], '}')
+	if open < 0 || close < 0 {
+		return false
+	}
+	return !p.trivia.contains(open, end+close)
+}
+
+func (p printer) emptyBody(block *ast.FunctionBlock) prettier.Doc {
+	style := p.options.EmptyBodies
+
+	start := block.StartPosition()
+	end := block.EndPosition(nil)
+	inside := p.code[start.Offset+1 : end.Offset]
+
+	if !p.options.enabled(RuleCollapseEmptyBodies) {
+		switch {
+		case start.Line != end.Line:
+			style = EmptyBodiesSplit
+		case len(inside) > 0:
+			style = EmptyBodiesSpaced
+		default:
+			style = EmptyBodiesCompact
+		}
+	}
+
+	//bodies only containing comments keep the comments inside
+	if len(bytes.TrimSpace(inside)) > 0 {
+		if start.Line == end.Line {
+			style = EmptyBodiesSpaced
+		} else {
+			style = EmptyBodiesSplit
+		}
+	}
+
+	switch style {
+	case EmptyBodiesSpaced:
+		return prettier.Text("{ }")
+	case EmptyBodiesSplit:
+		return prettier.Concat{
+			prettier.Text("{"),
+			prettier.HardLine{},
+			prettier.Text("}"),
+		}
+	}
+	return prettier.Text("{}")
+}
+
+func (p printer) transaction(declaration *ast.TransactionDeclaration) prettier.Doc {
+	var contents []prettier.Doc
+
+	header := declaration.StartPosition().Offset + len("transaction")
+	if !declaration.ParameterList.IsEmpty() {
+		header = declaration.ParameterList.EndPos.Offset + 1
+	}
+	from := p.trivia.openBrace(header)
+	if from >= 0 {
+		from++
+	}
+
+	//comments are only attached after declarations,
+	//the ones after conditions are kept by the token pass
+	addDeclaration := func(declaration ast.Declaration, doc prettier.Doc) {
+		start := declaration.StartPosition().Offset
+		contents = append(
+			contents,
+			prettier.Concat{
+				prettier.HardLine{},
+				p.leadingComments(p.trivia.take(from, start), start),
+				doc,
+			},
+		)
+		if from >= 0 {
+			from = declaration.EndPosition(nil).Offset + 1
+		}
+	}
+	//fields stay together, unless the code separates them with a blank line,
+	//and the phases are separated from the fields and from each other
+	addField := func(field *ast.FieldDeclaration, previous ast.Declaration) {
+		if previous == nil || from < 0 {
+			addDeclaration(field, field.Doc())
+			return
+		}
+		between := p.code[previous.EndPosition(nil).Offset+1 : field.StartPosition().Offset]
+		if p.keepsBlankLine(between) {
+			addDeclaration(field, field.Doc())
+			return
+		}
+		start := field.StartPosition().Offset
+		contents[len(contents)-1] = prettier.Concat{
+			contents[len(contents)-1],
+			prettier.HardLine{},
+			p.leadingComments(p.trivia.take(from, start), start),
+			field.Doc(),
+		}
+		from = field.EndPosition(nil).Offset + 1
+	}
+	addConditions := func(doc prettier.Doc) {
+		contents = append(
+			contents,
+			prettier.Concat{
+				prettier.HardLine{},
+				doc,
+			},
+		)
+		from = -1
+	}
+
+	for i, field := range declaration.Fields {
+		var previous ast.Declaration
+		if i > 0 {
+			previous = declaration.Fields[i-1]
+		}
+		addField(field, previous)
+	}
+	if declaration.Prepare != nil {
+		addDeclaration(declaration.Prepare, p.declaration(declaration.Prepare))
+	}
+	if conditionsDoc := p.conditions(declaration.PreConditions, "pre"); conditionsDoc != nil {
+		addConditions(conditionsDoc)
+	}
+	if declaration.Execute != nil {
+		addDeclaration(declaration.Execute, p.declaration(declaration.Execute))
+	}
+	if conditionsDoc := p.conditions(declaration.PostConditions, "post"); conditionsDoc != nil {
+		addConditions(conditionsDoc)
+	}
+
+	doc := prettier.Concat{
+		prettier.Text("transaction"),
+	}
+	if parameters := p.commentedParameters(declaration.ParameterList); parameters != nil {
+		doc = append(doc, parameters)
+	} else if parameters := p.packedParameters(declaration.ParameterList); parameters != nil {
+		doc = append(doc, parameters)
+	} else if !declaration.ParameterList.IsEmpty() {
+		doc = append(doc, declaration.ParameterList.Doc())
+	}
+
+	return append(
+		doc,
+		p.beforeBrace(false),
+		prettier.Text("{"),
+		prettier.Indent{
+			Doc: prettier.Concat{
+				prettier.Join(prettier.HardLine{}, contents...),
+				p.danglingComments(p.trivia.take(from, declaration.EndPosition(nil).Offset), from),
+			},
+		},
+		prettier.HardLine{},
+		prettier.Text("}"),
+	)
+}
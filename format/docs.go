@@ -0,0 +1,260 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"strings"
+
+	"github.com/turbolent/prettier"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/parser"
+	"github.com/onflow/cadence/runtime/parser/lexer"
+)
+
+// Declaration is the documentation of a declaration:
+// its formatted signature and the comment anchored to it
+type Declaration struct {
+	// Kind is the keyword of the declaration, e.g. "resource interface" or "fun"
+	Kind      string        `json:"kind"`
+	Name      string        `json:"name"`
+	Signature string        `json:"signature"`
+	Doc       string        `json:"doc,omitempty"`
+	Members   []Declaration `json:"members,omitempty"`
+}
+
+// Docs returns the documentation of the declarations of the code,
+// and their members. Private members are not part of the API, so they are left out.
+// The comments on the lines directly before a declaration are its documentation,
+// the same comments formatting keeps with it
+func Docs(code string, options Options) ([]Declaration, error) {
+	program, err := parser.ParseProgram(nil, []byte(code), parser.Config{})
+	if err != nil {
+		return nil, err
+	}
+
+	d := docs{
+		printer:  printer{options: options, code: []byte(code)},
+		code:     code,
+		comments: leadingComments(code),
+	}
+	return d.declarations(program.Declarations()), nil
+}
+
+type docs struct {
+	printer
+	code string
+	// comments are the leading comments, by the offset of the token they precede
+	comments map[int][]commentSpan
+}
+
+func (d docs) declarations(declarations []ast.Declaration) []Declaration {
+	var result []Declaration
+	for _, declaration := range declarations {
+		if declaration.DeclarationAccess() == ast.AccessPrivate {
+			continue
+		}
+		kind := declarationKind(declaration)
+		if kind == "" {
+			continue
+		}
+
+		documented := Declaration{
+			Kind:      kind,
+			Name:      declaration.DeclarationIdentifier().Identifier,
+			Signature: d.signature(declaration),
+			Doc:       d.doc(declaration),
+		}
+		if special, ok := declaration.(*ast.SpecialFunctionDeclaration); ok {
+			documented.Name = special.Kind.Keywords()
+		}
+		//the parameters of events are their only member, and are part of the signature
+		if members := declaration.DeclarationMembers(); members != nil && !isEvent(declaration) {
+			documented.Members = d.declarations(members.Declarations())
+		}
+		result = append(result, documented)
+	}
+	return result
+}
+
+// declarationKind is the keyword of an API declaration,
+// or empty for other declarations, e.g. imports and transactions
+func declarationKind(declaration ast.Declaration) string {
+	switch declaration := declaration.(type) {
+	case *ast.CompositeDeclaration,
+		*ast.InterfaceDeclaration,
+		*ast.AttachmentDeclaration,
+		*ast.FunctionDeclaration,
+		*ast.SpecialFunctionDeclaration,
+		*ast.EnumCaseDeclaration:
+
+		return declaration.DeclarationKind().Keywords()
+
+	case *ast.FieldDeclaration:
+		return declaration.VariableKind.Keyword()
+	}
+	return ""
+}
+
+// signature is the formatted declaration without its body
+func (d docs) signature(declaration ast.Declaration) string {
+	var doc prettier.Doc
+	switch declaration := declaration.(type) {
+	case *ast.FunctionDeclaration:
+		withoutBody := *declaration
+		withoutBody.FunctionBlock = nil
+		doc = d.function(&withoutBody, true, declaration.Identifier.Identifier)
+
+	case *ast.SpecialFunctionDeclaration:
+		withoutBody := *declaration.FunctionDeclaration
+		withoutBody.FunctionBlock = nil
+		doc = d.function(&withoutBody, false, declaration.Kind.Keywords())
+
+	case *ast.CompositeDeclaration, *ast.InterfaceDeclaration, *ast.AttachmentDeclaration:
+		if isEvent(declaration) {
+			doc = d.declaration(declaration)
+			break
+		}
+		doc = d.declaration(withoutMembers(declaration))
+
+	default:
+		doc = d.declaration(declaration)
+	}
+
+	var b strings.Builder
+	prettier.Prettier(&b, doc, d.options.MaxLineLength, "    ")
+	//composites are printed with empty members, which are not part of the signature
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(b.String()), "{}"))
+}
+
+func isEvent(declaration ast.Declaration) bool {
+	composite, ok := declaration.(*ast.CompositeDeclaration)
+	return ok && composite.CompositeKind == common.CompositeKindEvent
+}
+
+func withoutMembers(declaration ast.Declaration) ast.Declaration {
+	members := ast.NewMembers(nil, nil)
+	switch declaration := declaration.(type) {
+	case *ast.CompositeDeclaration:
+		withoutMembers := *declaration
+		withoutMembers.Members = members
+		return &withoutMembers
+	case *ast.InterfaceDeclaration:
+		withoutMembers := *declaration
+		withoutMembers.Members = members
+		return &withoutMembers
+	case *ast.AttachmentDeclaration:
+		withoutMembers := *declaration
+		withoutMembers.Members = members
+		return &withoutMembers
+	}
+	return declaration
+}
+
+// doc is the text of the comments anchored to the declaration, without the comment markers
+func (d docs) doc(declaration ast.Declaration) string {
+	var lines []string
+	for _, s := range d.comments[declaration.StartPosition().Offset] {
+		lines = append(lines, commentLines(d.code[s.start:s.end])...)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// commentSpan is a range of the code, from start to end exclusive
+type commentSpan struct {
+	start int
+	end   int
+}
+
+// leadingComments finds the comments on their own lines directly before a token.
+// Comments after code on the same line belong to that code,
+// and a blank line separates comments from the code after them
+func leadingComments(code string) map[int][]commentSpan {
+	result := map[int][]commentSpan{}
+
+	tokens := lexer.Lex([]byte(code), nil)
+	defer tokens.Reclaim()
+
+	var pending []commentSpan
+	codeLine := 0
+	depth := 0
+	blockStart := lexer.Token{}
+
+	for {
+		token := tokens.Next()
+
+		switch token.Type {
+		case lexer.TokenSpace:
+			if depth == 0 && strings.Count(extractTokenText(code, token), "\n") > 1 {
+				pending = nil
+			}
+
+		case lexer.TokenLineComment:
+			if token.StartPos.Line != codeLine {
+				pending = append(pending, commentSpan{token.StartPos.Offset, token.EndPos.Offset + 1})
+			}
+
+		case lexer.TokenBlockCommentStart:
+			if depth == 0 {
+				blockStart = token
+			}
+			depth++
+
+		case lexer.TokenBlockCommentContent:
+
+		case lexer.TokenBlockCommentEnd:
+			depth--
+			if depth == 0 && blockStart.StartPos.Line != codeLine {
+				pending = append(pending, commentSpan{blockStart.StartPos.Offset, token.EndPos.Offset + 1})
+			}
+
+		default:
+			if len(pending) > 0 {
+				result[token.StartPos.Offset] = pending
+				pending = nil
+			}
+			codeLine = token.EndPos.Line
+		}
+
+		if token.Is(lexer.TokenEOF) {
+			return result
+		}
+	}
+}
+
+// commentLines is the text of a comment, without the comment markers
+func commentLines(comment string) []string {
+	if text, ok := strings.CutPrefix(comment, "//"); ok {
+		text = strings.TrimPrefix(text, "/")
+		return []string{strings.TrimPrefix(text, " ")}
+	}
+
+	text := strings.TrimPrefix(comment, "/*")
+	text = strings.TrimPrefix(text, "*")
+	text = strings.TrimSuffix(text, "*/")
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		line = strings.TrimPrefix(line, "*")
+		lines[i] = strings.TrimPrefix(line, " ")
+	}
+	return lines
+}
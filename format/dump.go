@@ -0,0 +1,74 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/turbolent/prettier"
+)
+
+// DocForOptions returns the layout of the Cadence code, without its comments,
+// as it is rendered with the options
+func DocForOptions(src []byte, options Options) (prettier.Doc, error) {
+	return docFor(src, options)
+}
+
+// DumpDoc prints the document as a tree, one node per line,
+// for debugging surprising line breaks
+func DumpDoc(doc prettier.Doc) string {
+	var b strings.Builder
+	dumpDoc(&b, doc, 0)
+	return b.String()
+}
+
+func dumpDoc(b *strings.Builder, doc prettier.Doc, depth int) {
+	b.WriteString(strings.Repeat("  ", depth))
+
+	switch doc := doc.(type) {
+	case prettier.Text:
+		b.WriteString("Text " + strconv.Quote(string(doc)) + "\n")
+	case prettier.Line:
+		b.WriteString("Line\n")
+	case prettier.SoftLine:
+		b.WriteString("SoftLine\n")
+	case prettier.HardLine:
+		b.WriteString("HardLine\n")
+	case prettier.Indent:
+		b.WriteString("Indent\n")
+		dumpDoc(b, doc.Doc, depth+1)
+	case prettier.Dedent:
+		b.WriteString("Dedent\n")
+		dumpDoc(b, doc.Doc, depth+1)
+	case prettier.Group:
+		b.WriteString("Group\n")
+		dumpDoc(b, doc.Doc, depth+1)
+	case prettier.Concat:
+		b.WriteString("Concat\n")
+		for _, child := range doc {
+			dumpDoc(b, child, depth+1)
+		}
+	case nil:
+		b.WriteString("nil\n")
+	default:
+		fmt.Fprintf(b, "%T\n", doc)
+	}
+}
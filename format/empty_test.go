@@ -0,0 +1,92 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"testing"
+)
+
+func TestSourceWithoutDeclarations(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		expected string
+	}{
+		{
+			name:     "empty",
+			code:     "",
+			expected: "",
+		},
+		{
+			name:     "blank lines",
+			code:     "\n\n  \n",
+			expected: "",
+		},
+		{
+			name:     "license header",
+			code:     "/*\n * Copyright  A\n *\n *   Licensed\n */\n",
+			expected: "/*\n * Copyright  A\n *\n *   Licensed\n */",
+		},
+		{
+			name:     "line comments",
+			code:     "// a  b\n\n//   c\n\n\n",
+			expected: "// a  b\n\n//   c",
+		},
+		{
+			name:     "more blank lines than kept",
+			code:     "// a\n\n\n\n// b\n",
+			expected: "// a\n\n// b",
+		},
+		{
+			name:     "pragma",
+			code:     "#allowAccountLinking\n",
+			expected: "#allowAccountLinking",
+		},
+		{
+			name:     "pragmas and comments",
+			code:     "// a\n#allowAccountLinking\n\n#b   // c\n\n",
+			expected: "// a\n#allowAccountLinking\n\n#b   // c",
+		},
+		{
+			name:     "trailing whitespace",
+			code:     "// a  \n\t\n",
+			expected: "// a",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			formatted, err := Source(test.code, DefaultOptions)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if formatted != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, formatted)
+			}
+			//formatting again keeps the result
+			again, err := Source(formatted, DefaultOptions)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if again != formatted {
+				t.Errorf("formatting again changed %q to %q", formatted, again)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// byteOrderMark is written by some editors at the start of UTF-8 files
+const byteOrderMark = "\uFEFF"
+
+// decode returns the code without its byte order mark, and if it had one.
+// Code which is not UTF-8 is rejected, as the offsets of its tokens would be garbled
+func decode(code string) (string, bool, error) {
+	code, marked := strings.CutPrefix(code, byteOrderMark)
+	if utf8.ValidString(code) {
+		return code, marked, nil
+	}
+
+	offset := 0
+	for {
+		r, size := utf8.DecodeRuneInString(code[offset:])
+		if r == utf8.RuneError && size == 1 {
+			break
+		}
+		offset += size
+	}
+	line := strings.Count(code[:offset], "\n") + 1
+	column := utf8.RuneCountInString(code[strings.LastIndex(code[:offset], "\n")+1:offset]) + 1
+	return "", false, fmt.Errorf("%d:%d: invalid UTF-8, the code must be encoded in UTF-8", line, column)
+}
+
+// encode returns the formatted code with the byte order mark, if the code had one and it is kept
+func encode(formatted string, marked bool, options Options) string {
+	if marked && options.KeepByteOrderMark {
+		return byteOrderMark + formatted
+	}
+	return formatted
+}
@@ -0,0 +1,80 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/parser"
+)
+
+// packsEnumCases reports if the enum cases are printed on the same line when they fit,
+// if the options pack enum cases and no comment is between them,
+// as the merge of the tokens would move trailing comments after the next case
+func (p printer) packsEnumCases(previous, next ast.Declaration) bool {
+	if p.options.EnumCases != EnumCasesPacked || !isEnumCase(previous) || !isEnumCase(next) {
+		return false
+	}
+	between := p.code[previous.EndPosition(nil).Offset+1 : next.StartPosition().Offset]
+	return !bytes.Contains(between, []byte("/"))
+}
+
+func isEnumCase(declaration ast.Declaration) bool {
+	_, ok := declaration.(*ast.EnumCaseDeclaration)
+	return ok
+}
+
+// trimEnumCaseSemicolons removes the semicolons the packed layout put after the enum cases
+// which end their line, as they only separate the cases of a line
+func trimEnumCaseSemicolons(code string) (string, error) {
+	program, err := parser.ParseProgram(nil, []byte(code), parser.Config{})
+	if err != nil {
+		return "", err
+	}
+
+	var semicolons []int
+	seen := map[ast.Element]bool{}
+	var visit func(element ast.Element)
+	visit = func(element ast.Element) {
+		if element == nil || reflect.ValueOf(element).IsNil() || seen[element] {
+			return
+		}
+		seen[element] = true
+
+		if enumCase, ok := element.(*ast.EnumCaseDeclaration); ok {
+			end := enumCase.EndPosition(nil).Offset + 1
+			if strings.HasPrefix(code[end:], ";\n") || strings.HasPrefix(code[end:], ";\r\n") {
+				semicolons = append(semicolons, end)
+			}
+		}
+		element.Walk(visit)
+	}
+	visit(program)
+
+	sort.Ints(semicolons)
+	//edit from the end, so the offsets of earlier cases stay valid
+	for i := len(semicolons) - 1; i >= 0; i-- {
+		code = code[:semicolons[i]] + code[semicolons[i]+1:]
+	}
+	return code, nil
+}
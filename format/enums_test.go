@@ -0,0 +1,61 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package format
+
+import (
+	"testing"
+)
+
+func TestSourceEnumCaseComments(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		expected string
+	}{
+		{
+			name:     "trailing comments",
+			code:     "pub enum E: UInt8 {\n    pub case a // a\n    pub case b // b\n}\n",
+			expected: "pub enum E: UInt8 {\n    pub case a // a\n    pub case b // b\n}",
+		},
+		{
+			name:     "trailing comment after access(all)",
+			code:     "pub enum E: UInt8 {\n    access(all) case red // r\n    pub case green\n}\n",
+			expected: "pub enum E: UInt8 {\n    access(all) case red // r\n    pub case green\n}",
+		},
+		{
+			name:     "leading comment after access(self)",
+			code:     "pub enum E: UInt8 {\n    access(self) case red\n    // green\n    pub case green\n}\n",
+			expected: "pub enum E: UInt8 {\n    access(self) case red\n    // green\n    pub case green\n}",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			formatted, err := Source(test.code, DefaultOptions)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if formatted != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, formatted)
+			}
+			if err := Verify(test.code, formatted, DefaultOptions); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
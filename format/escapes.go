@@ -0,0 +1,114 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"strings"
+
+	"github.com/onflow/cadence/runtime/parser/lexer"
+)
+
+// normalizeEscapes writes the \u{...} escapes of string literals in one form,
+// with upper case hexadecimal digits and without leading zeros, e.g. \u{00e9} becomes \u{E9}.
+// Other escapes have only one form. The result is only used if it has the program of the code,
+// so the values of the strings never change
+func normalizeEscapes(code string) string {
+	tokens := lexer.Lex([]byte(code), nil)
+	defer tokens.Reclaim()
+
+	var edits []commentEdit
+	for {
+		token := tokens.Next()
+		if token.Is(lexer.TokenEOF) {
+			break
+		}
+		if !token.Is(lexer.TokenString) {
+			continue
+		}
+		text := extractTokenText(code, token)
+		if normalized := normalizedEscapes(text); normalized != text {
+			s := commentSpan{token.StartPos.Offset, token.EndPos.Offset + 1}
+			edits = append(edits, commentEdit{s, normalized})
+		}
+	}
+	if len(edits) == 0 {
+		return code
+	}
+
+	normalized := applyEdits(code, outsideRegions(edits, keptComments(code)))
+	expected, err := programTree(code)
+	if err != nil {
+		return code
+	}
+	actual, err := programTree(normalized)
+	if err != nil {
+		return code
+	}
+	if _, same := firstTreeDifference("program", expected, actual); !same {
+		return code
+	}
+	return normalized
+}
+
+// normalizedEscapes returns the string literal with its \u{...} escapes normalized
+func normalizedEscapes(literal string) string {
+	var result strings.Builder
+	for i := 0; i < len(literal); i++ {
+		if literal[i] != '\\' || i+1 == len(literal) {
+			result.WriteByte(literal[i])
+			continue
+		}
+		//other escapes are kept, including escaped backslashes before a u
+		if literal[i+1] != 'u' || !strings.HasPrefix(literal[i+2:], "{") {
+			result.WriteString(literal[i : i+2])
+			i++
+			continue
+		}
+		end := strings.IndexByte(literal[i:], '}')
+		if end < 0 {
+			result.WriteString(literal[i:])
+			break
+		}
+		digits := literal[i+3 : i+end]
+		if !hexDigits(digits) {
+			result.WriteString(literal[i : i+end+1])
+		} else {
+			digits = strings.TrimLeft(strings.ToUpper(digits), "0")
+			if digits == "" {
+				digits = "0"
+			}
+			result.WriteString(`\u{` + digits + `}`)
+		}
+		i += end
+	}
+	return result.String()
+}
+
+// hexDigits reports if the text is a non-empty run of hexadecimal digits
+func hexDigits(text string) bool {
+	if text == "" {
+		return false
+	}
+	for _, c := range text {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
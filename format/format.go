@@ -0,0 +1,774 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package format formats Cadence code
+package format
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/turbolent/prettier"
+	"golang.org/x/exp/slices"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/parser"
+	"github.com/onflow/cadence/runtime/parser/lexer"
+)
+
+// DocFor returns the layout of the Cadence code, without its comments,
+// for rendering it with prettier.Prettier or other printers
+func DocFor(src []byte) (prettier.Doc, error) {
+	return docFor(src, DefaultOptions)
+}
+
+func docFor(src []byte, options Options) (prettier.Doc, error) {
+	program, err := parser.ParseProgram(nil, src, parser.Config{})
+	if err != nil {
+		return nil, err
+	}
+	return printer{options: options, code: src}.program(program), nil
+}
+
+// Layout returns the formatted code without its comments,
+// the first stage of Source
+func Layout(code string, options Options) (string, error) {
+	return pretty(code, options)
+}
+
+func pretty(code string, options Options) (string, error) {
+	program, err := parser.ParseProgram(nil, []byte(code), parser.Config{})
+	if err != nil {
+		return "", err
+	}
+	return render(program, []byte(code), options, nil), nil
+}
+
+// render prints the program, with the comments the printer attaches if there are trivia
+func render(program *ast.Program, src []byte, options Options, trivia *trivia) string {
+	p := printer{options: options, code: src, trivia: trivia}
+	p.breakLists(program.Declarations())
+	doc := p.program(program)
+
+	var b strings.Builder
+	prettier.Prettier(&b, doc, options.MaxLineLength, "    ")
+	return b.String()
+}
+
+// parseTokens parses the program of the tokens, and reverts the tokens to where they were,
+// so the code is not lexed again
+func parseTokens(tokens lexer.TokenStream) (*ast.Program, error) {
+	cursor := tokens.Cursor()
+	defer tokens.Revert(cursor)
+	return parser.ParseProgramFromTokenStream(nil, tokens, parser.Config{})
+}
+
+func extractTokenText(text string, token lexer.Token) string {
+	return text[token.StartPos.Offset : token.EndPos.Offset+1]
+}
+
+// rewrite applies the changes of the options to the code which come before the layout:
+// converting the comments, normalizing escapes, removing unused imports, sorting and grouping the imports, and reordering the members
+func rewrite(code string, options Options) (string, error) {
+	code, err := convertComments(code, options)
+	if err != nil {
+		return "", err
+	}
+	code = applyStyleRules(code, options.StyleRules, true)
+	if options.NormalizeEscapes {
+		code = normalizeEscapes(code)
+	}
+	if options.CleanImports {
+		code, err = cleanImports(code)
+		if err != nil {
+			return "", err
+		}
+	}
+	if options.SortImports || options.ImportGroups != "" {
+		code, err = arrangeImports(code, options)
+		if err != nil {
+			return "", err
+		}
+	}
+	if options.ReorderMembers {
+		return reorderMembers(code)
+	}
+	return code, nil
+}
+
+// Source formats the Cadence code, keeping its comments
+func Source(existingCode string, options Options) (string, error) {
+	return SourceContext(context.Background(), existingCode, options)
+}
+
+// SourceContext is Source, which stops with the error of the context when it is done.
+// The context is checked between the stages of formatting.
+// The header comment of the code overrides the options, see FileOptions
+func SourceContext(ctx context.Context, existingCode string, options Options) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	existingCode, marked, err := decode(existingCode)
+	if err != nil {
+		return "", err
+	}
+
+	options, err = FileOptions(existingCode, options)
+	if err != nil {
+		return "", err
+	}
+
+	//the directive lines are kept as written, and blank while the rest is formatted,
+	//so the positions of errors stay the same
+	directives, rest := splitDirectives(existingCode)
+
+	//the code is formatted with \n line endings, which are converted at the end
+	result, err := source(ctx, strings.ReplaceAll(blankDirectives(directives, rest), "\r\n", "\n"), options)
+	if err != nil {
+		return "", err
+	}
+	result = withDirectives(directives, rest, result)
+	return encode(options.LineEndings.Convert(result, existingCode), marked, options), nil
+}
+
+// source formats the code with \n line endings
+func source(ctx context.Context, existingCode string, options Options) (string, error) {
+	var result strings.Builder
+	if err := sourceTo(ctx, &result, existingCode, options); err != nil {
+		return "", err
+	}
+	return result.String(), nil
+}
+
+// sourceTo formats the code with \n line endings, writing the result to dst as it is merged.
+// Nothing is written before the code is parsed and laid out
+func sourceTo(ctx context.Context, dst io.Writer, existingCode string, options Options) error {
+	existingCode, err := rewrite(existingCode, options)
+	if err != nil {
+		return err
+	}
+
+	//the code is lexed once, for the regions, the parser, the comments and the merge
+	src := []byte(existingCode)
+	oldTokens := lexer.Lex(src, nil)
+	defer oldTokens.Reclaim()
+
+	code, regions := disableRegions(existingCode, oldTokens)
+	if regions != nil {
+		//the markers of the regions change the code
+		src = []byte(code)
+		oldTokens = lexer.Lex(src, nil)
+		defer oldTokens.Reclaim()
+	}
+
+	program, err := parseTokens(oldTokens)
+	if err != nil {
+		if regions == nil {
+			return err
+		}
+		//report the errors of the code as written, at their positions
+		if _, err := parser.ParseProgram(nil, []byte(existingCode), parser.Config{}); err != nil {
+			return err
+		}
+		return errOffRegion
+	}
+	existingCode = code
+
+	//files without declarations, e.g. with only a license header or pragmas,
+	//are kept as they are, only their whitespace is normalized
+	if len(program.Declarations()) == len(program.PragmaDeclarations()) {
+		restored, err := regions.restore(withoutDeclarations(existingCode, triviaOf(oldTokens), options.MaxBlankLines))
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(dst, restored)
+		return err
+	}
+
+	trivia := triviaOf(oldTokens)
+	prettyCode := render(program, src, options, trivia)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	newTokens := lexer.Lex([]byte(prettyCode), nil)
+	defer newTokens.Reclaim()
+
+	oldToken := lexer.Token{Type: lexer.TokenSpace}
+	newToken := lexer.Token{Type: lexer.TokenSpace}
+
+	ignoredTokenTypes := []lexer.TokenType{
+		lexer.TokenParenClose,
+		lexer.TokenParenOpen,
+		lexer.TokenBracketOpen,
+		lexer.TokenBracketClose,
+	}
+
+	commentTokenTypes := []lexer.TokenType{
+		lexer.TokenLineComment,
+		lexer.TokenBlockCommentStart,
+		lexer.TokenBlockCommentContent,
+		lexer.TokenBlockCommentEnd,
+	}
+
+	//the result is written as it is merged, unless it is rearranged as a whole afterwards
+	streamed := regions == nil && !options.TrailingCommas && !options.AlignValues && !options.AlignParameters && !options.AlignComments &&
+		!options.OneLineCases && options.EnumCases != EnumCasesPacked && options.StyleRules == 0
+	result := &lineWriter{dst: dst, indenter: indenter{tabs: options.Tabs}}
+	var buffer strings.Builder
+	if !streamed {
+		buffer.Grow(len(prettyCode))
+		result = &lineWriter{dst: &buffer}
+	}
+	comment := strings.Builder{}
+	trailing := strings.Builder{}
+	//line comments between the modifiers and the name of a declaration keep their line break,
+	//so they stay in the header instead of moving after the declaration
+	headers := declarationHeaders(program)
+	headerComment := false
+
+	//the pending spaces of the pretty code are consecutive, so they are a slice of it
+	spaceStart, spaceEnd := 0, 0
+	spaces := func() string {
+		return prettyCode[spaceStart:spaceEnd]
+	}
+	resetSpaces := func() {
+		spaceStart, spaceEnd = 0, 0
+	}
+
+	//keptBlankLines is the number of consecutive blank lines of the code which are kept
+	keptBlankLines := func(blankLines int) int {
+		return min(blankLines, options.MaxBlankLines)
+	}
+
+	//writeBlankLines writes the blank lines of the code before the comment at the offset
+	//which are not already pending
+	writeBlankLines := func(offset int) {
+		pending := spaces()
+		if comment.Len() > 0 {
+			pending = comment.String()
+		}
+		missing := keptBlankLines(blankLinesBefore(existingCode, offset)) - trailingBlankLines(pending)
+		comment.WriteString(strings.Repeat("\n", max(missing, 0)))
+	}
+
+	//writeTrailing writes the pending trailing comments before the next line break.
+	//Strict comments must stay after the code they followed, so they break the line
+	writeTrailing := func(spacesString string) string {
+		if trailing.Len() == 0 {
+			return spacesString
+		}
+		if !strings.Contains(spacesString, "\n") && options.Comments != CommentsStrict && !headerComment {
+			return spacesString
+		}
+
+		result.WriteString(trailing.String())
+		trailing.Reset()
+		inHeader := headerComment
+		headerComment = false
+
+		if strings.Contains(spacesString, "\n") {
+			return spacesString
+		}
+		//the rest of the header continues at the indentation of the declaration
+		if inHeader {
+			return "\n" + lineIndent(result.lastLine(), len(result.lastLine()))
+		}
+		return "\n" + continuationIndent(result.lastLine())
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if !newToken.Is(lexer.TokenEOF) {
+			newToken = newTokens.Next()
+		}
+
+		if newToken.Is(lexer.TokenSpace) {
+			if spaceStart == spaceEnd {
+				spaceStart = newToken.StartPos.Offset
+			}
+			spaceEnd = newToken.EndPos.Offset + 1
+			continue
+		}
+
+		isComment := slices.Contains(commentTokenTypes, newToken.Type)
+
+		if slices.Contains(ignoredTokenTypes, newToken.Type) {
+			result.WriteString(writeTrailing(spaces()))
+			result.WriteString(extractTokenText(prettyCode, newToken))
+			resetSpaces()
+			continue
+		}
+
+		if !oldToken.Is(lexer.TokenEOF) {
+			for {
+				oldToken = oldTokens.Next()
+
+				//the layout writes access(all) and access(self) as their keywords,
+				//so the modifier is a single token, or the cases of enums would not match again
+				if oldToken.Is(lexer.TokenIdentifier) && newToken.Is(lexer.TokenIdentifier) &&
+					extractTokenText(existingCode, oldToken) == "access" {
+
+					accessModifier(existingCode, oldTokens, extractTokenText(prettyCode, newToken))
+				}
+
+				//comments attached by the printer are already part of the pretty code,
+				//they only match the comments of the pretty code
+				if slices.Contains(commentTokenTypes, oldToken.Type) && trivia.attachedAt(oldToken.StartPos.Offset) {
+					if isComment && oldToken.Type == newToken.Type {
+						break
+					}
+					continue
+				}
+
+				//check only comments
+				if oldToken.Is(lexer.TokenLineComment) || oldToken.Is(lexer.TokenBlockCommentStart) {
+
+					switch oldToken.Type {
+					case lexer.TokenLineComment:
+						isTrailing := false
+
+						//check trailing
+						offset := oldToken.StartPos.Offset
+						oldLine := strings.Trim(existingCode[trivia.lineStart(offset):offset], " \t")
+						if len(oldLine) > 0 {
+							isTrailing = true
+						}
+
+						//keep the blank lines before it
+						if !isTrailing {
+							writeBlankLines(oldToken.StartPos.Offset)
+						}
+
+						//without the trailing whitespace the lexer includes
+						commentString := strings.TrimRight(extractTokenText(existingCode, oldToken), " \t")
+
+						//trailing comment, with space before it
+						if isTrailing {
+							trailing.WriteString(" ")
+							trailing.WriteString(commentString)
+							headerComment = headerComment || inSpans(headers, offset)
+							break
+						}
+
+						//add comment, and the blank lines after it
+						comment.WriteString(commentString)
+						comment.WriteString("\n")
+						comment.WriteString(strings.Repeat("\n", keptBlankLines(blankLinesAfter(existingCode, oldToken.EndPos.Offset+1))))
+
+					case lexer.TokenBlockCommentStart:
+						//nested block comments are written as a whole, at their outermost start
+						blockComment, ok := trivia.commentStartingAt(oldToken.StartPos.Offset)
+						if !ok {
+							break
+						}
+
+						//trailing block comment, after code and only followed by comments
+						if isTrailingBlock(existingCode, blockComment) {
+							trailing.WriteString(" ")
+							trailing.WriteString(blockComment.text)
+							break
+						}
+
+						if blockComment.ownLine {
+							writeBlankLines(blockComment.start)
+						}
+						comment.WriteString(blockComment.text)
+
+						//multiline block comments always end their line
+						if strings.Contains(blockComment.text, "\n") ||
+							(blockComment.ownLine && endsLine(existingCode, blockComment.end)) {
+
+							comment.WriteString("\n")
+							comment.WriteString(strings.Repeat("\n", keptBlankLines(blankLinesAfter(existingCode, blockComment.end))))
+						}
+					}
+
+				}
+
+				if (oldToken.Type == newToken.Type && !isComment) || oldToken.Is(lexer.TokenEOF) {
+					break
+				}
+			}
+		}
+
+		//attached comments are written as printed, after the pending trailing comments
+		if isComment {
+			result.WriteString(writeTrailing(spaces()))
+			result.WriteString(extractTokenText(prettyCode, newToken))
+			resetSpaces()
+			continue
+		}
+
+		if oldToken.Is(lexer.TokenEOF) && newToken.Is(lexer.TokenEOF) {
+			//add remaining comments and finish
+			result.WriteString(trailing.String())
+			result.WriteString(comment.String())
+			break
+		}
+
+		//add spaces without existing indent in case we put comment
+		spacesString := writeTrailing(spaces())
+		//keep the blank lines of the code before elements the layout puts on a new line,
+		//except at the start and end of blocks
+		if comment.Len() == 0 && strings.Contains(spacesString, "\n") &&
+			!newToken.Is(lexer.TokenBraceClose) &&
+			result.last != '{' {
+
+			missing := keptBlankLines(blankLinesBefore(existingCode, oldToken.StartPos.Offset)) - trailingBlankLines(spacesString)
+			//the layout already has the exact blank line between declarations
+			if options.ExactBlankLines && trailingBlankLines(spacesString) > 0 {
+				missing = 0
+			}
+			spacesString = strings.Repeat("\n", max(missing, 0)) + spacesString
+		}
+		existingIndent := len(spacesString) - (strings.LastIndex(spacesString, "\n") + 1)
+		result.WriteString(strings.TrimRight(spacesString, " "))
+		resetSpaces()
+
+		commentString := comment.String()
+		inline := result.inline()
+
+		if comment.Len() > 0 && !strings.Contains(commentString, "\n") {
+			//inline block comment, keep it before the element
+			result.WriteString(strings.Repeat(" ", existingIndent))
+			result.WriteString(commentString)
+			result.WriteString(" ")
+			comment.Reset()
+		} else if comment.Len() > 0 && inline && options.Comments == CommentsStrict {
+			//comment on its own line, keep it there by breaking the line
+			padding := continuationIndent(result.lastLine())
+			result.WriteString("\n")
+			result.WriteString(indentComments(padding, strings.TrimLeft(commentString, "\n")))
+			result.WriteString(padding)
+			comment.Reset()
+		} else if comment.Len() > 0 && inline {
+			//comment on its own line, move it above the line the element ended up on
+			line := result.takeLine()
+			padding := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+			result.WriteString(indentComments(padding, strings.TrimLeft(commentString, "\n")))
+			result.WriteString(line)
+			result.WriteString(strings.Repeat(" ", existingIndent))
+			comment.Reset()
+		} else if comment.Len() > 0 {
+			//add existing comment (leading), pad to next element
+			padding := strings.Repeat(" ", newToken.StartPosition().Column)
+			commentPadding := padding
+			//comments before a closing brace are inside the block
+			if newToken.Is(lexer.TokenBraceClose) {
+				commentPadding += "    "
+			}
+			result.WriteString(indentComments(commentPadding, comment.String()))
+			result.WriteString(padding)
+			comment.Reset()
+		} else {
+			result.WriteString(strings.Repeat(" ", existingIndent))
+		}
+
+		//add prettified code
+		result.WriteString(tokenText(existingCode, oldToken, prettyCode, newToken))
+
+	}
+
+	if result.close(); streamed {
+		return result.err
+	}
+
+	formatted := buffer.String()
+	//after placing the comments, which are matched to the tokens of the layout
+	if options.TrailingCommas {
+		formatted, err = trailingCommas(formatted)
+		if err != nil {
+			return err
+		}
+	}
+	if options.AlignValues {
+		formatted, err = alignValues(formatted, options.MaxLineLength)
+		if err != nil {
+			return err
+		}
+	}
+	if options.AlignParameters {
+		formatted, err = alignParameters(formatted, options.MaxLineLength)
+		if err != nil {
+			return err
+		}
+	}
+	if options.OneLineCases {
+		formatted, err = alignCases(formatted, options.MaxLineLength)
+		if err != nil {
+			return err
+		}
+	}
+	if options.EnumCases == EnumCasesPacked {
+		formatted, err = trimEnumCaseSemicolons(formatted)
+		if err != nil {
+			return err
+		}
+	}
+	if options.Tabs {
+		formatted = useTabs(formatted)
+	}
+	//after using tabs, so the padding stays spaces
+	if options.AlignComments {
+		formatted = alignComments(formatted)
+	}
+	formatted = applyStyleRules(formatted, options.StyleRules, false)
+	restored, err := regions.restore(formatted)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(dst, restored)
+	return err
+}
+
+// useTabs indents the lines with tabs instead of four spaces
+func useTabs(code string) string {
+	tabbedResult := &strings.Builder{}
+	t := indenter{tabs: true}
+	for _, line := range strings.Split(code, "\n") {
+		tabbedResult.WriteString(t.line(line))
+		tabbedResult.WriteString("\n")
+	}
+	return tabbedResult.String()
+}
+
+// indenter writes the indentation of lines, with tabs instead of four spaces if tabs are used,
+// and none on blank lines, which the layout indents like the code around them.
+// Only the indentation changes, never the text of strings and comments,
+// and lines starting inside block comments are kept as they are,
+// so it follows the comments from line to line
+type indenter struct {
+	tabs bool
+	//comments is the depth of the nested block comments at the end of the last line
+	comments int
+}
+
+// line returns the line with its indentation
+func (t *indenter) line(line string) string {
+	if t.comments == 0 {
+		switch {
+		case strings.TrimLeft(line, " \t") == "":
+			line = ""
+		case t.tabs:
+			line = tabbed(line)
+		}
+	}
+	t.scan(line)
+	return line
+}
+
+// scan follows the block comments of the line.
+// Strings and line comments end on their line
+func (t *indenter) scan(line string) {
+	for i := 0; i < len(line); i++ {
+		switch {
+		case strings.HasPrefix(line[i:], "*/") && t.comments > 0:
+			t.comments--
+			i++
+		case strings.HasPrefix(line[i:], "/*"):
+			t.comments++
+			i++
+		case t.comments > 0:
+		case strings.HasPrefix(line[i:], "//"):
+			return
+		case line[i] == '"':
+			for i++; i < len(line) && line[i] != '"'; i++ {
+				if line[i] == '\\' {
+					i++
+				}
+			}
+		}
+	}
+}
+
+// tabbed replaces the runs of four spaces of the indentation of the line with tabs
+func tabbed(line string) string {
+	text := strings.TrimLeft(line, " \t")
+	indentation := line[:len(line)-len(text)]
+	return strings.ReplaceAll(indentation, "    ", "\t") + text
+}
+
+// blankLinesBefore is the number of blank lines directly before the line of the offset
+func blankLinesBefore(code string, offset int) int {
+	start := len(strings.TrimRight(code[:offset], " \t\r\n"))
+	return max(strings.Count(code[start:offset], "\n")-1, 0)
+}
+
+// blankLinesAfter is the number of blank lines directly after the line of the offset
+func blankLinesAfter(code string, offset int) int {
+	end := len(code) - len(strings.TrimLeft(code[offset:], " \t\r\n"))
+	//blank lines at the end of the code are removed
+	if end == len(code) {
+		return 0
+	}
+	return max(strings.Count(code[offset:end], "\n")-1, 0)
+}
+
+// trailingBlankLines is the number of blank lines at the end of the text
+func trailingBlankLines(text string) int {
+	start := len(strings.TrimRight(text, " \t\r\n"))
+	return max(strings.Count(text[start:], "\n")-1, 0)
+}
+
+// tokenText returns the text of the prettified token.
+// The AST only keeps the values of string literals and quotes them again,
+// so they are kept as written, e.g. with their escapes and unicode spaces
+func tokenText(existingCode string, oldToken lexer.Token, prettyCode string, newToken lexer.Token) string {
+	newText := extractTokenText(prettyCode, newToken)
+	if !newToken.Is(lexer.TokenString) || !oldToken.Is(lexer.TokenString) {
+		return newText
+	}
+
+	oldText := extractTokenText(existingCode, oldToken)
+	oldValue, ok := stringValue(oldText)
+	if !ok {
+		return newText
+	}
+	if newValue, ok := stringValue(newText); !ok || oldValue != newValue {
+		return newText
+	}
+	return oldText
+}
+
+// accessModifier consumes the rest of an access(all) or access(self) modifier after access,
+// if the layout wrote it as the keyword
+func accessModifier(code string, tokens lexer.TokenStream, keyword string) bool {
+	cursor := tokens.Cursor()
+	if modifierKeyword, ok := accessKeyword(code, tokens); ok && modifierKeyword == keyword {
+		return true
+	}
+	tokens.Revert(cursor)
+	return false
+}
+
+// stringValue returns the value of a string literal, decoding its escapes like the parser,
+// without parsing the literal
+func stringValue(literal string) (string, bool) {
+	if len(literal) < 2 || literal[0] != '"' || literal[len(literal)-1] != '"' {
+		return "", false
+	}
+	content := literal[1 : len(literal)-1]
+
+	var value strings.Builder
+	value.Grow(len(content))
+	for i := 0; i < len(content); i++ {
+		if content[i] != '\\' {
+			value.WriteByte(content[i])
+			continue
+		}
+		i++
+		if i == len(content) {
+			return "", false
+		}
+		switch content[i] {
+		case '0':
+			value.WriteByte(0)
+		case 'n':
+			value.WriteByte('\n')
+		case 'r':
+			value.WriteByte('\r')
+		case 't':
+			value.WriteByte('\t')
+		case '"', '\'', '\\':
+			value.WriteByte(content[i])
+		case 'u':
+			end := strings.IndexByte(content[i:], '}')
+			if end < 0 || !strings.HasPrefix(content[i+1:], "{") {
+				return "", false
+			}
+			digits := content[i+2 : i+end]
+			if len(digits) > 8 || !hexDigits(digits) {
+				return "", false
+			}
+			r, err := strconv.ParseUint(digits, 16, 32)
+			if err != nil {
+				return "", false
+			}
+			value.WriteRune(rune(r))
+			i += end
+		default:
+			return "", false
+		}
+	}
+	return value.String(), true
+}
+
+// isTrailingBlock reports if the single-line block comment follows code on its line,
+// and is only followed by whitespace, other single-line block comments, or a line comment
+func isTrailingBlock(code string, c comment) bool {
+	if c.ownLine || strings.Contains(c.text, "\n") {
+		return false
+	}
+	after, _, _ := strings.Cut(code[c.end:], "\n")
+	after = strings.TrimSpace(after)
+	for strings.HasPrefix(after, "/*") {
+		_, rest, ok := strings.Cut(after, "*/")
+		if !ok {
+			return false
+		}
+		after = strings.TrimSpace(rest)
+	}
+	return after == "" || strings.HasPrefix(after, "//")
+}
+
+// indentComments indents the lines of the comments,
+// except the lines inside of block comments, which are kept as written
+func indentComments(padding, comments string) string {
+	var b strings.Builder
+	depth := 0
+	lineStart := true
+	for i := 0; i < len(comments); i++ {
+		if lineStart && depth == 0 {
+			b.WriteString(padding)
+		}
+		lineStart = comments[i] == '\n'
+
+		switch {
+		case depth == 0 && strings.HasPrefix(comments[i:], "//"):
+			end := strings.IndexByte(comments[i:], '\n')
+			if end < 0 {
+				end = len(comments) - i
+			}
+			b.WriteString(comments[i : i+end])
+			i += end - 1
+			continue
+		case strings.HasPrefix(comments[i:], "/*"):
+			depth++
+		case depth > 0 && strings.HasPrefix(comments[i:], "*/"):
+			depth--
+		default:
+			b.WriteByte(comments[i])
+			continue
+		}
+		b.WriteString(comments[i : i+2])
+		i++
+	}
+	return b.String()
+}
+
+// continuationIndent returns the indentation for a line continuing the last line
+func continuationIndent(code string) string {
+	line := code[strings.LastIndex(code, "\n")+1:]
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))] + "    "
+}
@@ -0,0 +1,158 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"context"
+	"io"
+)
+
+// Indent is the indentation of each level of the code
+type Indent string
+
+const (
+	// IndentSpaces indents with four spaces
+	IndentSpaces Indent = "spaces"
+	// IndentTabs indents with tabs
+	IndentTabs Indent = "tabs"
+)
+
+// Option configures a Formatter
+type Option func(*Formatter)
+
+// WithOptions starts from the options, instead of DefaultOptions.
+// The options which follow it override them
+func WithOptions(options Options) Option {
+	return func(f *Formatter) {
+		f.options = options
+	}
+}
+
+// WithMaxWidth sets the number of columns of the formatted code
+func WithMaxWidth(columns int) Option {
+	return func(f *Formatter) {
+		f.options.MaxLineLength = columns
+	}
+}
+
+// WithIndent sets the indentation of the formatted code
+func WithIndent(indent Indent) Option {
+	return func(f *Formatter) {
+		f.options.Tabs = indent == IndentTabs
+	}
+}
+
+// WithCommentsPolicy sets where comments go when the code around them is laid out differently
+func WithCommentsPolicy(strategy CommentStrategy) Option {
+	return func(f *Formatter) {
+		f.options.Comments = strategy
+	}
+}
+
+// WithSafetyChecks checks each result, see Verify and VerifyAST,
+// and fails with a SafetyError instead of returning code which does not pass them
+func WithSafetyChecks(enabled bool) Option {
+	return func(f *Formatter) {
+		f.safetyChecks = enabled
+	}
+}
+
+// WithPasses adds passes, which change each result after the formatting and the safety checks
+func WithPasses(passes ...Pass) Option {
+	return func(f *Formatter) {
+		f.passes = append(f.passes, passes...)
+	}
+}
+
+// Formatter formats code with the options it was created with.
+// It is not changed after NewFormatter, so it can be created once
+// and used by many goroutines at the same time
+type Formatter struct {
+	options      Options
+	safetyChecks bool
+	passes       []Pass
+}
+
+// NewFormatter returns a formatter with DefaultOptions, changed by the given options in order
+func NewFormatter(opts ...Option) *Formatter {
+	f := &Formatter{options: DefaultOptions}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+// Options are the options the formatter formats with
+func (f *Formatter) Options() Options {
+	return f.options
+}
+
+// Format formats the code, see Source
+func (f *Formatter) Format(code string) (string, error) {
+	return f.FormatContext(context.Background(), code)
+}
+
+// FormatContext formats the code, and stops with the error of the context when it is done,
+// see SourceContext
+func (f *Formatter) FormatContext(ctx context.Context, code string) (string, error) {
+	result, err := SourceContext(ctx, code, f.options)
+	if err != nil {
+		return "", err
+	}
+	if err := f.check(code, result); err != nil {
+		return "", err
+	}
+	//the checks compare the result to the code, which the passes may change on purpose
+	return runPasses(f.passes, result, f.options)
+}
+
+// FormatExpression formats a single expression, see FormatExpression
+func (f *Formatter) FormatExpression(src string) (string, error) {
+	return FormatExpression(src, f.options)
+}
+
+// FormatStatements formats statements, see FormatStatements
+func (f *Formatter) FormatStatements(src string) (string, error) {
+	return FormatStatements(src, f.options)
+}
+
+// FormatTo writes the formatted code to dst, see FormatTo.
+// With safety checks or passes, the whole result is made before anything is written
+func (f *Formatter) FormatTo(dst io.Writer, src []byte) error {
+	if !f.safetyChecks && len(f.passes) == 0 {
+		return FormatTo(dst, src, f.options)
+	}
+	result, err := f.Format(string(src))
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(dst, result)
+	return err
+}
+
+// check runs the safety checks on the formatted code, if they are enabled
+func (f *Formatter) check(code string, result string) error {
+	if !f.safetyChecks {
+		return nil
+	}
+	if err := Verify(code, result, f.options); err != nil {
+		return err
+	}
+	return VerifyAST(code, result, f.options)
+}
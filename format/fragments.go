@@ -0,0 +1,82 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"strings"
+
+	"github.com/onflow/cadence/runtime/parser"
+)
+
+// fragmentHeader starts the function which fragments are formatted in,
+// as they are not programs on their own
+const fragmentHeader = "fun _() "
+
+// FormatExpression formats a single expression, e.g. of a REPL or of documentation,
+// which does not parse as a program on its own
+func FormatExpression(src string, options Options) (string, error) {
+	if _, errs := parser.ParseExpression(nil, []byte(src), parser.Config{}); len(errs) > 0 {
+		return "", parser.Error{Code: []byte(src), Errors: errs}
+	}
+	return formatFragment(src, options)
+}
+
+// FormatStatements formats statements, e.g. the body of a function shown in documentation,
+// which do not parse as a program on their own
+func FormatStatements(src string, options Options) (string, error) {
+	if _, errs := parser.ParseStatements(nil, []byte(src), parser.Config{}); len(errs) > 0 {
+		return "", parser.Error{Code: []byte(src), Errors: errs}
+	}
+	return formatFragment(src, options)
+}
+
+// formatFragment formats the statements in the body of a function,
+// which is one level deeper, so the width grows by its indentation
+func formatFragment(src string, options Options) (string, error) {
+	if strings.TrimSpace(src) == "" {
+		return "", nil
+	}
+
+	wrapperOptions := options
+	if wrapperOptions.MaxLineLength > 0 {
+		wrapperOptions.MaxLineLength += 4
+	}
+	formatted, err := Source(fragmentHeader+"{\n"+strings.ReplaceAll(src, "\r\n", "\n")+"\n}", wrapperOptions)
+	if err != nil {
+		return "", err
+	}
+
+	//the body is between the braces of the function, which may be on the line of the header,
+	//on the next line, or around a single statement on one line
+	formatted = strings.TrimRight(formatted, "\n")
+	body := formatted[strings.Index(formatted, "{")+1 : strings.LastIndex(formatted, "}")]
+	if !strings.Contains(body, "\n") {
+		return strings.TrimSpace(body), nil
+	}
+
+	unit := "    "
+	if options.Tabs {
+		unit = "\t"
+	}
+	lines := strings.Split(strings.Trim(body, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimPrefix(line, unit)
+	}
+	return strings.Join(lines, "\n"), nil
+}
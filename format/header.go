@@ -0,0 +1,159 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// headerDirective starts the comment on the first line of a file
+// which overrides the options for the file, e.g.
+//
+//	// cadencefmt: max-width=100 indent=tab
+const headerDirective = "cadencefmt:"
+
+// header returns the settings of the header comment of the code, and its span
+func header(code string) (string, commentSpan, bool) {
+	line, _, _ := strings.Cut(code, "\n")
+	text, ok := strings.CutPrefix(strings.TrimSpace(line), "//")
+	if !ok {
+		return "", commentSpan{}, false
+	}
+	settings, ok := strings.CutPrefix(strings.TrimSpace(text), headerDirective)
+	//directives like cadencefmt:off are not headers
+	if !ok || (settings != "" && settings[0] != ' ' && settings[0] != '\t') {
+		return "", commentSpan{}, false
+	}
+	return settings, commentSpan{0, len(line)}, true
+}
+
+// FileOptions returns the options with the overrides of the header comment of the code, if any
+func FileOptions(code string, options Options) (Options, error) {
+	//the header comment follows the byte order mark and the directive lines
+	_, rest := splitDirectives(strings.TrimPrefix(code, byteOrderMark))
+	settings, _, ok := header(rest)
+	if !ok {
+		return options, nil
+	}
+
+	for _, setting := range strings.Fields(settings) {
+		key, value, ok := strings.Cut(setting, "=")
+		if !ok {
+			return Options{}, fmt.Errorf("line 1: invalid setting %q, expected key=value", setting)
+		}
+		if err := options.set(key, value); err != nil {
+			return Options{}, fmt.Errorf("line 1: %w", err)
+		}
+	}
+	return options, nil
+}
+
+// set sets the option of the header setting
+func (o *Options) set(key, value string) error {
+	var err error
+	switch key {
+	case "max-width":
+		o.MaxLineLength, err = strconv.Atoi(value)
+		if err == nil && o.MaxLineLength <= 0 {
+			err = fmt.Errorf("must be positive")
+		}
+	case "indent":
+		switch value {
+		case "tab":
+			o.Tabs = true
+		case "space":
+			o.Tabs = false
+		default:
+			err = fmt.Errorf("expected %q or %q", "tab", "space")
+		}
+	case "comments":
+		o.Comments, err = ParseCommentStrategy(value)
+	case "comment-style":
+		o.CommentStyle, err = ParseCommentStyle(value)
+	case "empty-bodies":
+		o.EmptyBodies, err = ParseEmptyBodyStyle(value)
+	case "parameter-wrap":
+		o.ParameterWrap, err = ParseParameterWrapStyle(value)
+	case "enum-cases":
+		o.EnumCases, err = ParseEnumCaseStyle(value)
+	case "return-type":
+		o.ReturnType, err = ParseReturnTypeStyle(value)
+	case "operator-position":
+		o.OperatorPosition, err = ParseOperatorPosition(value)
+	case "braces":
+		o.Braces, err = ParseBraceStyle(value)
+	case "line-endings":
+		o.LineEndings, err = ParseLineEndings(value)
+	case "group-fields":
+		o.GroupFields, err = strconv.ParseBool(value)
+	case "align-comments":
+		o.AlignComments, err = strconv.ParseBool(value)
+	case "align-values":
+		o.AlignValues, err = strconv.ParseBool(value)
+	case "align-parameters":
+		o.AlignParameters, err = strconv.ParseBool(value)
+	case "reflow-docs":
+		o.ReflowDocs, err = strconv.ParseBool(value)
+	case "sort-imports":
+		o.SortImports, err = strconv.ParseBool(value)
+	case "normalize-escapes":
+		o.NormalizeEscapes, err = strconv.ParseBool(value)
+	case "clean-imports":
+		o.CleanImports, err = strconv.ParseBool(value)
+	case "import-groups":
+		o.ImportGroups, err = ParseImportGroups(value)
+	case "core-contracts":
+		o.CoreContracts = value
+	case "trailing-commas":
+		o.TrailingCommas, err = strconv.ParseBool(value)
+	case "one-line-functions":
+		o.OneLineFunctions, err = strconv.ParseBool(value)
+	case "one-line-cases":
+		o.OneLineCases, err = strconv.ParseBool(value)
+	case "keep-bom":
+		o.KeepByteOrderMark, err = strconv.ParseBool(value)
+	case "exact-blank-lines":
+		o.ExactBlankLines, err = strconv.ParseBool(value)
+	case "reorder-members":
+		o.ReorderMembers, err = strconv.ParseBool(value)
+	case "collection-elements":
+		o.CollectionElements, err = strconv.Atoi(value)
+		if err == nil && o.CollectionElements < 0 {
+			err = fmt.Errorf("must not be negative")
+		}
+	case "collection-width":
+		o.CollectionWidth, err = strconv.Atoi(value)
+		if err == nil && o.CollectionWidth < 0 {
+			err = fmt.Errorf("must not be negative")
+		}
+	case "max-blank-lines":
+		o.MaxBlankLines, err = strconv.Atoi(value)
+		if err == nil && o.MaxBlankLines < 0 {
+			err = fmt.Errorf("must not be negative")
+		}
+	default:
+		return fmt.Errorf("unknown setting %q", key)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %s %q: %w", key, value, err)
+	}
+	return nil
+}
@@ -0,0 +1,251 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/parser"
+)
+
+const (
+	// ImportGroupCore are the imports of core contracts, see Options.CoreContracts,
+	// and of built-in contracts like Crypto
+	ImportGroupCore = "core"
+	// ImportGroupAddress are the imports from addresses
+	ImportGroupAddress = "address"
+	// ImportGroupString are the imports of string locations, like paths
+	ImportGroupString = "string"
+)
+
+// DefaultImportGroups is the order of the import groups when grouping is enabled without one
+const DefaultImportGroups = ImportGroupCore + "," + ImportGroupAddress + "," + ImportGroupString
+
+// DefaultCoreContracts are the contracts in the core import group, if Options.CoreContracts is empty
+const DefaultCoreContracts = "FungibleToken,NonFungibleToken,MetadataViews,ViewResolver,FungibleTokenMetadataViews," +
+	"FlowToken,FlowFees,FlowStorageFees,FlowServiceAccount,FlowIDTableStaking,FlowEpoch,LockedTokens,StakingProxy"
+
+// ParseImportGroups parses the comma separated order of import groups, the empty order disables grouping
+func ParseImportGroups(s string) (string, error) {
+	if strings.TrimSpace(s) == "" {
+		return "", nil
+	}
+	var groups []string
+	for _, group := range strings.Split(s, ",") {
+		group = strings.TrimSpace(group)
+		switch group {
+		case ImportGroupCore, ImportGroupAddress, ImportGroupString:
+			groups = append(groups, group)
+		default:
+			return "", fmt.Errorf(
+				"invalid import group %q, expected %q, %q or %q",
+				group,
+				ImportGroupCore,
+				ImportGroupAddress,
+				ImportGroupString,
+			)
+		}
+	}
+	return strings.Join(groups, ","), nil
+}
+
+// importLines are the lines of an import declaration,
+// with the comments on the lines directly before it and after it on its last line
+type importLines struct {
+	// first and last are the indices of the lines
+	first, last int
+	// group is the index of the import group, and key the text of the declaration,
+	// which imports are sorted by
+	group int
+	key   string
+}
+
+// arrangeImports sorts the consecutive import declarations at the top of the code alphabetically,
+// and orders them by import group, if the options enable it.
+// Line comments before an import move with it, except the ones before the first import,
+// which usually are the header of the file, and blank lines stay where they are,
+// besides the ones between grouped imports, as the layout separates the groups.
+// Other code between imports, e.g. block comments, separates runs which are arranged on their own.
+// The code is kept if an import shares its lines with other code, or is in an off region
+func arrangeImports(code string, options Options) (string, error) {
+	program, err := parser.ParseProgram(nil, []byte(code), parser.Config{})
+	if err != nil {
+		return "", err
+	}
+	declarations := leadingImports(program)
+	if len(declarations) < 2 {
+		return code, nil
+	}
+
+	lines := strings.Split(code, "\n")
+	regions := offRegions(code)
+	imports := make([]importLines, 0, len(declarations))
+	for i, declaration := range declarations {
+		start := declaration.StartPosition().Offset
+		end := declaration.EndPosition(nil).Offset + 1
+		lineStart := strings.LastIndex(code[:start], "\n") + 1
+		rest, _, _ := strings.Cut(code[end:], "\n")
+		rest = strings.TrimSpace(rest)
+		if strings.TrimSpace(code[lineStart:start]) != "" || (rest != "" && !strings.HasPrefix(rest, "//")) {
+			return code, nil
+		}
+		for _, region := range regions {
+			if start < region.end && region.start < end {
+				return code, nil
+			}
+		}
+
+		chunk := importLines{
+			first: declaration.StartPosition().Line - 1,
+			last:  declaration.EndPosition(nil).Line - 1,
+			group: options.importGroup(declaration),
+		}
+		if options.SortImports {
+			chunk.key = strings.Join(strings.Fields(code[start:end]), " ")
+		}
+		if i > 0 {
+			previous := imports[i-1].last
+			line := chunk.first - 1
+			for line > previous && (isBlank(lines[line]) || strings.HasPrefix(strings.TrimSpace(lines[line]), "//")) {
+				if !isBlank(lines[line]) {
+					chunk.first = line
+				}
+				line--
+			}
+			//line comments inside of a block comment stay in it
+			if line > previous && strings.Contains(lines[line], "*/") {
+				chunk.first = declaration.StartPosition().Line - 1
+			}
+		}
+		imports = append(imports, chunk)
+	}
+
+	var sorted []string
+	from := 0
+	for start := 0; start < len(imports); {
+		//imports separated only by blank lines are in the same run
+		end := start + 1
+		for end < len(imports) && separatedByBlankLines(lines, imports[end-1].last, imports[end].first) {
+			end++
+		}
+
+		run := make([]importLines, end-start)
+		copy(run, imports[start:end])
+		sort.SliceStable(run, func(i, j int) bool {
+			if run[i].group != run[j].group {
+				return run[i].group < run[j].group
+			}
+			return run[i].key < run[j].key
+		})
+
+		sorted = append(sorted, lines[from:imports[start].first]...)
+		for i, chunk := range run {
+			sorted = append(sorted, lines[chunk.first:chunk.last+1]...)
+			//the blank lines after the i-th import of the run
+			if slot := start + i; slot+1 < end && options.ImportGroups == "" {
+				sorted = append(sorted, lines[imports[slot].last+1:imports[slot+1].first]...)
+			}
+		}
+		from = imports[end-1].last + 1
+
+		start = end
+	}
+	sorted = append(sorted, lines[from:]...)
+	return strings.Join(sorted, "\n"), nil
+}
+
+// separatedByBlankLines reports if only blank lines are between the lines
+func separatedByBlankLines(lines []string, last, next int) bool {
+	for _, line := range lines[last+1 : next] {
+		if !isBlank(line) {
+			return false
+		}
+	}
+	return true
+}
+
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+// leadingImports are the consecutive import declarations starting with the first one of the program
+func leadingImports(program *ast.Program) []*ast.ImportDeclaration {
+	var imports []*ast.ImportDeclaration
+	for _, declaration := range program.Declarations() {
+		importDeclaration, ok := declaration.(*ast.ImportDeclaration)
+		if ok {
+			imports = append(imports, importDeclaration)
+		} else if imports != nil {
+			break
+		}
+	}
+	return imports
+}
+
+// importGroup returns the index of the group of the import in the order of the options,
+// or the number of groups for imports which are in none of them, so they come last.
+// Without grouping, all imports are in the same group
+func (o Options) importGroup(declaration *ast.ImportDeclaration) int {
+	if o.ImportGroups == "" {
+		return 0
+	}
+
+	kind := ImportGroupString
+	switch location := declaration.Location.(type) {
+	case common.IdentifierLocation:
+		kind = ImportGroupCore
+	case common.AddressLocation:
+		kind = ImportGroupAddress
+	case common.StringLocation:
+		if o.coreContract(string(location)) {
+			kind = ImportGroupCore
+		}
+	}
+	for _, identifier := range declaration.Identifiers {
+		if o.coreContract(identifier.Identifier) {
+			kind = ImportGroupCore
+		}
+	}
+
+	groups := strings.Split(o.ImportGroups, ",")
+	for i, group := range groups {
+		if group == kind {
+			return i
+		}
+	}
+	return len(groups)
+}
+
+// coreContract reports if the contract is in the core import group
+func (o Options) coreContract(name string) bool {
+	contracts := o.CoreContracts
+	if contracts == "" {
+		contracts = DefaultCoreContracts
+	}
+	for _, contract := range strings.Split(contracts, ",") {
+		if strings.TrimSpace(contract) == name {
+			return true
+		}
+	}
+	return false
+}
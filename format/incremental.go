@@ -0,0 +1,133 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"strings"
+)
+
+// Formatted is code with its formatted result, which Reformat updates for edits of the code
+type Formatted struct {
+	Code    string
+	Result  string
+	Options Options
+}
+
+// Reformat formats the code, which is the code of the previous result after an edit,
+// if the edit is inside a single top-level declaration: only that declaration is formatted
+// and spliced into the previous result, as the rest of the result stays the same.
+// It is false otherwise, e.g. for edits between declarations or options which change
+// the result as a whole like sorting imports, and all of the code must be formatted
+func Reformat(previous Formatted, code string, options Options) (string, bool) {
+	if previous.Result == "" || previous.Options != options || !spliceable(code) {
+		return "", false
+	}
+
+	options, err := FileOptions(code, options)
+	if err != nil || !spliceableOptions(options) {
+		return "", false
+	}
+
+	//the edit replaces previous.Code[start:oldEnd] with code[start:newEnd]
+	start := commonPrefix(previous.Code, code)
+	suffix := commonSuffix(previous.Code[start:], code[start:])
+	oldEnd := len(previous.Code) - suffix
+	delta := len(code) - len(previous.Code)
+
+	oldSpans := topLevelDeclarations(previous.Code)
+	newSpans := topLevelDeclarations(code)
+	resultSpans := topLevelDeclarations(previous.Result)
+	if len(newSpans) != len(oldSpans) || len(resultSpans) != len(oldSpans) {
+		return "", false
+	}
+
+	edited := -1
+	for i, s := range oldSpans {
+		if s.start <= start && oldEnd <= s.end {
+			edited = i
+			break
+		}
+	}
+	if edited < 0 {
+		return "", false
+	}
+
+	//the edit must not join, split or move declarations
+	for i, s := range oldSpans {
+		if i == edited {
+			s.end += delta
+		} else if i > edited {
+			s.start += delta
+			s.end += delta
+		}
+		if newSpans[i] != s {
+			return "", false
+		}
+	}
+
+	declaration := newSpans[edited]
+	formatted, err := Source(code[declaration.start:declaration.end], options)
+	if err != nil {
+		//the error is reported at its position in the code by formatting all of it
+		return "", false
+	}
+
+	r := resultSpans[edited]
+	return previous.Result[:r.start] + strings.TrimRight(formatted, "\n") + previous.Result[r.end:], true
+}
+
+// spliceable reports if the formatted declarations of the code are as they are in the result,
+// which is not the case for code which is converted as a whole before and after formatting
+func spliceable(code string) bool {
+	return !strings.HasPrefix(code, byteOrderMark) &&
+		!strings.HasPrefix(code, directivePrefix) &&
+		!strings.Contains(code, "\r") &&
+		!strings.Contains(code, offDirective)
+}
+
+// spliceableOptions reports if the options format each top-level declaration on its own
+func spliceableOptions(options Options) bool {
+	return !options.SortImports &&
+		!options.CleanImports &&
+		options.ImportGroups == "" &&
+		!options.AlignComments &&
+		!options.AlignValues
+}
+
+// commonPrefix is the length of the common prefix of the strings
+func commonPrefix(a, b string) int {
+	n := min(len(a), len(b))
+	for i := 0; i < n; i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return n
+}
+
+// commonSuffix is the length of the common suffix of the strings
+func commonSuffix(a, b string) int {
+	n := min(len(a), len(b))
+	for i := 0; i < n; i++ {
+		if a[len(a)-1-i] != b[len(b)-1-i] {
+			return i
+		}
+	}
+	return n
+}
@@ -0,0 +1,523 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/turbolent/prettier"
+	"golang.org/x/exp/slices"
+
+	"github.com/onflow/cadence/runtime/ast"
+)
+
+// listComments are the comments attached to the elements of a parameter or argument list
+type listComments struct {
+	starts   []int
+	leading  [][]comment
+	trailing [][]comment
+	dangling []comment
+	// end is the offset after the last element
+	end int
+}
+
+// list attaches the comments of the list between the parentheses at the offsets,
+// if one of them must be on its own line, i.e. is a line comment or on its own line.
+// Comments which fit in a list on one line are kept by the token pass
+func (t *trivia) list(open, close int, elements []ast.HasPosition) (listComments, bool) {
+	if t == nil || !t.breaksList(open, close, elements) {
+		return listComments{}, false
+	}
+
+	comments := listComments{end: open + 1}
+	for _, element := range elements {
+		start := element.StartPosition().Offset
+		comments.starts = append(comments.starts, start)
+		comments.leading = append(comments.leading, t.take(comments.end, start))
+		comments.end = element.EndPosition(nil).Offset + 1
+	}
+	for i := range elements {
+		next := close
+		if i+1 < len(elements) {
+			next = comments.starts[i+1]
+		}
+		end := elements[i].EndPosition(nil).Offset + 1
+		comments.trailing = append(comments.trailing, t.trailing(end, next))
+	}
+	comments.dangling = t.take(comments.end, close)
+	return comments, true
+}
+
+// trailing attaches the comments between the offsets which follow the code before the first offset on its line,
+// if the code at the end offset is on a later line
+func (t *trivia) trailing(from, to int) []comment {
+	line := t.line(from - 1)
+	if t.line(to) == line {
+		return nil
+	}
+
+	var result []comment
+	for i := t.after(from); i < len(t.comments) && t.comments[i].end <= to; i++ {
+		c := t.comments[i]
+		if t.attached[i] || c.ownLine || c.line != line {
+			continue
+		}
+		t.attached[i] = true
+		result = append(result, c)
+	}
+	return result
+}
+
+// breaksList reports if a comment between the elements of the list must be on its own line
+func (t *trivia) breaksList(open, close int, elements []ast.HasPosition) bool {
+	from := open + 1
+	for i := 0; i <= len(elements); i++ {
+		to := close
+		if i < len(elements) {
+			to = elements[i].StartPosition().Offset
+		}
+		for i := t.after(from); i < len(t.comments) && t.comments[i].end <= to; i++ {
+			if c := t.comments[i]; c.ownLine || strings.HasPrefix(c.text, "//") {
+				return true
+			}
+		}
+		if i < len(elements) {
+			from = elements[i].EndPosition(nil).Offset + 1
+		}
+	}
+	return false
+}
+
+// brokenList prints the elements in parentheses, each on its own line after its comments
+func (p printer) brokenList(docs []prettier.Doc, comments listComments) prettier.Doc {
+	var elements prettier.Concat
+	for i, doc := range docs {
+		elements = append(
+			elements,
+			p.leadingComments(comments.leading[i], comments.starts[i]),
+			doc,
+		)
+		if i+1 < len(docs) {
+			elements = append(elements, prettier.Text(","))
+		}
+		for _, c := range comments.trailing[i] {
+			elements = append(elements, prettier.Text(" "+c.text))
+		}
+		if i+1 < len(docs) {
+			elements = append(elements, prettier.HardLine{})
+		}
+	}
+
+	return prettier.Concat{
+		prettier.Text("("),
+		prettier.Indent{
+			Doc: prettier.Concat{
+				prettier.HardLine{},
+				elements,
+				p.danglingComments(comments.dangling, comments.end),
+			},
+		},
+		prettier.HardLine{},
+		prettier.Text(")"),
+	}
+}
+
+// commentedParameters prints the parameter list broken, if comments between the parameters require it,
+// or returns nil
+func (p printer) commentedParameters(list *ast.ParameterList) prettier.Doc {
+	if list == nil {
+		return nil
+	}
+
+	elements := make([]ast.HasPosition, len(list.Parameters))
+	for i, parameter := range list.Parameters {
+		elements[i] = parameter
+	}
+	comments, ok := p.trivia.list(list.StartPos.Offset, list.EndPos.Offset, elements)
+	if !ok {
+		return nil
+	}
+	return p.brokenList(parameterDocs(list), comments)
+}
+
+// packedParameters prints the parameter list with as many parameters on each line as fit when it is broken,
+// if the options pack parameters, or returns nil
+func (p printer) packedParameters(list *ast.ParameterList) prettier.Doc {
+	if p.options.ParameterWrap != ParametersPacked || list.IsEmpty() {
+		return nil
+	}
+
+	var elements prettier.Concat
+	for i, doc := range parameterDocs(list) {
+		if i > 0 {
+			//each separator breaks on its own
+			elements = append(elements, prettier.Text(","), prettier.Group{Doc: prettier.Line{}})
+		}
+		elements = append(elements, doc)
+	}
+	return prettier.Group{
+		Doc: prettier.Concat{
+			prettier.Text("("),
+			prettier.Indent{
+				Doc: prettier.Concat{
+					prettier.SoftLine{},
+					elements,
+				},
+			},
+			prettier.SoftLine{},
+			prettier.Text(")"),
+		},
+	}
+}
+
+func parameterDocs(list *ast.ParameterList) []prettier.Doc {
+	docs := make([]prettier.Doc, len(list.Parameters))
+	for i, parameter := range list.Parameters {
+		var doc prettier.Concat
+		if parameter.Label != "" {
+			doc = append(doc, prettier.Text(parameter.Label), prettier.Space)
+		}
+		docs[i] = append(
+			doc,
+			prettier.Text(parameter.Identifier.Identifier),
+			prettier.Text(": "),
+			parameter.TypeAnnotation.Doc(),
+		)
+	}
+	return docs
+}
+
+// signatureIndex returns the index of the group of the signature in the document of a function
+// the AST prints, which is before the body
+func signatureIndex(concat prettier.Concat, block *ast.FunctionBlock) int {
+	//non-empty bodies are printed after a space
+	if block.IsEmpty() {
+		return len(concat) - 2
+	}
+	return len(concat) - 3
+}
+
+// withParameters replaces the parameter list in the document of a function
+// the AST prints, which has the signature in the group before the body
+func withParameters(doc prettier.Doc, parameters prettier.Doc, typeParameters *ast.TypeParameterList, block *ast.FunctionBlock) prettier.Concat {
+	concat := slices.Clone(doc.(prettier.Concat))
+
+	index := signatureIndex(concat, block)
+	signature := slices.Clone(concat[index].(prettier.Group).Doc.(prettier.Concat))
+
+	parametersIndex := 0
+	if typeParameters != nil && typeParameters.Doc() != nil {
+		parametersIndex = 1
+	}
+	signature[parametersIndex] = parameters
+	concat[index] = prettier.Group{Doc: signature}
+
+	return concat
+}
+
+// withReturnTypeLine puts the return type in the document of a function the AST prints on its own line,
+// if the signature is broken, or always if the parameters are
+func withReturnTypeLine(doc prettier.Doc, block *ast.FunctionBlock, brokenParameters bool) prettier.Concat {
+	concat := slices.Clone(doc.(prettier.Concat))
+
+	index := signatureIndex(concat, block)
+	signature := slices.Clone(concat[index].(prettier.Group).Doc.(prettier.Concat))
+
+	var line prettier.Doc = prettier.SoftLine{}
+	if brokenParameters {
+		line = prettier.HardLine{}
+	}
+	//the AST prints the return type last, after its separator
+	signature[len(signature)-2] = prettier.Indent{
+		Doc: prettier.Concat{
+			line,
+			prettier.Text(": "),
+		},
+	}
+	concat[index] = prettier.Group{Doc: signature}
+
+	return concat
+}
+
+// commentedInvocation is an invocation which prints its arguments broken,
+// so the comments between them stay with them
+type commentedInvocation struct {
+	*ast.InvocationExpression
+	printer  printer
+	comments listComments
+}
+
+func (e *commentedInvocation) Doc() prettier.Doc {
+	docs := make([]prettier.Doc, len(e.Arguments))
+	for i, argument := range e.Arguments {
+		docs[i] = argument.Doc()
+	}
+
+	//the AST prints the arguments last
+	doc := slices.Clone(e.InvocationExpression.Doc().(prettier.Concat))
+	doc[len(doc)-1] = e.printer.brokenList(docs, e.comments)
+	return doc
+}
+
+// commentedCreate, commentedEmit and commentedAttach print their commented invocation
+type commentedCreate struct {
+	*ast.CreateExpression
+	invocation *commentedInvocation
+}
+
+func (e *commentedCreate) Doc() prettier.Doc {
+	return prettier.Concat{
+		prettier.Text("create "),
+		e.invocation.Doc(),
+	}
+}
+
+type commentedEmit struct {
+	*ast.EmitStatement
+	invocation *commentedInvocation
+}
+
+func (s *commentedEmit) Doc() prettier.Doc {
+	return prettier.Concat{
+		prettier.Text("emit "),
+		s.invocation.Doc(),
+	}
+}
+
+type commentedAttach struct {
+	*ast.AttachExpression
+	invocation *commentedInvocation
+}
+
+func (e *commentedAttach) Doc() prettier.Doc {
+	return prettier.Concat{
+		prettier.Text("attach "),
+		e.invocation.Doc(),
+		prettier.Text(" to "),
+		e.Base.Doc(),
+	}
+}
+
+// commentedFunction is a function expression which prints its parameter list broken
+type commentedFunction struct {
+	*ast.FunctionExpression
+	parameters prettier.Doc
+}
+
+func (e *commentedFunction) Doc() prettier.Doc {
+	return withParameters(e.FunctionExpression.Doc(), e.parameters, nil, e.FunctionBlock)
+}
+
+// commentedInvocation returns the invocation printing its arguments broken,
+// if comments between them require it, or nil
+func (p printer) commentedInvocation(invocation *ast.InvocationExpression) *commentedInvocation {
+	elements := make([]ast.HasPosition, len(invocation.Arguments))
+	for i, argument := range invocation.Arguments {
+		elements[i] = argument
+	}
+	comments, ok := p.trivia.list(invocation.ArgumentsStartPos.Offset, invocation.EndPos.Offset, elements)
+	if !ok {
+		return nil
+	}
+	return &commentedInvocation{
+		InvocationExpression: invocation,
+		printer:              p,
+		comments:             comments,
+	}
+}
+
+// breakLists replaces the expressions and statements with lists which must be broken:
+// lists which have comments between their elements requiring it,
+// collection literals over the thresholds of the options, chains of calls and logical operators,
+// which break at each call or operator, borrow chains with their ?? fallback, and switch statements, which print their cases themselves.
+// The AST prints expressions and statements, so they are replaced where the AST references them
+func (p printer) breakLists(declarations []ast.Declaration) {
+	visited := map[uintptr]bool{}
+	var visit func(declarations []ast.Declaration)
+	visit = func(declarations []ast.Declaration) {
+		for _, declaration := range declarations {
+			p.replaceCommented(reflect.ValueOf(declaration), visited)
+			if members := declaration.DeclarationMembers(); members != nil {
+				visit(members.Declarations())
+			}
+		}
+	}
+	visit(declarations)
+}
+
+// replaceCommented replaces the elements in the value.
+// The AST has references back to elements, so visited pointers are skipped
+func (p printer) replaceCommented(value reflect.Value, visited map[uintptr]bool) {
+	switch value.Kind() {
+	case reflect.Interface:
+		if !value.IsNil() {
+			p.replaceCommented(value.Elem(), visited)
+		}
+
+	case reflect.Pointer:
+		if value.IsNil() || visited[value.Pointer()] {
+			return
+		}
+		visited[value.Pointer()] = true
+		p.replaceCommented(value.Elem(), visited)
+
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			//unexported fields can not be replaced, members are visited separately
+			if value.Type().Field(i).IsExported() {
+				p.replace(value.Field(i), visited)
+			}
+		}
+
+	case reflect.Slice:
+		for i := 0; i < value.Len(); i++ {
+			p.replace(value.Index(i), visited)
+		}
+	}
+}
+
+// replace replaces the expression or statement if it has a list which must be broken,
+// and continues with the elements in it
+func (p printer) replace(value reflect.Value, visited map[uintptr]bool) {
+	if value.Kind() == reflect.Interface && value.CanSet() && !value.IsNil() {
+		var replacement any
+		switch element := value.Interface().(type) {
+		case *ast.InvocationExpression:
+			//chains break the commented arguments of their calls themselves
+			if chained := p.chained(element, visited); chained != nil {
+				replacement = chained
+			} else if invocation := p.commentedInvocation(element); invocation != nil {
+				replacement = invocation
+			} else if forced := forced(element); forced != nil {
+				replacement = forced
+			}
+		case *ast.BinaryExpression:
+			if logical := p.logical(element, visited); logical != nil {
+				replacement = logical
+			} else if borrowed := p.borrowed(element, visited); borrowed != nil {
+				replacement = borrowed
+			}
+		case *ast.MemberExpression, *ast.ForceExpression, *ast.IndexExpression:
+			if chained := p.chained(element.(ast.Expression), visited); chained != nil {
+				replacement = chained
+			} else if forced := forced(element.(ast.Expression)); forced != nil {
+				replacement = forced
+			}
+		case *ast.CreateExpression:
+			if invocation := p.commentedInvocation(element.InvocationExpression); invocation != nil {
+				replacement = &commentedCreate{element, invocation}
+			}
+		case *ast.AttachExpression:
+			if invocation := p.commentedInvocation(element.Attachment); invocation != nil {
+				replacement = &commentedAttach{element, invocation}
+			}
+		case *ast.EmitStatement:
+			if invocation := p.commentedInvocation(element.InvocationExpression); invocation != nil {
+				replacement = &commentedEmit{element, invocation}
+			}
+		case *ast.FunctionExpression:
+			if parameters := p.commentedParameters(element.ParameterList); parameters != nil {
+				replacement = &commentedFunction{element, parameters}
+			}
+		case *ast.RestrictedType:
+			//restricted types without a restricted type may break after the opening brace, like the AST does
+			if element.Type != nil {
+				replacement = &restrictedType{element}
+			}
+		case *ast.SwitchStatement:
+			replacement = &switchStatement{element, p}
+		case *ast.ArrayExpression:
+			if p.breaksCollection(element, len(element.Values)) {
+				replacement = &brokenArray{element}
+			}
+		case *ast.DictionaryExpression:
+			if p.breaksCollection(element, len(element.Entries)) {
+				replacement = &brokenDictionary{element}
+			}
+		}
+		if replacement != nil && reflect.TypeOf(replacement).AssignableTo(value.Type()) {
+			value.Set(reflect.ValueOf(replacement))
+		}
+	}
+	p.replaceCommented(value, visited)
+
+	//after their values are replaced, statements with broken values keep them after the transfer
+	if value.Kind() == reflect.Interface && value.CanSet() && !value.IsNil() {
+		var replacement any
+		switch element := value.Interface().(type) {
+		case *ast.VariableDeclaration:
+			if element.SecondValue == nil && hasBrokenArguments(element.Value) {
+				replacement = &brokenVariable{element}
+			}
+		case *ast.AssignmentStatement:
+			if hasBrokenArguments(element.Value) {
+				replacement = &brokenAssignment{element}
+			}
+		}
+		if replacement != nil && reflect.TypeOf(replacement).AssignableTo(value.Type()) {
+			value.Set(reflect.ValueOf(replacement))
+		}
+	}
+}
+
+// hasBrokenArguments reports if the expression is an invocation which always breaks its arguments
+func hasBrokenArguments(expression ast.Expression) bool {
+	switch expression.(type) {
+	case *commentedInvocation, *commentedCreate, *commentedAttach:
+		return true
+	}
+	return false
+}
+
+// brokenVariable is a variable declaration of an invocation which breaks its arguments.
+// The AST indents the value for breaking it onto its own line, which it never is,
+// as the line of the invocation fits before the break of its arguments,
+// so the value is printed after the transfer, with the arguments indented once
+type brokenVariable struct {
+	*ast.VariableDeclaration
+}
+
+func (d *brokenVariable) Doc() prettier.Doc {
+	return variableWithValue(d.VariableDeclaration)
+}
+
+// variableWithValue prints the variable declaration with its value after the transfer
+func variableWithValue(declaration *ast.VariableDeclaration) prettier.Doc {
+	doc := declaration.Doc().(prettier.Group)
+	concat := slices.Clone(doc.Doc.(prettier.Concat))
+	//the AST prints the identifier, the transfer and the value last
+	values := slices.Clone(concat[len(concat)-1].(prettier.Group).Doc.(prettier.Concat))
+	values[len(values)-1] = prettier.Concat{prettier.Space, declaration.Value.Doc()}
+	concat[len(concat)-1] = prettier.Group{Doc: values}
+	return prettier.Group{Doc: concat}
+}
+
+// brokenAssignment is an assignment of an invocation which breaks its arguments,
+// printed like brokenVariable
+type brokenAssignment struct {
+	*ast.AssignmentStatement
+}
+
+func (s *brokenAssignment) Doc() prettier.Doc {
+	concat := slices.Clone(s.AssignmentStatement.Doc().(prettier.Group).Doc.(prettier.Concat))
+	//the AST prints the value last
+	concat[len(concat)-1] = s.Value.Doc()
+	return prettier.Group{Doc: concat}
+}
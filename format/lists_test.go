@@ -0,0 +1,76 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package format
+
+import (
+	"testing"
+)
+
+func TestSourceCommentedArguments(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		expected string
+	}{
+		{
+			name:     "variable",
+			code:     "let x = foo(\n a, // arg a\n // before b\n b\n)\n",
+			expected: "let x = foo(\n    a, // arg a\n    // before b\n    b\n)",
+		},
+		{
+			name:     "variable in a function",
+			code:     "pub fun f() {\n    let x = foo(\n        a, // arg a\n        b\n    )\n}\n",
+			expected: "pub fun f() {\n    let x = foo(\n        a, // arg a\n        b\n    )\n}",
+		},
+		{
+			name:     "assignment",
+			code:     "pub fun f() {\n    x = foo(\n        a, // arg a\n        b\n    )\n}\n",
+			expected: "pub fun f() {\n    x = foo(\n        a, // arg a\n        b\n    )\n}",
+		},
+		{
+			name:     "create",
+			code:     "pub fun f() {\n    let r <- create R(\n        a, // arg a\n        b\n    )\n}\n",
+			expected: "pub fun f() {\n    let r <- create R(\n        a, // arg a\n        b\n    )\n}",
+		},
+		{
+			name:     "return",
+			code:     "pub fun f(): Int {\n    return foo(\n        a, // arg a\n        b\n    )\n}\n",
+			expected: "pub fun f(): Int {\n    return foo(\n        a, // arg a\n        b\n    )\n}",
+		},
+		{
+			name:     "parameters",
+			code:     "pub fun f(a: Int, /* amount */ b: UFix64) {}\n",
+			expected: "pub fun f(a: Int, /* amount */ b: UFix64) {}",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			formatted, err := Source(test.code, DefaultOptions)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if formatted != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, formatted)
+			}
+			if err := Verify(test.code, formatted, DefaultOptions); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"reflect"
+
+	"github.com/turbolent/prettier"
+
+	"github.com/onflow/cadence/runtime/ast"
+)
+
+// logicalExpression is a chain of the same logical operator, e.g. `a && b && c`,
+// which breaks before or after each operator when it does not fit, all at once
+type logicalExpression struct {
+	*ast.BinaryExpression
+	position OperatorPosition
+}
+
+// logical returns the binary expression printing it as a logical chain,
+// if it is one and logical operators are broken, or nil.
+// The operators of the chain are printed by it, so only the operands are replaced
+func (p printer) logical(expression *ast.BinaryExpression, visited map[uintptr]bool) *logicalExpression {
+	if !p.options.enabled(RuleBreakLogicalOperators) {
+		return nil
+	}
+	switch expression.Operation {
+	case ast.OperationAnd, ast.OperationOr:
+	default:
+		return nil
+	}
+
+	//the chain is nested to the left, as the operators are left associative
+	for binary := expression; ; {
+		visited[reflect.ValueOf(binary).Pointer()] = true
+		value := reflect.ValueOf(binary).Elem()
+		p.replace(value.FieldByName("Right"), visited)
+		left, ok := binary.Left.(*ast.BinaryExpression)
+		if !ok || left.Operation != expression.Operation {
+			p.replace(value.FieldByName("Left"), visited)
+			break
+		}
+		binary = left
+	}
+
+	return &logicalExpression{
+		BinaryExpression: expression,
+		position:         p.options.OperatorPosition,
+	}
+}
+
+func (e *logicalExpression) Doc() prettier.Doc {
+	//the AST prints the operands of each operator, in parentheses if needed,
+	//before and after it
+	var operands []prettier.Doc
+	for binary := e.BinaryExpression; ; {
+		doc := binary.Doc().(prettier.Group).Doc.(prettier.Concat)
+		operands = append(operands, doc[len(doc)-1].(prettier.Group).Doc)
+		left, ok := binary.Left.(*ast.BinaryExpression)
+		if !ok || left.Operation != e.Operation {
+			operands = append(operands, doc[0].(prettier.Group).Doc)
+			break
+		}
+		binary = left
+	}
+
+	symbol := e.Operation.Symbol()
+	var rest prettier.Concat
+	for i := len(operands) - 2; i >= 0; i-- {
+		if e.position == OperatorsTrailing {
+			rest = append(rest, prettier.Text(" "+symbol), prettier.Line{}, operands[i])
+		} else {
+			rest = append(rest, prettier.Line{}, prettier.Text(symbol+" "), operands[i])
+		}
+	}
+	return prettier.Group{
+		Doc: prettier.Concat{
+			operands[len(operands)-1],
+			prettier.Indent{Doc: rest},
+		},
+	}
+}
@@ -0,0 +1,211 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/parser"
+)
+
+// memberLines are the lines of a member declaration,
+// with the comments on the lines directly before it and after it on its last line
+type memberLines struct {
+	// first and last are the indices of the lines
+	first, last int
+	// rank is the position of the kind of the member in the canonical order
+	rank int
+}
+
+// memberRank is the position of the member in the canonical order:
+// events, fields, initializers and destructors, public functions, other functions,
+// and then the other members, e.g. nested composites
+func memberRank(declaration ast.Declaration) int {
+	switch declaration.DeclarationKind() {
+	case common.DeclarationKindEvent:
+		return 0
+	case common.DeclarationKindField:
+		return 1
+	case common.DeclarationKindInitializer, common.DeclarationKindDestructor:
+		return 2
+	case common.DeclarationKindFunction:
+		if declaration.DeclarationAccess() == ast.AccessPublic {
+			return 3
+		}
+		return 4
+	}
+	return 5
+}
+
+// reorderMembers orders the members of contracts, resources and structs, and of their interfaces,
+// canonically, see memberRank. Members of the same rank keep their order.
+// Comments on the lines directly before a member, and after it on its last line, move with it,
+// and blank lines stay where they are.
+// The members of a body are kept if one of them shares its lines with other code, or is in an off region
+func reorderMembers(code string) (string, error) {
+	//each pass reorders the first body which is not ordered yet,
+	//moving members as a whole keeps the order of the bodies inside of them
+	skipped := map[int]bool{}
+	for {
+		program, err := parser.ParseProgram(nil, []byte(code), parser.Config{})
+		if err != nil {
+			return "", err
+		}
+		container, chunks := unorderedMembers(code, program.Declarations(), skipped)
+		if chunks == nil {
+			return code, nil
+		}
+		if !orderable(code, container, chunks) {
+			skipped[container.StartPosition().Offset] = true
+			continue
+		}
+		code = arrangeMembers(code, chunks)
+	}
+}
+
+// unorderedMembers returns the first declaration, in the order of the code,
+// whose members are not in the canonical order, and the lines of its members
+func unorderedMembers(code string, declarations []ast.Declaration, skipped map[int]bool) (ast.Declaration, []memberLines) {
+	for _, declaration := range declarations {
+		members := declaration.DeclarationMembers()
+		if members == nil {
+			continue
+		}
+		if reorderable(declaration) && !skipped[declaration.StartPosition().Offset] {
+			chunks := memberChunks(code, declaration, members.Declarations())
+			if !sort.SliceIsSorted(chunks, func(i, j int) bool { return chunks[i].rank < chunks[j].rank }) {
+				return declaration, chunks
+			}
+		}
+		if container, chunks := unorderedMembers(code, members.Declarations(), skipped); chunks != nil {
+			return container, chunks
+		}
+	}
+	return nil, nil
+}
+
+// reorderable reports if the members of the declaration are reordered:
+// enums keep the order of their cases, and events have no members
+func reorderable(declaration ast.Declaration) bool {
+	switch declaration.DeclarationKind() {
+	case common.DeclarationKindContract,
+		common.DeclarationKindResource,
+		common.DeclarationKindStructure,
+		common.DeclarationKindContractInterface,
+		common.DeclarationKindResourceInterface,
+		common.DeclarationKindStructureInterface:
+		return true
+	}
+	return false
+}
+
+// memberChunks returns the lines of the members, including the comments directly before them
+func memberChunks(code string, container ast.Declaration, members []ast.Declaration) []memberLines {
+	lines := strings.Split(code, "\n")
+	comments := commentOnlyLines(code)
+
+	chunks := make([]memberLines, 0, len(members))
+	previous := container.StartPosition().Line - 1
+	for _, member := range members {
+		chunk := memberLines{
+			first: member.StartPosition().Line - 1,
+			last:  member.EndPosition(nil).Line - 1,
+			rank:  memberRank(member),
+		}
+		for line := chunk.first - 1; line > previous && (isBlank(lines[line]) || comments[line]); line-- {
+			if !isBlank(lines[line]) {
+				chunk.first = line
+			}
+		}
+		chunks = append(chunks, chunk)
+		previous = chunk.last
+	}
+	return chunks
+}
+
+// commentOnlyLines returns the indices of the lines which only have comments
+func commentOnlyLines(code string) map[int]bool {
+	t := newTrivia([]byte(code))
+	result := map[int]bool{}
+	for _, c := range t.comments {
+		lineEnd := strings.IndexByte(code[c.end:], '\n')
+		if lineEnd < 0 {
+			lineEnd = len(code) - c.end
+		}
+		if !c.ownLine || !isBlank(code[c.end:c.end+lineEnd]) {
+			continue
+		}
+		for line := c.line - 1; line < t.line(c.end-1); line++ {
+			result[line] = true
+		}
+	}
+	return result
+}
+
+// orderable reports if each member of the declaration has its own lines, out of off regions
+func orderable(code string, container ast.Declaration, chunks []memberLines) bool {
+	regions := offRegions(code)
+	for _, member := range container.DeclarationMembers().Declarations() {
+		start := member.StartPosition().Offset
+		end := member.EndPosition(nil).Offset + 1
+		lineStart := strings.LastIndex(code[:start], "\n") + 1
+		rest, _, _ := strings.Cut(code[end:], "\n")
+		rest = strings.TrimSpace(rest)
+		if strings.TrimSpace(code[lineStart:start]) != "" || (rest != "" && !strings.HasPrefix(rest, "//")) {
+			return false
+		}
+		for _, region := range regions {
+			if start < region.end && region.start < end {
+				return false
+			}
+		}
+	}
+	//members ending on the line another one starts on share it
+	for i := 1; i < len(chunks); i++ {
+		if chunks[i].first <= chunks[i-1].last {
+			return false
+		}
+	}
+	return true
+}
+
+// arrangeMembers sorts the lines of the members by rank,
+// keeping the blank lines between the i-th and the next member after the i-th member of the order
+func arrangeMembers(code string, chunks []memberLines) string {
+	lines := strings.Split(code, "\n")
+
+	sorted := make([]memberLines, len(chunks))
+	copy(sorted, chunks)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].rank < sorted[j].rank
+	})
+
+	result := append([]string{}, lines[:chunks[0].first]...)
+	for i, chunk := range sorted {
+		result = append(result, lines[chunk.first:chunk.last+1]...)
+		if i+1 < len(chunks) {
+			result = append(result, lines[chunks[i].last+1:chunks[i+1].first]...)
+		}
+	}
+	result = append(result, lines[chunks[len(chunks)-1].last+1:]...)
+	return strings.Join(result, "\n")
+}
@@ -0,0 +1,150 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"reflect"
+	"regexp"
+	"sort"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/parser"
+)
+
+// authAccountType is the Cadence 1.0 type of AuthAccount, a reference with all account entitlements
+const authAccountType = "auth(Storage, Contracts, Keys, Inbox, Capabilities) &Account"
+
+// legacyAccess matches the access modifiers Cadence 1.0 removed, at the start of a declaration
+var legacyAccess = regexp.MustCompile(`^(?:pub\s*\(\s*set\s*\)|pub\b|priv\b)`)
+
+// Migrate rewrites the syntax of Cadence before 1.0 which has a mechanical replacement:
+// the access modifiers pub and pub(set) become access(all), priv becomes access(self),
+// and the types AuthAccount and PublicAccount become references to Account.
+// Fields which were pub(set) can then only be set by the contract, as Cadence 1.0 has no setter access.
+// The result is Cadence 1.0 code, which the parser of this build cannot parse,
+// so Migrate runs on formatted code, after the checks of the formatting
+func Migrate(code string) (string, error) {
+	program, err := parser.ParseProgram(nil, []byte(code), parser.Config{})
+	if err != nil {
+		return "", err
+	}
+
+	var edits []commentEdit
+	//special functions and their function declarations start at the same offset
+	edited := map[int]bool{}
+	seen := map[uintptr]bool{}
+	var visit func(value reflect.Value, reference bool)
+	visit = func(value reflect.Value, reference bool) {
+		switch value.Kind() {
+		case reflect.Interface:
+			if !value.IsNil() {
+				visit(value.Elem(), reference)
+			}
+
+		case reflect.Pointer:
+			if value.IsNil() || seen[value.Pointer()] {
+				return
+			}
+			seen[value.Pointer()] = true
+
+			switch element := value.Interface().(type) {
+			case ast.Declaration:
+				if edit, ok := migrateAccess(code, element); ok && !edited[edit.start] {
+					edited[edit.start] = true
+					edits = append(edits, edit)
+				}
+				//the declarations of members are unexported
+				if members := element.DeclarationMembers(); members != nil {
+					visit(reflect.ValueOf(members.Declarations()), false)
+				}
+			case *ast.NominalType:
+				if edit, ok := migrateAccountType(element, reference); ok {
+					edits = append(edits, edit)
+				}
+				return
+			case *ast.ReferenceType:
+				visit(reflect.ValueOf(element.Type), true)
+				return
+			}
+			visit(value.Elem(), false)
+
+		case reflect.Struct:
+			for i := 0; i < value.NumField(); i++ {
+				if value.Type().Field(i).IsExported() {
+					visit(value.Field(i), false)
+				}
+			}
+
+		case reflect.Slice:
+			for i := 0; i < value.Len(); i++ {
+				visit(value.Index(i), false)
+			}
+		}
+	}
+	visit(reflect.ValueOf(program.Declarations()), false)
+
+	sort.Slice(edits, func(i, j int) bool {
+		return edits[i].start < edits[j].start
+	})
+	//edit from the end, so the offsets of earlier edits stay valid
+	for i := len(edits) - 1; i >= 0; i-- {
+		edit := edits[i]
+		code = code[:edit.start] + edit.text + code[edit.end:]
+	}
+	return code, nil
+}
+
+// migrateAccess returns the edit replacing the legacy access modifier of the declaration
+func migrateAccess(code string, declaration ast.Declaration) (commentEdit, bool) {
+	var replacement string
+	switch declaration.DeclarationAccess() {
+	case ast.AccessPublic, ast.AccessPublicSettable:
+		replacement = "access(all)"
+	case ast.AccessPrivate:
+		replacement = "access(self)"
+	default:
+		return commentEdit{}, false
+	}
+
+	start := declaration.StartPosition().Offset
+	match := legacyAccess.FindStringIndex(code[start:])
+	if match == nil {
+		return commentEdit{}, false
+	}
+	return commentEdit{commentSpan{start, start + match[1]}, replacement}, true
+}
+
+// migrateAccountType returns the edit replacing the account types of Cadence before 1.0.
+// References to them, e.g. &AuthAccount, are kept, as their entitlements are not mechanical
+func migrateAccountType(nominal *ast.NominalType, reference bool) (commentEdit, bool) {
+	if reference || len(nominal.NestedIdentifiers) > 0 {
+		return commentEdit{}, false
+	}
+	var replacement string
+	switch nominal.Identifier.Identifier {
+	case "AuthAccount":
+		replacement = authAccountType
+	case "PublicAccount":
+		replacement = "&Account"
+	default:
+		return commentEdit{}, false
+	}
+	start := nominal.Identifier.Pos.Offset
+	return commentEdit{commentSpan{start, start + len(nominal.Identifier.Identifier)}, replacement}, true
+}
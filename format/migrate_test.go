@@ -0,0 +1,55 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package format
+
+import (
+	"testing"
+)
+
+func TestMigrateIsFormatted(t *testing.T) {
+	code := "pub contract C {\n    pub enum E: UInt8 {\n        pub case a // a\n        pub case b\n    }\n\n    priv let x: Int // x\n\n    init() {\n        self.x = 1\n    }\n}\n"
+	expected := "access(all) contract C {\n    access(all) enum E: UInt8 {\n        access(all) case a // a\n        access(all) case b\n    }\n\n    access(self) let x: Int // x\n\n    init() {\n        self.x = 1\n    }\n}"
+
+	formatted, err := Source(code, DefaultOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrated, err := Migrate(formatted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if migrated != expected {
+		t.Fatalf("expected %q, got %q", expected, migrated)
+	}
+
+	//formatting the migrated code, e.g. in check mode, must not undo the migration
+	reformatted, err := Source(migrated, DefaultOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reformatted != migrated {
+		t.Errorf("expected the migrated code to be formatted, got %q", reformatted)
+	}
+	remigrated, err := Migrate(reformatted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if remigrated != migrated {
+		t.Errorf("expected migrating again to keep the code, got %q", remigrated)
+	}
+}
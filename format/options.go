@@ -0,0 +1,437 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CommentStrategy determines where comments go,
+// when the code around them is laid out differently
+type CommentStrategy string
+
+const (
+	// CommentsReanchor moves comments along with the statement or declaration
+	// they belong to: trailing comments go to the end of its line,
+	// and comments on their own line go above it
+	CommentsReanchor CommentStrategy = "re-anchor"
+	// CommentsStrict keeps comments right after the code they followed,
+	// even if this prevents joining lines
+	CommentsStrict CommentStrategy = "strict"
+)
+
+// ParseCommentStrategy parses the name of a strategy, the empty name is the default
+func ParseCommentStrategy(s string) (CommentStrategy, error) {
+	switch strategy := CommentStrategy(s); strategy {
+	case CommentsReanchor, CommentsStrict:
+		return strategy, nil
+	case "":
+		return CommentsReanchor, nil
+	}
+	return "", fmt.Errorf("invalid comment strategy %q, expected %q or %q", s, CommentsReanchor, CommentsStrict)
+}
+
+// EmptyBodyStyle determines how empty function bodies are printed
+type EmptyBodyStyle string
+
+const (
+	// EmptyBodiesCompact prints `{}`
+	EmptyBodiesCompact EmptyBodyStyle = "compact"
+	// EmptyBodiesSpaced prints `{ }`
+	EmptyBodiesSpaced EmptyBodyStyle = "spaced"
+	// EmptyBodiesSplit prints the braces on separate lines
+	EmptyBodiesSplit EmptyBodyStyle = "split"
+)
+
+// ParseEmptyBodyStyle parses the name of a style, the empty name is the default
+func ParseEmptyBodyStyle(s string) (EmptyBodyStyle, error) {
+	switch style := EmptyBodyStyle(s); style {
+	case EmptyBodiesCompact, EmptyBodiesSpaced, EmptyBodiesSplit:
+		return style, nil
+	case "":
+		return EmptyBodiesCompact, nil
+	}
+	return "", fmt.Errorf(
+		"invalid empty body style %q, expected %q, %q or %q",
+		s,
+		EmptyBodiesCompact,
+		EmptyBodiesSpaced,
+		EmptyBodiesSplit,
+	)
+}
+
+// CommentStyle determines if comments are converted to one form
+type CommentStyle string
+
+const (
+	// CommentStyleKeep keeps comments as they are written
+	CommentStyleKeep CommentStyle = "keep"
+	// CommentStyleLine converts block comments on a single line, /* comment */,
+	// to line comments, // comment, if no code follows them on the line
+	CommentStyleLine CommentStyle = "line"
+	// CommentStyleDoc converts the comments documenting declarations to /// comments
+	CommentStyleDoc CommentStyle = "doc"
+	// CommentStyleDocBlock converts the line comments documenting declarations
+	// to /** */ comments, one for each group of consecutive lines
+	CommentStyleDocBlock CommentStyle = "doc-block"
+)
+
+// ParseCommentStyle parses the name of a style, the empty name is the default
+func ParseCommentStyle(s string) (CommentStyle, error) {
+	switch style := CommentStyle(s); style {
+	case CommentStyleKeep, CommentStyleLine, CommentStyleDoc, CommentStyleDocBlock:
+		return style, nil
+	case "":
+		return CommentStyleKeep, nil
+	}
+	return "", fmt.Errorf(
+		"invalid comment style %q, expected %q, %q, %q or %q",
+		s,
+		CommentStyleKeep,
+		CommentStyleLine,
+		CommentStyleDoc,
+		CommentStyleDocBlock,
+	)
+}
+
+// ParameterWrapStyle determines how parameter lists are broken when a signature does not fit
+type ParameterWrapStyle string
+
+const (
+	// ParametersSeparate prints each parameter on its own line
+	ParametersSeparate ParameterWrapStyle = "separate"
+	// ParametersPacked prints as many parameters on each line as fit
+	ParametersPacked ParameterWrapStyle = "packed"
+)
+
+// ParseParameterWrapStyle parses the name of a style, the empty name is the default
+func ParseParameterWrapStyle(s string) (ParameterWrapStyle, error) {
+	switch style := ParameterWrapStyle(s); style {
+	case ParametersSeparate, ParametersPacked:
+		return style, nil
+	case "":
+		return ParametersSeparate, nil
+	}
+	return "", fmt.Errorf("invalid parameter wrap style %q, expected %q or %q", s, ParametersSeparate, ParametersPacked)
+}
+
+// EnumCaseStyle determines how the cases of enums are printed
+type EnumCaseStyle string
+
+const (
+	// EnumCasesSeparate prints each case on its own line
+	EnumCasesSeparate EnumCaseStyle = "separate"
+	// EnumCasesPacked prints as many cases on each line as fit, separated by semicolons
+	EnumCasesPacked EnumCaseStyle = "packed"
+)
+
+// ParseEnumCaseStyle parses the name of a style, the empty name is the default
+func ParseEnumCaseStyle(s string) (EnumCaseStyle, error) {
+	switch style := EnumCaseStyle(s); style {
+	case EnumCasesSeparate, EnumCasesPacked:
+		return style, nil
+	case "":
+		return EnumCasesSeparate, nil
+	}
+	return "", fmt.Errorf("invalid enum case style %q, expected %q or %q", s, EnumCasesSeparate, EnumCasesPacked)
+}
+
+// ReturnTypeStyle determines where the return type goes when a signature is broken
+type ReturnTypeStyle string
+
+const (
+	// ReturnTypeSameLine prints the return type after the closing parenthesis
+	ReturnTypeSameLine ReturnTypeStyle = "same-line"
+	// ReturnTypeOwnLine prints the return type on its own line after the closing parenthesis, indented
+	ReturnTypeOwnLine ReturnTypeStyle = "own-line"
+)
+
+// ParseReturnTypeStyle parses the name of a style, the empty name is the default
+func ParseReturnTypeStyle(s string) (ReturnTypeStyle, error) {
+	switch style := ReturnTypeStyle(s); style {
+	case ReturnTypeSameLine, ReturnTypeOwnLine:
+		return style, nil
+	case "":
+		return ReturnTypeSameLine, nil
+	}
+	return "", fmt.Errorf("invalid return type style %q, expected %q or %q", s, ReturnTypeSameLine, ReturnTypeOwnLine)
+}
+
+// BraceStyle determines where the opening braces of the bodies of declarations go
+type BraceStyle string
+
+const (
+	// BracesSameLine prints the opening brace at the end of the header
+	BracesSameLine BraceStyle = "same-line"
+	// BracesNextLine prints the opening brace on the line after the header,
+	// except for empty bodies
+	BracesNextLine BraceStyle = "next-line"
+)
+
+// ParseBraceStyle parses the name of a style, the empty name is the default
+func ParseBraceStyle(s string) (BraceStyle, error) {
+	switch style := BraceStyle(s); style {
+	case BracesSameLine, BracesNextLine:
+		return style, nil
+	case "":
+		return BracesSameLine, nil
+	}
+	return "", fmt.Errorf("invalid brace style %q, expected %q or %q", s, BracesSameLine, BracesNextLine)
+}
+
+// LineEndings determines the line endings of the formatted code
+type LineEndings string
+
+const (
+	// LineEndingsAuto keeps the line ending used by most lines of the code,
+	// or \n if the code has no line break
+	LineEndingsAuto LineEndings = "auto"
+	// LineEndingsLF ends the lines with \n
+	LineEndingsLF LineEndings = "lf"
+	// LineEndingsCRLF ends the lines with \r\n
+	LineEndingsCRLF LineEndings = "crlf"
+)
+
+// ParseLineEndings parses the name of the line endings, the empty name is the default
+func ParseLineEndings(s string) (LineEndings, error) {
+	switch endings := LineEndings(s); endings {
+	case LineEndingsAuto, LineEndingsLF, LineEndingsCRLF:
+		return endings, nil
+	case "":
+		return LineEndingsAuto, nil
+	}
+	return "", fmt.Errorf("invalid line endings %q, expected %q, %q or %q", s, LineEndingsAuto, LineEndingsLF, LineEndingsCRLF)
+}
+
+// ending returns the line ending of the formatted code
+func (l LineEndings) ending(code string) string {
+	switch l {
+	case LineEndingsLF:
+		return "\n"
+	case LineEndingsCRLF:
+		return "\r\n"
+	}
+	crlf := strings.Count(code, "\r\n")
+	if crlf > strings.Count(code, "\n")-crlf {
+		return "\r\n"
+	}
+	return "\n"
+}
+
+// Convert returns the text with the line endings chosen for the code
+func (l LineEndings) Convert(text string, code string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if ending := l.ending(code); ending != "\n" {
+		text = strings.ReplaceAll(text, "\n", ending)
+	}
+	return text
+}
+
+// OperatorPosition determines where the operators of broken logical expressions go
+type OperatorPosition string
+
+const (
+	// OperatorsLeading prints the operators at the start of the continuation lines
+	OperatorsLeading OperatorPosition = "leading"
+	// OperatorsTrailing prints the operators at the end of the broken lines
+	OperatorsTrailing OperatorPosition = "trailing"
+)
+
+// ParseOperatorPosition parses the name of a position, the empty name is the default
+func ParseOperatorPosition(s string) (OperatorPosition, error) {
+	switch position := OperatorPosition(s); position {
+	case OperatorsLeading, OperatorsTrailing:
+		return position, nil
+	case "":
+		return OperatorsLeading, nil
+	}
+	return "", fmt.Errorf("invalid operator position %q, expected %q or %q", s, OperatorsLeading, OperatorsTrailing)
+}
+
+// Rule is a layout rule, which can be disabled
+// when it does not suit a codebase.
+// Rules are flags, so a set of rules is their union
+type Rule uint
+
+const (
+	// RuleWrapConformances wraps conformances onto their own lines when they do not fit
+	RuleWrapConformances Rule = 1 << iota
+	// RuleCollapseEmptyBodies prints empty function bodies in the configured style,
+	// instead of as they are written
+	RuleCollapseEmptyBodies
+	// RuleSeparateMembers separates members with blank lines,
+	// instead of only where they are written
+	RuleSeparateMembers
+	// RuleWrapChains breaks chains of calls of members before each call when they do not fit
+	RuleWrapChains
+	// RuleBreakLogicalOperators breaks chains of && and || at each operator when they do not fit
+	RuleBreakLogicalOperators
+	// RuleBreakBorrowChains breaks chains of calls ending with a call of borrow
+	// together with their ?? fallback when they do not fit
+	RuleBreakBorrowChains
+)
+
+// RuleInfo describes a rule
+type RuleInfo struct {
+	Rule        Rule
+	Name        string
+	Description string
+}
+
+// Rules are all rules, all are enabled by default
+var Rules = []RuleInfo{
+	{
+		Rule:        RuleWrapConformances,
+		Name:        "wrapConformances",
+		Description: "wrap conformances onto their own lines when they do not fit",
+	},
+	{
+		Rule:        RuleCollapseEmptyBodies,
+		Name:        "collapseEmptyBodies",
+		Description: "print empty function bodies in the configured style, instead of as written",
+	},
+	{
+		Rule:        RuleSeparateMembers,
+		Name:        "separateMembers",
+		Description: "separate members with blank lines, instead of only where they are written",
+	},
+	{
+		Rule:        RuleWrapChains,
+		Name:        "wrapChains",
+		Description: "break chains of calls of members before each call when they do not fit",
+	},
+	{
+		Rule:        RuleBreakLogicalOperators,
+		Name:        "breakLogicalOperators",
+		Description: "break chains of && and || at each operator when they do not fit",
+	},
+	{
+		Rule:        RuleBreakBorrowChains,
+		Name:        "breakBorrowChains",
+		Description: "break chains of calls ending with borrow together with their ?? fallback when they do not fit",
+	},
+}
+
+// ParseRule parses the name of a rule
+func ParseRule(name string) (Rule, error) {
+	for _, info := range Rules {
+		if info.Name == name {
+			return info.Rule, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown rule %q, see cadencefmt rules list", name)
+}
+
+// Options configure the formatting
+type Options struct {
+	MaxLineLength int
+	Tabs          bool
+	Comments      CommentStrategy
+	EmptyBodies   EmptyBodyStyle
+	CommentStyle  CommentStyle
+	// GroupFields keeps fields of the same access level together,
+	// and only separates the groups with blank lines
+	GroupFields bool
+	// AlignComments aligns the trailing line comments of consecutive lines to a common column
+	AlignComments bool
+	// AlignValues aligns the values of dictionary literals with an entry on each line,
+	// and the = of consecutive assignments, if the lines stay within the width
+	AlignValues bool
+	// AlignParameters aligns the types of broken parameter lists with a parameter on each line,
+	// if the lines stay within the width
+	AlignParameters bool
+	// ReflowDocs re-wraps the paragraphs of doc comments to the line length
+	ReflowDocs bool
+	// MaxBlankLines is the maximum of consecutive blank lines of the code which are kept.
+	// Blank lines the layout adds, e.g. between members, are not affected
+	MaxBlankLines int
+	// ExactBlankLines separates declarations of the program and members with exactly one blank line,
+	// even if RuleSeparateMembers is disabled or MaxBlankLines keeps more.
+	// Fields of the same group, imports of the same group and enum cases stay together
+	ExactBlankLines bool
+	// ReorderMembers orders the members of contracts, resources and structs:
+	// events, fields, initializers, public functions, other functions, and then the other members
+	ReorderMembers bool
+	// SortImports sorts the imports at the top of the code alphabetically
+	SortImports bool
+	// CleanImports removes duplicate imports, and imports of names the code never references
+	CleanImports bool
+	// NormalizeEscapes writes the \u{...} escapes of string literals with upper case digits
+	// and without leading zeros, keeping the values of the strings
+	NormalizeEscapes bool
+	// ImportGroups is the comma separated order of the import groups, e.g. DefaultImportGroups.
+	// Imports are ordered by group, and the groups are separated with blank lines.
+	// Grouping is disabled if it is empty
+	ImportGroups string
+	// CoreContracts are the comma separated names of the contracts in the core import group,
+	// DefaultCoreContracts if it is empty
+	CoreContracts string
+	// TrailingCommas adds a comma after the last parameter or argument of lists the layout breaks
+	TrailingCommas bool
+	// OneLineFunctions keeps the bodies of functions with a single simple statement
+	// on the line of the signature, if they fit
+	OneLineFunctions bool
+	// OneLineCases keeps the cases of switch statements with a single simple statement
+	// on the line of the case, if they fit, and aligns the statements of consecutive ones
+	OneLineCases bool
+	// KeepByteOrderMark keeps the byte order mark at the start of the code, which is removed otherwise
+	KeepByteOrderMark bool
+	// CollectionElements is the number of elements of array and dictionary literals
+	// above which they are broken, one element per line, 0 for no limit
+	CollectionElements int
+	// CollectionWidth is the width of array and dictionary literals printed on one line
+	// above which they are broken, even if the line fits, 0 for no limit
+	CollectionWidth int
+	// ParameterWrap and ReturnType determine how signatures which do not fit are broken
+	ParameterWrap ParameterWrapStyle
+	ReturnType    ReturnTypeStyle
+	// EnumCases determines how the cases of enums are printed
+	EnumCases EnumCaseStyle
+	// OperatorPosition is where the operators of broken chains of && and || go
+	OperatorPosition OperatorPosition
+	// Braces is where the opening braces of composites and functions go
+	Braces BraceStyle
+	// LineEndings are the line endings of the formatted code
+	LineEndings LineEndings
+	// DisabledRules are the rules which are not applied
+	DisabledRules Rule
+	// StyleRules are the style rules which are applied
+	StyleRules StyleRule
+}
+
+func (o Options) enabled(rule Rule) bool {
+	return o.DisabledRules&rule == 0
+}
+
+// DefaultOptions are the options used when nothing is configured
+var DefaultOptions = Options{
+	MaxLineLength: 80,
+	Comments:      CommentsReanchor,
+	EmptyBodies:   EmptyBodiesCompact,
+	CommentStyle:  CommentStyleKeep,
+	MaxBlankLines: 1,
+	ParameterWrap: ParametersSeparate,
+	ReturnType:    ReturnTypeSameLine,
+	EnumCases:     EnumCasesSeparate,
+
+	OperatorPosition: OperatorsLeading,
+	Braces:           BracesSameLine,
+	LineEndings:      LineEndingsAuto,
+}
@@ -0,0 +1,78 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"testing"
+)
+
+func TestSourceParentheses(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		expected string
+	}{
+		{
+			name:     "condition",
+			code:     "pub fun f(a: Int, b: Int) {\n    if (a > b) { return }\n}\n",
+			expected: "pub fun f(a: Int, b: Int) {\n    if a > b {\n        return\n    }\n}",
+		},
+		{
+			name:     "returned expression",
+			code:     "pub fun f(a: Int, b: Int): Int {\n    return (a + b)\n}\n",
+			expected: "pub fun f(a: Int, b: Int): Int {\n    return a + b\n}",
+		},
+		{
+			name:     "needed for precedence",
+			code:     "pub fun f(a: Int, b: Int): Int {\n    return (a + b) * 2\n}\n",
+			expected: "pub fun f(a: Int, b: Int): Int {\n    return (a + b) * 2\n}",
+		},
+		{
+			name:     "redundant for precedence",
+			code:     "pub fun f(a: Int, b: Int): Int {\n    return a + (b * 2)\n}\n",
+			expected: "pub fun f(a: Int, b: Int): Int {\n    return a + b * 2\n}",
+		},
+		{
+			name:     "needed for associativity",
+			code:     "pub fun f(a: Int, b: Int, c: Int): Int {\n    return (a - (b - c))\n}\n",
+			expected: "pub fun f(a: Int, b: Int, c: Int): Int {\n    return a - (b - c)\n}",
+		},
+		{
+			name:     "nested",
+			code:     "pub fun f(a: Int): Int {\n    return ((a))\n}\n",
+			expected: "pub fun f(a: Int): Int {\n    return a\n}",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			formatted, err := Source(test.code, DefaultOptions)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if formatted != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, formatted)
+			}
+			//the parentheses are not in the syntax tree, so it is the same without them
+			if err := VerifyAST(test.code, formatted, DefaultOptions); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
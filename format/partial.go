@@ -0,0 +1,227 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/onflow/cadence/runtime/parser"
+	"github.com/onflow/cadence/runtime/parser/lexer"
+)
+
+// brokenMarker is the comment standing in for a declaration which does not parse while the code is formatted
+const brokenMarker = "//cadencefmt:broken:"
+
+// declarationKeywords start top-level declarations
+var declarationKeywords = map[string]bool{
+	"pub":         true,
+	"priv":        true,
+	"access":      true,
+	"import":      true,
+	"fun":         true,
+	"let":         true,
+	"var":         true,
+	"contract":    true,
+	"resource":    true,
+	"struct":      true,
+	"event":       true,
+	"enum":        true,
+	"attachment":  true,
+	"transaction": true,
+}
+
+// BrokenDeclaration is a top-level declaration which does not parse,
+// and which partial formatting keeps as written
+type BrokenDeclaration struct {
+	// StartLine and EndLine are the lines of the declaration, starting at 1
+	StartLine int
+	EndLine   int
+	// Err is the parse error of the declaration, with positions in the code
+	Err error
+}
+
+// Partial formats code in which some top-level declarations do not parse:
+// the declarations which parse are formatted, and the broken ones are kept as written and returned.
+// The check is called with the code and the formatted code in which the broken declarations
+// are replaced by marker comments, so the usual checks, e.g. Verify, apply to the rest.
+// If no declaration can be made out as broken, the parse error of the code is returned
+func Partial(code string, options Options, check func(code, formatted string) error) (string, []BrokenDeclaration, error) {
+	code, byteOrderMarked, err := decode(code)
+	if err != nil {
+		return "", nil, err
+	}
+	//the header comment of the code may set the line endings
+	fileOptions, err := FileOptions(code, options)
+	if err != nil {
+		return "", nil, err
+	}
+	directives, rest := splitDirectives(code)
+	code = blankDirectives(directives, rest)
+
+	var broken []BrokenDeclaration
+	var spans []commentSpan
+	for _, s := range topLevelDeclarations(code) {
+		//parse the declaration on its own, at its position in the code
+		_, err := parser.ParseProgram(nil, []byte(blankOut(code, s)), parser.Config{})
+		if err == nil {
+			continue
+		}
+		broken = append(broken, BrokenDeclaration{
+			StartLine: strings.Count(code[:s.start], "\n") + 1,
+			EndLine:   strings.Count(code[:s.end], "\n") + 1,
+			Err:       err,
+		})
+		spans = append(spans, s)
+	}
+
+	if len(broken) == 0 {
+		_, err := parser.ParseProgram(nil, []byte(code), parser.Config{})
+		if err == nil {
+			err = errors.New("no broken declaration found")
+		}
+		return "", nil, err
+	}
+
+	var marked strings.Builder
+	regions := make(verbatimRegions, 0, len(spans))
+	from := 0
+	for i, s := range spans {
+		marked.WriteString(code[from:s.start])
+		fmt.Fprintf(&marked, "%s%d", brokenMarker, i)
+		regions = append(regions, code[s.start:s.end])
+		from = s.end
+	}
+	marked.WriteString(code[from:])
+
+	formatted, err := Source(marked.String(), options)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := check(marked.String(), formatted); err != nil {
+		return "", nil, err
+	}
+
+	result, ok := regions.insert(formatted, brokenMarker)
+	if !ok {
+		return "", nil, &SafetyError{Check: "partial", Message: "a broken declaration was lost"}
+	}
+	result = withDirectives(directives, rest, result)
+	return encode(fileOptions.LineEndings.Convert(result, code), byteOrderMarked, fileOptions), broken, nil
+}
+
+// topLevelDeclarations returns the spans of the top-level declarations of the code, which may not parse.
+// A declaration starts at a declaration keyword at the start of a line outside of braces,
+// as unclosed parentheses are more common in broken code than declarations inside of them,
+// and spans whole lines, up to its last token and the comments on the same line
+func topLevelDeclarations(code string) []commentSpan {
+	tokens := lexer.Lex([]byte(code), nil)
+	defer tokens.Reclaim()
+
+	var spans []commentSpan
+	start := -1
+	end := 0
+	depth := 0
+	commentStart := 0
+	for {
+		token := tokens.Next()
+		if token.Is(lexer.TokenEOF) {
+			break
+		}
+
+		offset := token.StartPos.Offset
+		switch token.Type {
+		case lexer.TokenSpace, lexer.TokenBlockCommentContent:
+			continue
+
+		case lexer.TokenLineComment:
+			continue
+
+		case lexer.TokenBlockCommentStart:
+			commentStart = offset
+			continue
+
+		case lexer.TokenBlockCommentEnd:
+			//comments starting on the last line of a declaration belong to it
+			if start >= 0 && commentStart < end {
+				end = lineEnd(code, token.EndPos.Offset+1)
+			}
+			continue
+		}
+
+		lineStart := strings.LastIndex(code[:offset], "\n") + 1
+		startsLine := strings.TrimSpace(code[lineStart:offset]) == ""
+		keyword := token.Is(lexer.TokenPragma) ||
+			(token.Is(lexer.TokenIdentifier) && declarationKeywords[extractTokenText(code, token)])
+
+		if start < 0 || (depth == 0 && startsLine && keyword) {
+			if start >= 0 {
+				spans = append(spans, commentSpan{start, end})
+			}
+			start = lineStart
+		}
+
+		switch token.Type {
+		case lexer.TokenBraceOpen:
+			depth++
+		case lexer.TokenBraceClose:
+			depth = max(depth-1, 0)
+		}
+		end = lineEnd(code, token.EndPos.Offset+1)
+	}
+
+	if start >= 0 {
+		spans = append(spans, commentSpan{start, end})
+	}
+	return spans
+}
+
+// lineEnd is the offset of the end of the line of the offset, without its trailing whitespace
+func lineEnd(code string, offset int) int {
+	rest, _, _ := strings.Cut(code[offset:], "\n")
+	return offset + len(strings.TrimRight(rest, " \t\r"))
+}
+
+// blankOut replaces the code outside of the span with spaces, keeping the line breaks,
+// so the span parses on its own at the same positions
+func blankOut(code string, s commentSpan) string {
+	result := []byte(code)
+	for i := range result {
+		if (i < s.start || i >= s.end) && result[i] != '\n' {
+			result[i] = ' '
+		}
+	}
+	return string(result)
+}
+
+// brokenMarkers are the spans of the marker comments of broken declarations
+func brokenMarkers(code string) []commentSpan {
+	var spans []commentSpan
+	from := 0
+	for {
+		index := strings.Index(code[from:], brokenMarker)
+		if index < 0 {
+			return spans
+		}
+		start := from + index
+		from = lineEnd(code, start)
+		spans = append(spans, commentSpan{start, from})
+	}
+}
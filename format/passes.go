@@ -0,0 +1,96 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"fmt"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/parser"
+)
+
+// Pass changes formatted code after the layout and the comments are merged,
+// e.g. to insert a license header or to apply the rewrites of a project.
+// Passes are registered with WithPasses, and run in order on the result of the previous one
+type Pass interface {
+	// Name identifies the pass in its errors
+	Name() string
+	// Apply returns the changed code of the file
+	Apply(file *PassFile) (string, error)
+}
+
+// PassFile is the formatted code a pass changes.
+// Its syntax tree and tokens are only parsed when a pass asks for them
+type PassFile struct {
+	Code    string
+	Options Options
+
+	program *ast.Program
+	tokens  []Token
+}
+
+// Program is the syntax tree of the code
+func (f *PassFile) Program() (*ast.Program, error) {
+	if f.program == nil {
+		program, err := parser.ParseProgram(nil, []byte(f.Code), parser.Config{})
+		if err != nil {
+			return nil, err
+		}
+		f.program = program
+	}
+	return f.program, nil
+}
+
+// Tokens are the tokens of the code, including its comments and spaces
+func (f *PassFile) Tokens() []Token {
+	if f.tokens == nil {
+		f.tokens = tokens(f.Code)
+	}
+	return f.tokens
+}
+
+// PassFunc is a pass of a function, for passes without state
+func PassFunc(name string, apply func(file *PassFile) (string, error)) Pass {
+	return passFunc{name: name, apply: apply}
+}
+
+type passFunc struct {
+	name  string
+	apply func(file *PassFile) (string, error)
+}
+
+func (p passFunc) Name() string {
+	return p.name
+}
+
+func (p passFunc) Apply(file *PassFile) (string, error) {
+	return p.apply(file)
+}
+
+// runPasses runs the passes in order on the formatted code
+func runPasses(passes []Pass, code string, options Options) (string, error) {
+	for _, pass := range passes {
+		result, err := pass.Apply(&PassFile{Code: code, Options: options})
+		if err != nil {
+			return "", fmt.Errorf("pass %s: %w", pass.Name(), err)
+		}
+		code = result
+	}
+	return code, nil
+}
@@ -0,0 +1,75 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"testing"
+)
+
+func TestSourcePragmas(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		expected string
+	}{
+		{
+			name:     "consecutive pragmas",
+			code:     "#allowAccountLinking\n#b\npub fun f() {}\n",
+			expected: "#allowAccountLinking\n#b\n\npub fun f() {}",
+		},
+		{
+			name:     "separated pragmas",
+			code:     "#allowAccountLinking\n\n#b\n\npub fun f() {}\n",
+			expected: "#allowAccountLinking\n\n#b\n\npub fun f() {}",
+		},
+		{
+			name:     "pragma after imports",
+			code:     "import A from 0x1\n#allowAccountLinking\npub fun f() {}\n",
+			expected: "import A from 0x1\n\n#allowAccountLinking\n\npub fun f() {}",
+		},
+		{
+			name:     "commented pragmas",
+			code:     "// linking\n#allowAccountLinking\n#b // trailing\npub fun f() {}\n",
+			expected: "// linking\n#allowAccountLinking\n#b // trailing\n\npub fun f() {}",
+		},
+		{
+			name:     "pragma between declarations",
+			code:     "pub fun f() {}\n#allowAccountLinking\npub fun g() {}\n",
+			expected: "pub fun f() {}\n\n#allowAccountLinking\n\npub fun g() {}",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			formatted, err := Source(test.code, DefaultOptions)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if formatted != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, formatted)
+			}
+			if err := Verify(test.code, formatted, DefaultOptions); err != nil {
+				t.Error(err)
+			}
+			if err := VerifyAST(test.code, formatted, DefaultOptions); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
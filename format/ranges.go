@@ -0,0 +1,320 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/parser"
+)
+
+// LineRange is an inclusive range of lines, starting at 1
+type LineRange struct {
+	From int
+	To   int
+}
+
+// ParseLineRange parses a range of the form "from:to"
+func ParseLineRange(s string) (LineRange, error) {
+	from, to, ok := strings.Cut(s, ":")
+	if !ok {
+		return LineRange{}, fmt.Errorf("invalid line range %q, expected from:to", s)
+	}
+
+	var r LineRange
+	var err error
+	if r.From, err = strconv.Atoi(from); err != nil {
+		return LineRange{}, fmt.Errorf("invalid line range %q: %w", s, err)
+	}
+	if r.To, err = strconv.Atoi(to); err != nil {
+		return LineRange{}, fmt.Errorf("invalid line range %q: %w", s, err)
+	}
+	if r.From < 1 || r.To < r.From {
+		return LineRange{}, fmt.Errorf("invalid line range %q", s)
+	}
+
+	return r, nil
+}
+
+func (r LineRange) overlaps(first, last int) bool {
+	return r.From <= last && first <= r.To
+}
+
+// span is a run of whole lines holding sibling declarations,
+// which can be formatted on its own and spliced back in
+type span struct {
+	first int
+	last  int
+	// parent is the declaration containing the siblings,
+	// nil for top-level declarations
+	parent ast.Declaration
+	depth  int
+}
+
+// Lines formats only the declarations touching the given line ranges,
+// and leaves every other byte of the code unchanged.
+// The check, if not nil, runs on each formatted span, with the code and the options it was formatted with,
+// as the spans are complete programs, unlike the result
+func Lines(code string, options Options, ranges []LineRange, check func(code, formatted string, options Options) error) (string, error) {
+	code, marked, err := decode(code)
+	if err != nil {
+		return "", err
+	}
+
+	//the spans do not have the header comment of the code
+	options, err = FileOptions(code, options)
+	if err != nil {
+		return "", err
+	}
+
+	//the directive lines are blank while the code is formatted, and written back as they were
+	directives, rest := splitDirectives(code)
+	code = blankDirectives(directives, rest)
+
+	program, err := parser.ParseProgram(nil, []byte(code), parser.Config{})
+	if err != nil {
+		return "", err
+	}
+
+	lines := strings.SplitAfter(code, "\n")
+
+	//the spans are formatted with \n line endings, which are converted when they are written
+	ending := options.LineEndings.ending(code)
+	options.LineEndings = LineEndingsLF
+
+	var spans []span
+	for _, r := range ranges {
+		s, ok := findSpan(lines, program.Declarations(), nil, 0, r)
+		if !ok {
+			continue
+		}
+		spans = append(spans, s)
+	}
+
+	spans = mergeSpans(spans)
+
+	var result strings.Builder
+	next := 1
+
+	for _, s := range spans {
+		for ; next < s.first; next++ {
+			result.WriteString(lines[next-1])
+		}
+
+		formatted, err := prettySpan(lines, s, options, check)
+		if err != nil {
+			return "", err
+		}
+		result.WriteString(strings.ReplaceAll(formatted, "\n", ending))
+		if strings.HasSuffix(lines[s.last-1], "\n") {
+			result.WriteString(ending)
+		}
+
+		next = s.last + 1
+	}
+
+	for ; next <= len(lines); next++ {
+		result.WriteString(lines[next-1])
+	}
+
+	formatted := directives + result.String()[strings.Count(directives, "\n"):]
+	return encode(formatted, marked, options), nil
+}
+
+// findSpan finds the smallest span of declarations touching the range
+func findSpan(
+	lines []string,
+	declarations []ast.Declaration,
+	parent ast.Declaration,
+	depth int,
+	r LineRange,
+) (span, bool) {
+
+	firstIndex, lastIndex := -1, -1
+	for i, declaration := range declarations {
+		if r.overlaps(declaration.StartPosition().Line, declaration.EndPosition(nil).Line) {
+			if firstIndex < 0 {
+				firstIndex = i
+			}
+			lastIndex = i
+		}
+	}
+	if firstIndex < 0 {
+		return span{}, false
+	}
+
+	//siblings sharing a line must be formatted together
+	for firstIndex > 0 &&
+		declarations[firstIndex-1].EndPosition(nil).Line == declarations[firstIndex].StartPosition().Line {
+
+		firstIndex--
+	}
+	for lastIndex < len(declarations)-1 &&
+		declarations[lastIndex+1].StartPosition().Line == declarations[lastIndex].EndPosition(nil).Line {
+
+		lastIndex++
+	}
+
+	first := declarations[firstIndex]
+	last := declarations[lastIndex]
+
+	//prefer the members, if the range is inside a single composite
+	if firstIndex == lastIndex && hasFormattableMembers(first) {
+		members := first.DeclarationMembers().Declarations()
+		if s, ok := findSpan(lines, members, first, depth+1, r); ok {
+			return s, true
+		}
+	}
+
+	s := span{
+		first:  first.StartPosition().Line,
+		last:   last.EndPosition(nil).Line,
+		parent: parent,
+		depth:  depth,
+	}
+
+	//the lines must not contain any code of the parent
+	firstLine := lines[s.first-1]
+	if strings.TrimSpace(firstLine[:first.StartPosition().Column]) != "" {
+		return span{}, false
+	}
+	lastLine := lines[s.last-1]
+	rest := strings.TrimSpace(lastLine[last.EndPosition(nil).Column+1:])
+	if rest != "" && !strings.HasPrefix(rest, "//") {
+		return span{}, false
+	}
+
+	return s, true
+}
+
+func hasFormattableMembers(declaration ast.Declaration) bool {
+	members := declaration.DeclarationMembers()
+	if members == nil || len(members.Declarations()) == 0 {
+		return false
+	}
+
+	//event parameters are members, but not declarations on their own
+	if composite, ok := declaration.(*ast.CompositeDeclaration); ok {
+		return composite.CompositeKind != common.CompositeKindEvent
+	}
+
+	return true
+}
+
+// mergeSpans sorts the spans and merges overlapping ones,
+// keeping the outermost span
+func mergeSpans(spans []span) []span {
+	sort.Slice(spans, func(i, j int) bool {
+		return spans[i].first < spans[j].first
+	})
+
+	var merged []span
+	for _, s := range spans {
+		if len(merged) > 0 {
+			previous := &merged[len(merged)-1]
+			if s.first <= previous.last {
+				if s.depth < previous.depth ||
+					(s.depth == previous.depth && s.parent == previous.parent) {
+
+					s.first = min(s.first, previous.first)
+					s.last = max(s.last, previous.last)
+					*previous = s
+				}
+				continue
+			}
+		}
+		merged = append(merged, s)
+	}
+
+	return merged
+}
+
+// prettySpan formats the lines of the span, without the trailing newline.
+// Members are wrapped in a parent of the same kind,
+// so they parse as a program on their own
+func prettySpan(lines []string, s span, options Options, check func(code, formatted string, options Options) error) (string, error) {
+	code := strings.ReplaceAll(strings.Join(lines[s.first-1:s.last], ""), "\r\n", "\n")
+
+	if s.parent == nil {
+		formatted, err := checkedSource(code, options, check)
+		return strings.TrimRight(formatted, "\n"), err
+	}
+
+	header := wrapperHeader(s.parent)
+	wrapperOptions := options
+	wrapperOptions.MaxLineLength -= 4 * (s.depth - 1)
+
+	formatted, err := checkedSource(header+" {\n"+code+"\n}", wrapperOptions, check)
+	if err != nil {
+		return "", err
+	}
+
+	unit := "    "
+	if options.Tabs {
+		unit = "\t"
+	}
+	indentation := lines[s.first-1][:len(lines[s.first-1])-len(strings.TrimLeft(lines[s.first-1], " \t"))]
+
+	formattedLines := strings.Split(strings.TrimRight(formatted, "\n"), "\n")
+	//drop the wrapper's header and closing brace
+	formattedLines = formattedLines[1 : len(formattedLines)-1]
+
+	for i, line := range formattedLines {
+		if strings.TrimSpace(line) == "" {
+			formattedLines[i] = ""
+			continue
+		}
+		formattedLines[i] = indentation + strings.TrimPrefix(line, unit)
+	}
+
+	return strings.Join(formattedLines, "\n"), nil
+}
+
+// checkedSource formats the code, and runs the check on the result, if any
+func checkedSource(code string, options Options, check func(code, formatted string, options Options) error) (string, error) {
+	formatted, err := Source(code, options)
+	if err != nil || check == nil {
+		return formatted, err
+	}
+	if err := check(code, formatted, options); err != nil {
+		return "", err
+	}
+	return formatted, nil
+}
+
+func wrapperHeader(parent ast.Declaration) string {
+	switch parent := parent.(type) {
+	case *ast.InterfaceDeclaration:
+		return parent.CompositeKind.Keyword() + " interface _"
+	case *ast.AttachmentDeclaration:
+		return "attachment _ for AnyStruct"
+	case *ast.CompositeDeclaration:
+		if parent.CompositeKind == common.CompositeKindEnum {
+			return "enum _: UInt8"
+		}
+		return parent.CompositeKind.Keyword() + " _"
+	}
+
+	return "contract _"
+}
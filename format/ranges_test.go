@@ -0,0 +1,54 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package format
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLinesCheck(t *testing.T) {
+	code := "pub contract C {\n    pub fun f() {   }\n\n    pub fun g() {   }\n}\n"
+
+	var checked []string
+	result, err := Lines(code, DefaultOptions, []LineRange{{From: 2, To: 2}}, func(code, formatted string, options Options) error {
+		checked = append(checked, code)
+		return Verify(code, formatted, options)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "pub contract C {\n    pub fun f() {}\n\n    pub fun g() {   }\n}\n"
+	if result != expected {
+		t.Errorf("expected %q, got %q", expected, result)
+	}
+	//the member is checked in the wrapper it is formatted in
+	if len(checked) != 1 || !strings.HasPrefix(checked[0], "contract _ {") {
+		t.Errorf("expected the wrapped span to be checked, got %q", checked)
+	}
+
+	failure := errors.New("failed")
+	_, err = Lines(code, DefaultOptions, []LineRange{{From: 2, To: 2}}, func(string, string, Options) error {
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Errorf("expected the error of the check, got %v", err)
+	}
+}
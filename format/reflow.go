@@ -0,0 +1,246 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/onflow/cadence/runtime/ast"
+)
+
+// listMarkerPattern matches the marker of a list item, e.g. "- " or "2. "
+var listMarkerPattern = regexp.MustCompile(`^(\s*)([-*+]|\d+[.)])\s+`)
+
+// reflowDocEdits re-wraps the paragraphs of the /// and /** */ comments documenting declarations,
+// so their lines fit the width at the indentation of the declaration.
+// Code blocks, headings and indented lines are kept as written, list items are wrapped on their own
+func reflowDocEdits(code string, program *ast.Program, width int) []commentEdit {
+	leading := leadingComments(code)
+
+	var edits []commentEdit
+	var visit func(declarations []ast.Declaration, indent int)
+	visit = func(declarations []ast.Declaration, indent int) {
+		for _, declaration := range declarations {
+			if declarationKind(declaration) == "" {
+				continue
+			}
+			edits = append(edits, reflowComments(code, leading[declaration.StartPosition().Offset], indent, width)...)
+			if members := declaration.DeclarationMembers(); members != nil {
+				visit(members.Declarations(), indent+4)
+			}
+		}
+	}
+	visit(program.Declarations(), 0)
+
+	return edits
+}
+
+// reflowComments returns the edits re-wrapping the doc comments among the leading comments.
+// Consecutive /// comments are one paragraph of text
+func reflowComments(code string, comments []commentSpan, indent int, width int) []commentEdit {
+	var edits []commentEdit
+	var run []commentSpan
+	flush := func() {
+		if len(run) == 0 {
+			return
+		}
+		var lines []string
+		for _, s := range run {
+			lines = append(lines, commentLines(code[s.start:s.end])...)
+		}
+		var result []string
+		for _, line := range reflowLines(lines, width-indent-len("/// ")) {
+			result = append(result, lineComment("///", line))
+		}
+		s := commentSpan{run[0].start, run[len(run)-1].end}
+		edits = append(edits, commentEdit{s, strings.Join(result, "\n"+lineIndent(code, s.start))})
+		run = nil
+	}
+
+	for _, s := range comments {
+		comment := code[s.start:s.end]
+		switch {
+		case !endsLine(code, s.end):
+			flush()
+
+		case strings.HasPrefix(comment, "///") && !strings.HasPrefix(comment, "////"):
+			if len(run) > 0 && strings.TrimSpace(code[run[len(run)-1].end:s.start]) != "" {
+				flush()
+			}
+			run = append(run, s)
+
+		case strings.HasPrefix(comment, "/**"):
+			flush()
+			if text, ok := reflowBlock(comment, width); ok {
+				edits = append(edits, commentEdit{s, text})
+			}
+
+		default:
+			flush()
+		}
+	}
+	flush()
+
+	return edits
+}
+
+// reflowBlock re-wraps a /** */ comment which has its markers on their own lines.
+// The lines in between keep the indentation and the * they are written with
+func reflowBlock(comment string, width int) (string, bool) {
+	lines := strings.Split(comment, "\n")
+	if len(lines) < 3 ||
+		strings.TrimSpace(strings.TrimPrefix(lines[0], "/**")) != "" ||
+		strings.TrimSpace(lines[len(lines)-1]) != "*/" {
+
+		return "", false
+	}
+	body := lines[1 : len(lines)-1]
+
+	//the prefix of the first line with text is the one of all lines
+	prefix := ""
+	starred := false
+	for _, line := range body {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed == "*" {
+			continue
+		}
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		starred = strings.HasPrefix(trimmed, "*")
+		prefix = indent
+		if starred {
+			prefix += "*"
+		}
+		break
+	}
+
+	var texts []string
+	for _, line := range body {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || (starred && trimmed == "*") {
+			texts = append(texts, "")
+			continue
+		}
+		text, ok := strings.CutPrefix(line, prefix)
+		if !ok {
+			return "", false
+		}
+		if starred {
+			text = strings.TrimPrefix(text, " ")
+		}
+		texts = append(texts, strings.TrimRight(text, " \t"))
+	}
+
+	available := width - columns(prefix)
+	if starred {
+		available--
+	}
+	result := []string{lines[0]}
+	for _, text := range reflowLines(texts, available) {
+		switch {
+		case text == "" && starred:
+			result = append(result, prefix)
+		case text == "":
+			result = append(result, "")
+		case starred:
+			result = append(result, prefix+" "+text)
+		default:
+			result = append(result, prefix+text)
+		}
+	}
+	result = append(result, lines[len(lines)-1])
+	return strings.Join(result, "\n"), true
+}
+
+// reflowLines re-wraps the paragraphs of the lines of a comment to the width
+func reflowLines(lines []string, width int) []string {
+	var result []string
+
+	//the paragraph being wrapped, its words are put after the prefixes
+	var words []string
+	first, rest := "", ""
+	flush := func() {
+		if len(words) == 0 {
+			return
+		}
+		line := first + words[0]
+		for _, word := range words[1:] {
+			if columns(line)+1+columns(word) > width {
+				result = append(result, line)
+				line = rest + word
+				continue
+			}
+			line += " " + word
+		}
+		result = append(result, line)
+		words = nil
+	}
+
+	fenced := false
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+
+		switch {
+		case fenced || strings.HasPrefix(trimmed, "```"):
+			flush()
+			if strings.HasPrefix(trimmed, "```") {
+				fenced = !fenced
+			}
+			result = append(result, line)
+
+		case trimmed == "":
+			flush()
+			result = append(result, "")
+
+		case strings.HasPrefix(trimmed, "#"):
+			flush()
+			result = append(result, line)
+
+		case listMarkerPattern.MatchString(line):
+			flush()
+			marker := listMarkerPattern.FindString(line)
+			first = strings.TrimRight(marker, " \t") + " "
+			rest = strings.Repeat(" ", len(first))
+			words = strings.Fields(line[len(marker):])
+
+		case len(words) > 0 && indent == rest:
+			//continues the paragraph or list item
+			words = append(words, strings.Fields(line)...)
+
+		case columns(indent) >= 4:
+			//indented code
+			flush()
+			result = append(result, line)
+
+		default:
+			flush()
+			first, rest = indent, indent
+			words = strings.Fields(line)
+		}
+	}
+	flush()
+
+	return result
+}
+
+// columns is the width of the text, with tabs four columns wide
+func columns(text string) int {
+	return len([]rune(text)) + strings.Count(text, "\t")*3
+}
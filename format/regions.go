@@ -0,0 +1,169 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/onflow/cadence/runtime/parser"
+	"github.com/onflow/cadence/runtime/parser/lexer"
+)
+
+const (
+	offDirective = "cadencefmt:off"
+	onDirective  = "cadencefmt:on"
+	// verbatimMarker is the comment standing in for a region while the code is formatted
+	verbatimMarker = "//cadencefmt:verbatim:"
+)
+
+// errOffRegion is reported for off regions which cannot be left out of the code,
+// e.g. because they start or end inside of an expression
+var errOffRegion = errors.New(offDirective + " regions must contain whole statements or declarations")
+
+// offRegions returns the regions of the code between `// cadencefmt:off` and `// cadencefmt:on` comments,
+// which are kept as written. A region spans the whole lines of the comments and the lines in between.
+// A region without an end comment extends to the end of the code
+func offRegions(code string) []commentSpan {
+	tokens := lexer.Lex([]byte(code), nil)
+	defer tokens.Reclaim()
+	return offRegionsOf(code, tokens)
+}
+
+// offRegionsOf returns the off regions of the code from its tokens,
+// and reverts the tokens to where they were, so they can be read again
+func offRegionsOf(code string, tokens lexer.TokenStream) []commentSpan {
+	cursor := tokens.Cursor()
+	defer tokens.Revert(cursor)
+
+	var regions []commentSpan
+	start := -1
+	for {
+		token := tokens.Next()
+		if token.Is(lexer.TokenEOF) {
+			if start >= 0 {
+				regions = append(regions, commentSpan{start, len(strings.TrimRight(code, " \t\r\n"))})
+			}
+			return regions
+		}
+		if !token.Is(lexer.TokenLineComment) {
+			continue
+		}
+
+		offset := token.StartPos.Offset
+		lineStart := strings.LastIndex(code[:offset], "\n") + 1
+		if strings.TrimSpace(code[lineStart:offset]) != "" {
+			continue
+		}
+
+		//without the trailing whitespace the lexer includes
+		comment := strings.TrimRight(extractTokenText(code, token), " \t")
+		text := strings.TrimSpace(strings.TrimPrefix(comment, "//"))
+		switch {
+		case start < 0 && text == offDirective:
+			start = lineStart
+		case start >= 0 && text == onDirective:
+			regions = append(regions, commentSpan{start, offset + len(comment)})
+			start = -1
+		}
+	}
+}
+
+// outsideRegions returns the edits which are not inside any of the regions
+func outsideRegions(edits []commentEdit, regions []commentSpan) []commentEdit {
+	if len(regions) == 0 {
+		return edits
+	}
+	var result []commentEdit
+	for _, edit := range edits {
+		inside := false
+		for _, region := range regions {
+			if edit.start < region.end && region.start < edit.end {
+				inside = true
+				break
+			}
+		}
+		if !inside {
+			result = append(result, edit)
+		}
+	}
+	return result
+}
+
+// verbatimRegions are the regions of the code which are kept as written,
+// replaced by marker comments while the code is formatted
+type verbatimRegions []string
+
+// disableRegions replaces the off regions of the code, read from its tokens, with marker comments
+func disableRegions(code string, tokens lexer.TokenStream) (string, verbatimRegions) {
+	regions := offRegionsOf(code, tokens)
+	if len(regions) == 0 {
+		return code, nil
+	}
+
+	var result strings.Builder
+	texts := make(verbatimRegions, 0, len(regions))
+	from := 0
+	for i, region := range regions {
+		result.WriteString(code[from:region.start])
+		fmt.Fprintf(&result, "%s%d", verbatimMarker, i)
+		texts = append(texts, code[region.start:region.end])
+		from = region.end
+	}
+	result.WriteString(code[from:])
+	return result.String(), texts
+}
+
+// restore replaces the marker comments of the formatted code with the regions as written.
+// The result must parse, as the layout may have moved the markers out of an expression
+func (r verbatimRegions) restore(formatted string) (string, error) {
+	if len(r) == 0 {
+		return formatted, nil
+	}
+
+	result, ok := r.insert(formatted, verbatimMarker)
+	if !ok {
+		return "", errOffRegion
+	}
+	if _, err := parser.ParseProgram(nil, []byte(result), parser.Config{}); err != nil {
+		return "", errOffRegion
+	}
+	return result, nil
+}
+
+// insert replaces the lines of the marker comments with the regions,
+// and reports if all regions were inserted
+func (r verbatimRegions) insert(formatted string, marker string) (string, bool) {
+	lines := strings.Split(formatted, "\n")
+	inserted := 0
+	for i, line := range lines {
+		index, ok := strings.CutPrefix(strings.TrimSpace(line), marker)
+		if !ok {
+			continue
+		}
+		var n int
+		if _, err := fmt.Sscan(index, &n); err != nil || n < 0 || n >= len(r) {
+			continue
+		}
+		lines[i] = r[n]
+		inserted++
+	}
+	return strings.Join(lines, "\n"), inserted == len(r)
+}
@@ -0,0 +1,72 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"testing"
+)
+
+func TestSourceSemicolons(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		expected string
+	}{
+		{
+			name:     "statements on one line",
+			code:     "pub fun f() {\n    let a = 1; let b = 2;\n}\n",
+			expected: "pub fun f() {\n    let a = 1\n    let b = 2\n}",
+		},
+		{
+			name:     "statement in a block",
+			code:     "pub fun f() {\n    if true { return; }\n}\n",
+			expected: "pub fun f() {\n    if true {\n        return\n    }\n}",
+		},
+		{
+			name:     "declarations",
+			code:     "import A from 0x1;\npub let x = 1; pub let y = 2;\n",
+			expected: "import A from 0x1\n\npub let x = 1\n\npub let y = 2",
+		},
+		{
+			name:     "semicolon in a string",
+			code:     "pub let s = \"a; b\";\n",
+			expected: "pub let s = \"a; b\"",
+		},
+		{
+			name:     "semicolon in a comment",
+			code:     "pub let x = 1; // a; b\n",
+			expected: "pub let x = 1 // a; b",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			formatted, err := Source(test.code, DefaultOptions)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if formatted != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, formatted)
+			}
+			if err := VerifyTokens(test.code, formatted, DefaultOptions); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
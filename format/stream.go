@@ -0,0 +1,182 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"strings"
+)
+
+// FormatTo formats the code like Source, and writes the result to dst as it is merged,
+// instead of building all of it in memory first.
+// The code is parsed before anything is written, so dst is untouched when it has errors.
+// Options which change the result as a whole, like aligning values, and disabled regions
+// still need the whole result before it is written
+func FormatTo(dst io.Writer, src []byte, options Options) error {
+	existingCode, marked, err := decode(string(src))
+	if err != nil {
+		return err
+	}
+
+	options, err = FileOptions(existingCode, options)
+	if err != nil {
+		return err
+	}
+
+	directives, rest := splitDirectives(existingCode)
+	w := &streamWriter{
+		out:        bufio.NewWriter(dst),
+		directives: directives,
+		ending:     options.LineEndings.ending(existingCode),
+	}
+	if directives != "" {
+		w.preamble = options.LineEndings.Convert(directivesBefore(directives, rest), existingCode)
+	}
+	w.preamble = encode(w.preamble, marked, options)
+	w.empty = encode(options.LineEndings.Convert(withDirectives(directives, rest, ""), existingCode), marked, options)
+
+	err = sourceTo(context.Background(), w, strings.ReplaceAll(blankDirectives(directives, rest), "\r\n", "\n"), options)
+	if err != nil {
+		return err
+	}
+	return w.close()
+}
+
+// streamWriter writes the formatted code after the preamble, the byte order mark and
+// the directive lines, with the line endings chosen for the code.
+// Nothing is written until the first formatted code, so errors leave the writer untouched
+type streamWriter struct {
+	out      *bufio.Writer
+	preamble string
+	//empty is written instead of the preamble when there is no code
+	empty      string
+	directives string
+	ending     string
+	started    bool
+}
+
+func (w *streamWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	if !w.started {
+		//the blank directive lines may be kept before comments
+		if w.directives != "" {
+			p = bytes.TrimLeft(p, "\n")
+		}
+		if len(p) == 0 {
+			return n, nil
+		}
+		w.started = true
+		if _, err := w.out.WriteString(w.preamble); err != nil {
+			return 0, err
+		}
+	}
+
+	//like Convert, the line breaks of the code are replaced, including \r\n in comments
+	for len(p) > 0 {
+		end := bytes.IndexByte(p, '\n')
+		if end == -1 {
+			_, err := w.out.Write(p)
+			return n, err
+		}
+		w.out.Write(bytes.TrimSuffix(p[:end], []byte("\r")))
+		if _, err := w.out.WriteString(w.ending); err != nil {
+			return 0, err
+		}
+		p = p[end+1:]
+	}
+	return n, nil
+}
+
+// close writes the preamble if there was no code, and flushes the output
+func (w *streamWriter) close() error {
+	if !w.started {
+		w.out.WriteString(w.empty)
+	}
+	return w.out.Flush()
+}
+
+// lineWriter writes the merged code to dst line by line,
+// keeping the line which is written for the merge to look back at
+type lineWriter struct {
+	dst io.Writer
+	//indenter indents the lines with tabs, if tabs are used, and removes the indentation of blank lines
+	indenter indenter
+	line     []byte
+	//last is the last character written which is not whitespace
+	last byte
+	err  error
+}
+
+func (w *lineWriter) WriteString(s string) {
+	if trimmed := strings.TrimRight(s, " \t\n"); trimmed != "" {
+		w.last = trimmed[len(trimmed)-1]
+	}
+	for {
+		end := strings.IndexByte(s, '\n') + 1
+		if end == 0 {
+			w.line = append(w.line, s...)
+			return
+		}
+		w.line = append(w.line, s[:end]...)
+		w.flush()
+		s = s[end:]
+	}
+}
+
+// flush writes the complete line
+func (w *lineWriter) flush() {
+	if w.err == nil {
+		_, w.err = io.WriteString(w.dst, w.indenter.line(string(w.line[:len(w.line)-1]))+"\n")
+	}
+	w.line = w.line[:0]
+}
+
+// lastLine returns the line which is written
+func (w *lineWriter) lastLine() string {
+	return string(w.line)
+}
+
+// inline reports if the line which is written has code
+func (w *lineWriter) inline() bool {
+	return len(bytes.TrimSpace(w.line)) > 0
+}
+
+// takeLine returns the line which is written and removes it, to write something before it
+func (w *lineWriter) takeLine() string {
+	line := string(w.line)
+	w.line = w.line[:0]
+	return line
+}
+
+// close writes the last line, which has no line break
+func (w *lineWriter) close() {
+	if w.indenter.tabs {
+		//like useTabs, which ends every line with a line break
+		w.line = append(w.line, '\n')
+		w.flush()
+		return
+	}
+	if w.err == nil && len(w.line) > 0 {
+		_, w.err = w.dst.Write(w.line)
+	}
+	w.line = w.line[:0]
+}
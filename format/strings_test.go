@@ -0,0 +1,112 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/parser"
+)
+
+func TestSourceStrings(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    string
+		literal string
+	}{
+		{
+			name:    "longer than the columns",
+			code:    "pub fun f() {\n    log(\"a very long string which does not fit in the columns at all\")\n}\n",
+			literal: "\"a very long string which does not fit in the columns at all\"",
+		},
+		{
+			name:    "spaces",
+			code:    "pub let s = \"a    b  \"\n",
+			literal: "\"a    b  \"",
+		},
+		{
+			name:    "tabs",
+			code:    "pub let s = \"a\t\tb\t\"\n",
+			literal: "\"a\t\tb\t\"",
+		},
+		{
+			name:    "unicode spaces",
+			code:    "pub let s = \"a\u00a0b\u2003\u3000c \"\n",
+			literal: "\"a\u00a0b\u2003\u3000c \"",
+		},
+		{
+			name:    "escapes",
+			code:    "pub let s = \"\\t\\\"\\\\ \\u{1F600} \\n\"\n",
+			literal: "\"\\t\\\"\\\\ \\u{1F600} \\n\"",
+		},
+		{
+			name:    "argument of a chain",
+			code:    "pub let s = \"x\".concat(\"y, z\").concat(\"(w)\")\n",
+			literal: "\"y, z\"",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, columns := range []int{80, 20, 1} {
+				options := DefaultOptions
+				options.MaxLineLength = columns
+
+				formatted, err := Source(test.code, options)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !strings.Contains(formatted, test.literal) {
+					t.Errorf("%d columns: expected %s in %q", columns, test.literal, formatted)
+				}
+				if err := VerifyTokens(test.code, formatted, options); err != nil {
+					t.Errorf("%d columns: %s", columns, err)
+				}
+			}
+		})
+	}
+}
+
+func TestStringValue(t *testing.T) {
+	literals := []string{
+		`""`,
+		`"plain"`,
+		`"\t\"\\ \u{1F600} \n\0\r\'"`,
+		`"\u{e9}\u{00E9}\u{0}"`,
+		"\"tab\tand\u00a0unicode spaces\"",
+	}
+	for _, literal := range literals {
+		expression, err := parser.ParseExpression(nil, []byte(literal), parser.Config{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := expression.(*ast.StringExpression).Value
+		if value, ok := stringValue(literal); !ok || value != expected {
+			t.Errorf("%s: expected %q, got %q", literal, expected, value)
+		}
+	}
+
+	for _, literal := range []string{`"\u{}"`, `"\u{110000000}"`, `"\x"`, `"\"`, `"unterminated`} {
+		if value, ok := stringValue(literal); ok {
+			t.Errorf("%s: expected an invalid literal, got %q", literal, value)
+		}
+	}
+}
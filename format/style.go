@@ -0,0 +1,163 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/onflow/cadence/runtime/parser/lexer"
+)
+
+// StyleRule is a small change of the spacing of the formatted code.
+// Unlike the layout rules, style rules are off by default
+type StyleRule uint
+
+const (
+	// StyleSpaceAfterComment separates the slashes of line comments from their text with a space
+	StyleSpaceAfterComment StyleRule = 1 << iota
+	// StyleTightBraces removes the spaces inside the braces of bodies on a single line
+	StyleTightBraces
+)
+
+// StyleRuleInfo describes a style rule
+type StyleRuleInfo struct {
+	Rule        StyleRule
+	Name        string
+	Description string
+
+	//comments is true for rules which change comments, they are applied before the layout,
+	//so the comments are placed and checked as they end up
+	comments bool
+	//edits returns the changes of the rule to the code with the tokens
+	edits func(code string, tokens []lexer.Token) []commentEdit
+}
+
+// StyleRules are all style rules, all are disabled by default
+var StyleRules = []StyleRuleInfo{
+	{
+		Rule:        StyleSpaceAfterComment,
+		Name:        "spaceAfterComment",
+		Description: "separate the slashes of line comments from their text with a space, //x becomes // x",
+		comments:    true,
+		edits:       spaceAfterCommentEdits,
+	},
+	{
+		Rule:        StyleTightBraces,
+		Name:        "tightBraces",
+		Description: "remove the spaces inside the braces of bodies on a single line, { x } becomes {x}",
+		edits:       tightBracesEdits,
+	},
+}
+
+// ParseStyleRule parses the name of a style rule
+func ParseStyleRule(name string) (StyleRule, error) {
+	for _, info := range StyleRules {
+		if info.Name == name {
+			return info.Rule, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown style rule %q, see cadencefmt rules list", name)
+}
+
+// applyStyleRules applies the enabled style rules which change comments, or the ones which do not.
+// The code is lexed once for all rules, and comments which are kept as written are not changed
+func applyStyleRules(code string, rules StyleRule, comments bool) string {
+	var enabled []StyleRuleInfo
+	for _, info := range StyleRules {
+		if rules&info.Rule != 0 && info.comments == comments {
+			enabled = append(enabled, info)
+		}
+	}
+	if len(enabled) == 0 {
+		return code
+	}
+
+	stream := lexer.Lex([]byte(code), nil)
+	defer stream.Reclaim()
+	var tokens []lexer.Token
+	for {
+		token := stream.Next()
+		if token.Is(lexer.TokenEOF) {
+			break
+		}
+		tokens = append(tokens, token)
+	}
+
+	var edits []commentEdit
+	for _, info := range enabled {
+		edits = append(edits, info.edits(code, tokens)...)
+	}
+	if len(edits) == 0 {
+		return code
+	}
+	return applyEdits(code, outsideRegions(edits, keptComments(code)))
+}
+
+// spaceAfterCommentEdits separates the slashes of line comments from their text
+func spaceAfterCommentEdits(code string, tokens []lexer.Token) []commentEdit {
+	var edits []commentEdit
+	for _, token := range tokens {
+		if token.Type != lexer.TokenLineComment {
+			continue
+		}
+		text := extractTokenText(code, token)
+		body := strings.TrimLeft(text, "/")
+		if body == "" || body[0] == ' ' || body[0] == '\t' {
+			continue
+		}
+		s := commentSpan{token.StartPos.Offset, token.EndPos.Offset + 1}
+		edits = append(edits, commentEdit{s, text[:len(text)-len(body)] + " " + body})
+	}
+	return edits
+}
+
+// tightBracesEdits removes the spaces after the opening brace and before the closing brace
+// of braces on the same line with code between them. Empty bodies keep their style
+func tightBracesEdits(code string, tokens []lexer.Token) []commentEdit {
+	var edits []commentEdit
+	var open []int
+	for i, token := range tokens {
+		switch token.Type {
+		case lexer.TokenBraceOpen:
+			open = append(open, i)
+
+		case lexer.TokenBraceClose:
+			if len(open) == 0 {
+				continue
+			}
+			start := open[len(open)-1]
+			open = open[:len(open)-1]
+			if tokens[start].StartPos.Line != token.StartPos.Line || start+1 == i {
+				continue
+			}
+			first, last := tokens[start+1], tokens[i-1]
+			if start+2 == i && first.Type == lexer.TokenSpace {
+				continue
+			}
+			if first.Type == lexer.TokenSpace {
+				edits = append(edits, commentEdit{commentSpan{first.StartPos.Offset, first.EndPos.Offset + 1}, ""})
+			}
+			if last.Type == lexer.TokenSpace {
+				edits = append(edits, commentEdit{commentSpan{last.StartPos.Offset, last.EndPos.Offset + 1}, ""})
+			}
+		}
+	}
+	return edits
+}
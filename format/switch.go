@@ -0,0 +1,172 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/turbolent/prettier"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/parser"
+)
+
+// switchStatement prints a switch statement with its expression on the line of the keyword,
+// each case on its own line, and the broken expressions of cases indented,
+// so they stand out from the statements of the case.
+// With OneLineCases, cases with a single simple statement stay on one line if they fit
+type switchStatement struct {
+	*ast.SwitchStatement
+	printer printer
+}
+
+func (s *switchStatement) Doc() prettier.Doc {
+	body := make(prettier.Concat, 0, 2*len(s.Cases))
+	for _, switchCase := range s.Cases {
+		body = append(body, prettier.HardLine{}, s.caseDoc(switchCase))
+	}
+	return prettier.Concat{
+		prettier.Text("switch "),
+		s.Expression.Doc(),
+		prettier.Text(" {"),
+		prettier.Indent{Doc: body},
+		prettier.HardLine{},
+		prettier.Text("}"),
+	}
+}
+
+func (s *switchStatement) caseDoc(switchCase *ast.SwitchCase) prettier.Doc {
+	var label prettier.Doc = prettier.Text("default:")
+	if switchCase.Expression != nil {
+		label = prettier.Concat{
+			prettier.Text("case "),
+			prettier.Group{Doc: prettier.Indent{Doc: switchCase.Expression.Doc()}},
+			prettier.Text(":"),
+		}
+	}
+
+	if statement := s.printer.oneLineCase(switchCase); statement != nil {
+		return prettier.Group{
+			Doc: prettier.Concat{
+				label,
+				prettier.Indent{
+					Doc: prettier.Concat{
+						prettier.Line{},
+						statement,
+					},
+				},
+			},
+		}
+	}
+	return prettier.Concat{
+		label,
+		prettier.Indent{Doc: ast.StatementsDoc(switchCase.Statements)},
+	}
+}
+
+// oneLineCase returns the single simple statement of a case without comments,
+// if the options keep such cases on one line, or nil
+func (p printer) oneLineCase(switchCase *ast.SwitchCase) prettier.Doc {
+	if !p.options.OneLineCases || len(switchCase.Statements) != 1 ||
+		p.trivia.contains(switchCase.StartPos.Offset, switchCase.EndPos.Offset) {
+
+		return nil
+	}
+	statement := switchCase.Statements[0].Doc()
+	//statements with blocks, e.g. if statements, always have line breaks
+	if _, ok := flatWidth(statement); !ok {
+		return nil
+	}
+	return statement
+}
+
+// alignCases aligns the statements of consecutive cases on one line, after the longest label.
+// Runs of cases which would be longer than the width are kept
+func alignCases(code string, width int) (string, error) {
+	program, err := parser.ParseProgram(nil, []byte(code), parser.Config{})
+	if err != nil {
+		return "", err
+	}
+
+	var edits []commentEdit
+	seen := map[ast.Element]bool{}
+	var visit func(element ast.Element)
+	visit = func(element ast.Element) {
+		if element == nil || reflect.ValueOf(element).IsNil() || seen[element] {
+			return
+		}
+		seen[element] = true
+
+		if statement, ok := element.(*ast.SwitchStatement); ok {
+			edits = append(edits, caseRuns(code, statement, width)...)
+		}
+		element.Walk(visit)
+	}
+	visit(program)
+
+	sort.Slice(edits, func(i, j int) bool {
+		return edits[i].start < edits[j].start
+	})
+	//edit from the end, so the offsets of earlier cases stay valid
+	for i := len(edits) - 1; i >= 0; i-- {
+		edit := edits[i]
+		code = code[:edit.start] + edit.text + code[edit.end:]
+	}
+	return code, nil
+}
+
+// caseRuns returns the edits aligning the runs of consecutive cases of the switch statement
+// which have their single statement on the line of the label
+func caseRuns(code string, statement *ast.SwitchStatement, width int) []commentEdit {
+	var edits []commentEdit
+	var run []alignedLine
+	flush := func() {
+		if len(run) > 1 {
+			edits = append(edits, alignRun(code, run, width)...)
+		}
+		run = run[:0]
+	}
+
+	previous := -1
+	for _, switchCase := range statement.Cases {
+		//the label ends after the expression, or after the default keyword
+		labelEnd := switchCase.StartPos.Offset + len("default")
+		if switchCase.Expression != nil {
+			labelEnd = switchCase.Expression.EndPosition(nil).Offset + 1
+		}
+		line := switchCase.StartPos.Line
+		if len(switchCase.Statements) != 1 ||
+			switchCase.EndPos.Line != line ||
+			strings.TrimSpace(code[labelEnd:switchCase.Statements[0].StartPosition().Offset]) != ":" {
+
+			flush()
+			previous = -1
+			continue
+		}
+		if previous < 0 || line != previous+1 {
+			flush()
+		}
+		run = append(run, alignedLine{labelEnd, switchCase.Statements[0].StartPosition().Offset, ":"})
+		previous = line
+	}
+	flush()
+	return edits
+}
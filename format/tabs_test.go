@@ -0,0 +1,141 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"testing"
+)
+
+func TestUseTabs(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		expected string
+	}{
+		{
+			name:     "indentation",
+			code:     "a\n    b\n        c\n",
+			expected: "a\n\tb\n\t\tc\n",
+		},
+		{
+			name:     "padding after the indentation",
+			code:     "      a\n",
+			expected: "\t  a\n",
+		},
+		{
+			name:     "spaces after the indentation",
+			code:     "    let x =    1\n",
+			expected: "\tlet x =    1\n",
+		},
+		{
+			name:     "string",
+			code:     "    let s = \"a    b\"\n",
+			expected: "\tlet s = \"a    b\"\n",
+		},
+		{
+			name:     "escaped quote in a string",
+			code:     "    let s = \"\\\"    /*\"\n    b\n",
+			expected: "\tlet s = \"\\\"    /*\"\n\tb\n",
+		},
+		{
+			name:     "line comment",
+			code:     "    // a    b /*\n    c\n",
+			expected: "\t// a    b /*\n\tc\n",
+		},
+		{
+			name:     "doc comment",
+			code:     "    /// a    b\n",
+			expected: "\t/// a    b\n",
+		},
+		{
+			name:     "block comment",
+			code:     "    /* a\n        b    c\n    */\n    d\n",
+			expected: "\t/* a\n        b    c\n    */\n\td\n",
+		},
+		{
+			name:     "nested block comments",
+			code:     "/* /* a */\n    b */\n    c\n",
+			expected: "/* /* a */\n    b */\n\tc\n",
+		},
+		{
+			name:     "block comment in a string",
+			code:     "    let s = \"/*\"\n    b\n",
+			expected: "\tlet s = \"/*\"\n\tb\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			//useTabs ends every line with a line break
+			actual := useTabs(test.code)
+			if actual != test.expected+"\n" {
+				t.Errorf("expected %q, got %q", test.expected+"\n", actual)
+			}
+		})
+	}
+}
+
+func TestSourceTabs(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		expected string
+	}{
+		{
+			name:     "string with spaces",
+			code:     "pub fun f() {\n    let s = \"a    b\"\n}\n",
+			expected: "pub fun f() {\n\tlet s = \"a    b\"\n}\n",
+		},
+		{
+			name:     "aligned doc comment",
+			code:     "/// a:    the first\n/// bb:   the second\npub fun f(a: Int, bb: Int) {}\n",
+			expected: "/// a:    the first\n/// bb:   the second\npub fun f(a: Int, bb: Int) {}\n",
+		},
+		{
+			name:     "block comment",
+			code:     "pub fun f() {\n    /* a\n         b    c */\n    return\n}\n",
+			expected: "pub fun f() {\n\t/* a\n         b    c */\n\treturn\n}\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			//aligning comments formats the whole code at once, instead of streaming it
+			for _, alignComments := range []bool{false, true} {
+				options := DefaultOptions
+				options.Tabs = true
+				options.AlignComments = alignComments
+
+				formatted, err := Source(test.code, options)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if formatted != test.expected {
+					t.Errorf("expected %q, got %q", test.expected, formatted)
+				}
+				if err := Verify(test.code, formatted, options); err != nil {
+					t.Error(err)
+				}
+				if err := VerifyAST(test.code, formatted, options); err != nil {
+					t.Error(err)
+				}
+			}
+		})
+	}
+}
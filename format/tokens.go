@@ -0,0 +1,73 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/parser"
+	"github.com/onflow/cadence/runtime/parser/lexer"
+)
+
+// Token is a token of the Cadence lexer
+type Token struct {
+	Type  string       `json:"type"`
+	Text  string       `json:"text"`
+	Start ast.Position `json:"start"`
+	End   ast.Position `json:"end"`
+}
+
+// TokenStreams returns the tokens of the code and of its layout,
+// the two streams Source aligns to put the comments back into the layout.
+// The code is the one after converting the comments to the comment style, and sorting the imports
+func TokenStreams(code string, options Options) (source []Token, layout []Token, err error) {
+	options, err = FileOptions(code, options)
+	if err != nil {
+		return nil, nil, err
+	}
+	code, err = rewrite(code, options)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	program, err := parser.ParseProgram(nil, []byte(code), parser.Config{})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return tokens(code), tokens(render(program, []byte(code), options, newTrivia([]byte(code)))), nil
+}
+
+func tokens(code string) []Token {
+	stream := lexer.Lex([]byte(code), nil)
+	defer stream.Reclaim()
+
+	var result []Token
+	for {
+		token := stream.Next()
+		if token.Is(lexer.TokenEOF) {
+			return result
+		}
+		result = append(result, Token{
+			Type:  token.Type.String(),
+			Text:  extractTokenText(code, token),
+			Start: token.StartPos,
+			End:   token.EndPos,
+		})
+	}
+}
@@ -0,0 +1,208 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+
+	"github.com/onflow/cadence/runtime/parser/lexer"
+)
+
+// comment is a comment of the code
+type comment struct {
+	commentSpan
+	text string
+	// ownLine reports if no code precedes the comment on its line
+	ownLine bool
+	// line is the line the comment starts on
+	line int
+}
+
+// trivia are the comments of the code, collected while lexing it.
+// The printer attaches the comments before declarations and closing braces
+// to the declarations, and the token pass keeps the comments which are not attached
+type trivia struct {
+	code []byte
+	// lineStarts are the offsets of the lines
+	lineStarts []int
+	// comments are sorted by their offsets
+	comments []comment
+	attached []bool
+}
+
+// newTrivia lexes the comments of the code.
+// Nested block comments are a single comment
+func newTrivia(code []byte) *trivia {
+	tokens := lexer.Lex(code, nil)
+	defer tokens.Reclaim()
+	return triviaOf(tokens)
+}
+
+// triviaOf collects the comments of the tokens, and reverts the tokens to where they were,
+// so they can be read again
+func triviaOf(tokens lexer.TokenStream) *trivia {
+	code := tokens.Input()
+	t := &trivia{code: code, lineStarts: []int{0}}
+	for offset, b := range code {
+		if b == '\n' {
+			t.lineStarts = append(t.lineStarts, offset+1)
+		}
+	}
+
+	cursor := tokens.Cursor()
+	defer tokens.Revert(cursor)
+
+	depth := 0
+	var start lexer.Token
+	for {
+		token := tokens.Next()
+		switch token.Type {
+		case lexer.TokenEOF:
+			t.attached = make([]bool, len(t.comments))
+			return t
+
+		case lexer.TokenLineComment:
+			text := strings.TrimRight(string(code[token.StartPos.Offset:token.EndPos.Offset+1]), " \t")
+			t.add(token, token.StartPos.Offset+len(text))
+
+		case lexer.TokenBlockCommentStart:
+			if depth == 0 {
+				start = token
+			}
+			depth++
+
+		case lexer.TokenBlockCommentEnd:
+			depth--
+			if depth == 0 {
+				t.add(start, token.EndPos.Offset+1)
+			}
+		}
+	}
+}
+
+func (t *trivia) add(start lexer.Token, end int) {
+	offset := start.StartPos.Offset
+	lineStart := bytes.LastIndexByte(t.code[:offset], '\n') + 1
+	t.comments = append(t.comments, comment{
+		commentSpan: commentSpan{start: offset, end: end},
+		text:        string(t.code[offset:end]),
+		ownLine:     len(bytes.TrimSpace(t.code[lineStart:offset])) == 0,
+		line:        start.StartPos.Line,
+	})
+}
+
+// take attaches the comments between the offsets which lead the code at the end offset:
+// the comments on their own lines, and the ones on the line of that code.
+// Comments after other code on its line stay with that code
+func (t *trivia) take(from, to int) []comment {
+	if t == nil || from < 0 {
+		return nil
+	}
+
+	toLine := t.line(to)
+	//no code follows at the end, comments after code on the last line stay with it
+	if to >= len(t.code) {
+		toLine = 0
+	}
+
+	var result []comment
+	for i := t.after(from); i < len(t.comments) && t.comments[i].end <= to; i++ {
+		c := t.comments[i]
+		if t.attached[i] || (!c.ownLine && c.line != toLine) {
+			continue
+		}
+		t.attached[i] = true
+		result = append(result, c)
+	}
+	return result
+}
+
+// after returns the index of the first comment starting at or after the offset
+func (t *trivia) after(offset int) int {
+	return sort.Search(len(t.comments), func(i int) bool {
+		return t.comments[i].start >= offset
+	})
+}
+
+// contains reports if a comment starts between the offsets
+func (t *trivia) contains(from, to int) bool {
+	if t == nil {
+		return false
+	}
+	i := t.after(from)
+	return i < len(t.comments) && t.comments[i].start < to
+}
+
+// at returns the index of the comment containing the offset, or -1
+func (t *trivia) at(offset int) int {
+	i := sort.Search(len(t.comments), func(i int) bool {
+		return t.comments[i].end > offset
+	})
+	if i < len(t.comments) && t.comments[i].start <= offset {
+		return i
+	}
+	return -1
+}
+
+// commentStartingAt returns the comment starting at the offset
+func (t *trivia) commentStartingAt(offset int) (comment, bool) {
+	if i := t.at(offset); i >= 0 && t.comments[i].start == offset {
+		return t.comments[i], true
+	}
+	return comment{}, false
+}
+
+// line returns the line of the offset
+func (t *trivia) line(offset int) int {
+	return sort.SearchInts(t.lineStarts, offset+1)
+}
+
+// lineStart returns the offset of the start of the line of the offset
+func (t *trivia) lineStart(offset int) int {
+	return t.lineStarts[t.line(offset)-1]
+}
+
+// attachedAt reports if the offset is inside an attached comment
+func (t *trivia) attachedAt(offset int) bool {
+	if t == nil {
+		return false
+	}
+	i := t.at(offset)
+	return i >= 0 && t.attached[i]
+}
+
+// openBrace returns the offset of the first opening brace after the offset,
+// which is not part of a comment
+func (t *trivia) openBrace(from int) int {
+	if t == nil {
+		return -1
+	}
+	for offset := from; offset < len(t.code); offset++ {
+		if i := t.at(offset); i >= 0 {
+			offset = t.comments[i].end - 1
+			continue
+		}
+		if t.code[offset] == '{' {
+			return offset
+		}
+	}
+	return -1
+}
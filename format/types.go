@@ -0,0 +1,66 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/turbolent/prettier"
+)
+
+// restrictedType is a restricted type of a type, e.g. R{A, B} or R{},
+// which keeps the first restriction after the opening brace when it is broken,
+// as the parser does not allow a line break there.
+// The restrictions continue on their own lines, and the closing brace follows the last one
+type restrictedType struct {
+	*ast.RestrictedType
+}
+
+func (t *restrictedType) Doc() prettier.Doc {
+	if len(t.Restrictions) == 0 {
+		return prettier.Concat{
+			t.Type.Doc(),
+			prettier.Text("{}"),
+		}
+	}
+	restrictions := prettier.Concat{
+		t.Restrictions[0].Doc(),
+	}
+	for _, restriction := range t.Restrictions[1:] {
+		restrictions = append(
+			restrictions,
+			prettier.Text(","),
+			prettier.Indent{
+				Doc: prettier.Concat{
+					prettier.Line{},
+					restriction.Doc(),
+				},
+			},
+		)
+	}
+	return prettier.Concat{
+		t.Type.Doc(),
+		prettier.Group{
+			Doc: prettier.Concat{
+				prettier.Text("{"),
+				restrictions,
+				prettier.Text("}"),
+			},
+		},
+	}
+}
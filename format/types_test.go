@@ -0,0 +1,138 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"testing"
+)
+
+func TestSourceRestrictedTypes(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+	}{
+		{
+			name: "fields",
+			code: `pub struct Test {
+    pub let testRestrictedWithoutType: {Bar, Baz}
+    pub let testRestrictedWithType: Foo{Bar, Baz}
+    pub let testRestrictedWithoutRestrictions: Foo{}
+}
+`,
+		},
+		{
+			name: "type argument of a chain",
+			code: `pub fun main(addresses: [Address]): {Address: UFix64} {
+    let balances: {Address: UFix64} = {}
+    for address in addresses {
+        let vault = getAccount(address)
+            .getCapability(/public/exampleTokenBalance)
+            .borrow<&ExampleToken.Vault{ExampleToken.Balance}>()
+        if let vault = vault {
+            balances[address] = vault.balance
+        }
+    }
+    return balances
+}
+`,
+		},
+		{
+			name: "type arguments with many restrictions",
+			code: `pub fun main() {
+    let vault = getAccount(0x1)
+        .getCapability(/public/vault)
+        .borrow<&FungibleToken.Vault{FungibleToken.Receiver, FungibleToken.Balance}>()!
+        .balance
+}
+`,
+		},
+		{
+			name: "empty restrictions",
+			code: `pub fun main() {
+    let w: &AVeryLongTypeNameHere{} = x
+    let v = x.borrow<&AVeryLongTypeNameHere{}>()
+    let u = x.y().borrow<&AVeryLongTypeNameHere{}>()!.z()
+}
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, columns := range []int{40, 20} {
+				options := DefaultOptions
+				options.MaxLineLength = columns
+
+				formatted, err := Source(test.code, options)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := Verify(test.code, formatted, options); err != nil {
+					t.Errorf("%d columns: %s", columns, err)
+				}
+				if err := VerifyAST(test.code, formatted, options); err != nil {
+					t.Errorf("%d columns: %s", columns, err)
+				}
+			}
+		})
+	}
+}
+
+func TestRestrictedTypeLayout(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		columns  int
+		expected string
+	}{
+		{
+			name:     "fits",
+			code:     "let x: &Vault{Receiver, Balance} = y\n",
+			columns:  80,
+			expected: "let x: &Vault{Receiver, Balance} = y",
+		},
+		{
+			name:     "broken after the first restriction",
+			code:     "let x: &Vault{Receiver, Balance} = y\n",
+			columns:  20,
+			expected: "let x: &Vault{Receiver,\n    Balance} = y",
+		},
+		{
+			name:     "empty",
+			code:     "let x: &AVeryLongTypeName{} = y\n",
+			columns:  10,
+			expected: "let x: &AVeryLongTypeName{} =\n    y",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			options := DefaultOptions
+			options.MaxLineLength = test.columns
+
+			formatted, err := Source(test.code, options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if formatted != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, formatted)
+			}
+		})
+	}
+}
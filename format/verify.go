@@ -0,0 +1,325 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/onflow/cadence/runtime/parser"
+	"github.com/onflow/cadence/runtime/parser/lexer"
+)
+
+// SafetyError reports formatted code which failed a safety check,
+// which is a bug of the formatter
+type SafetyError struct {
+	// Check is the failed check: "panic", "parse", "comments", "idempotency", "tokens" or "ast"
+	Check   string
+	Message string
+}
+
+func (e *SafetyError) Error() string {
+	return fmt.Sprintf("internal error, %s check failed: %s", e.Check, e.Message)
+}
+
+// Verify checks that the formatted code parses, has all comments of the code,
+// and does not change when it is formatted again
+func Verify(code string, formatted string, options Options) error {
+	options, err := FileOptions(code, options)
+	if err != nil {
+		return err
+	}
+
+	//formatting again starts from the result as it is
+	result := formatted
+	code, formatted = withoutPreamble(code), withoutPreamble(formatted)
+
+	if _, err := parser.ParseProgram(nil, []byte(formatted), parser.Config{}); err != nil {
+		return &SafetyError{Check: "parse", Message: err.Error()}
+	}
+
+	//comments are expected in the configured style, and with their imports
+	converted, err := rewrite(code, options)
+	if err != nil {
+		return &SafetyError{Check: "comments", Message: err.Error()}
+	}
+	expected := comments(converted)
+	actual := comments(formatted)
+	for i := 0; i < len(expected) || i < len(actual); i++ {
+		switch {
+		case i >= len(actual):
+			return &SafetyError{Check: "comments", Message: fmt.Sprintf("comment %q is missing", expected[i])}
+		case i >= len(expected):
+			return &SafetyError{Check: "comments", Message: fmt.Sprintf("comment %q was added", actual[i])}
+		case expected[i] != actual[i]:
+			return &SafetyError{Check: "comments", Message: fmt.Sprintf("comment %q was changed to %q", expected[i], actual[i])}
+		}
+	}
+
+	reformatted, err := Source(result, options)
+	if err != nil {
+		return &SafetyError{Check: "idempotency", Message: err.Error()}
+	}
+	if strings.TrimRight(reformatted, "\n") != strings.TrimRight(result, "\n") {
+		return &SafetyError{Check: "idempotency", Message: "formatting the result again changes it"}
+	}
+
+	return nil
+}
+
+// VerifyAST checks that the formatted code has the same program as the code,
+// besides the positions and the doc strings, which formatting may change.
+// Imports are expected in the order of the options
+func VerifyAST(code string, formatted string, options Options) error {
+	options, err := FileOptions(code, options)
+	if err != nil {
+		return err
+	}
+	code, formatted = withoutPreamble(code), withoutPreamble(formatted)
+	code, err = rewrite(code, options)
+	if err != nil {
+		return &SafetyError{Check: "ast", Message: err.Error()}
+	}
+
+	expected, err := programTree(code)
+	if err != nil {
+		return &SafetyError{Check: "ast", Message: err.Error()}
+	}
+	actual, err := programTree(formatted)
+	if err != nil {
+		return &SafetyError{Check: "ast", Message: err.Error()}
+	}
+
+	if path, ok := firstTreeDifference("program", expected, actual); !ok {
+		return &SafetyError{Check: "ast", Message: fmt.Sprintf("%s differs", path)}
+	}
+	return nil
+}
+
+// programTree is the generic tree of the program of the code, without positions and doc strings
+func programTree(code string) (any, error) {
+	program, err := parser.ParseProgram(nil, []byte(code), parser.Config{})
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := json.Marshal(program)
+	if err != nil {
+		return nil, err
+	}
+	var tree any
+	if err := json.Unmarshal(encoded, &tree); err != nil {
+		return nil, err
+	}
+	return withoutPositions(tree), nil
+}
+
+// withoutPositions removes the positions and doc strings from the tree
+func withoutPositions(tree any) any {
+	switch tree := tree.(type) {
+	case map[string]any:
+		for key, value := range tree {
+			if strings.HasSuffix(key, "Pos") || key == "DocString" {
+				delete(tree, key)
+				continue
+			}
+			tree[key] = withoutPositions(value)
+		}
+	case []any:
+		for i, value := range tree {
+			tree[i] = withoutPositions(value)
+		}
+	}
+	return tree
+}
+
+// firstTreeDifference returns the path of the first difference of the trees
+func firstTreeDifference(path string, expected, actual any) (string, bool) {
+	switch expected := expected.(type) {
+	case map[string]any:
+		actual, ok := actual.(map[string]any)
+		if !ok || len(actual) != len(expected) {
+			return path, false
+		}
+		keys := make([]string, 0, len(expected))
+		for key := range expected {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if path, ok := firstTreeDifference(path+"."+key, expected[key], actual[key]); !ok {
+				return path, false
+			}
+		}
+		return "", true
+
+	case []any:
+		actual, ok := actual.([]any)
+		if !ok || len(actual) != len(expected) {
+			return path, false
+		}
+		for i := range expected {
+			if path, ok := firstTreeDifference(fmt.Sprintf("%s[%d]", path, i), expected[i], actual[i]); !ok {
+				return path, false
+			}
+		}
+		return "", true
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		return path, false
+	}
+	return "", true
+}
+
+// VerifyTokens checks that the formatted code has the tokens of the code, in order,
+// besides the whitespace, comments and separators, which formatting may change.
+// The layout may also add parentheses for grouping, writes addresses without leading zeros,
+// and access(all) and access(self) with their keywords pub and priv
+func VerifyTokens(code string, formatted string, options Options) error {
+	options, err := FileOptions(code, options)
+	if err != nil {
+		return err
+	}
+	code, formatted = withoutPreamble(code), withoutPreamble(formatted)
+	converted, err := rewrite(code, options)
+	if err != nil {
+		return &SafetyError{Check: "tokens", Message: err.Error()}
+	}
+
+	expected := codeTokens(converted)
+	actual := codeTokens(formatted)
+	for i := 0; i < len(expected) || i < len(actual); i++ {
+		switch {
+		case i >= len(actual):
+			return &SafetyError{Check: "tokens", Message: fmt.Sprintf("%s is missing at the end", expected[i])}
+		case i >= len(expected):
+			return &SafetyError{Check: "tokens", Message: fmt.Sprintf("%s was added at the end", actual[i])}
+		case expected[i].text != actual[i].text:
+			return &SafetyError{
+				Check:   "tokens",
+				Message: fmt.Sprintf("%s was changed to %s", expected[i], actual[i]),
+			}
+		}
+	}
+	return nil
+}
+
+// codeToken is a token which formatting must keep
+type codeToken struct {
+	text string
+	line int
+}
+
+func (t codeToken) String() string {
+	return fmt.Sprintf("%q on line %d", t.text, t.line)
+}
+
+// codeTokens returns the tokens of the code, without whitespace, comments, separators and parentheses
+func codeTokens(code string) []codeToken {
+	tokens := lexer.Lex([]byte(code), nil)
+	defer tokens.Reclaim()
+
+	var result []codeToken
+	for {
+		token := tokens.Next()
+		switch token.Type {
+		case lexer.TokenEOF:
+			return result
+
+		case lexer.TokenSpace,
+			lexer.TokenSemicolon,
+			lexer.TokenComma,
+			lexer.TokenParenOpen,
+			lexer.TokenParenClose,
+			lexer.TokenLineComment,
+			lexer.TokenBlockCommentStart,
+			lexer.TokenBlockCommentContent,
+			lexer.TokenBlockCommentEnd:
+
+			continue
+		}
+		text := extractTokenText(code, token)
+		if token.Is(lexer.TokenHexadecimalIntegerLiteral) {
+			text = "0x" + strings.TrimLeft(strings.ToLower(text[2:]), "0_")
+		}
+		if token.Is(lexer.TokenIdentifier) && text == "access" {
+			if keyword, ok := accessKeyword(code, tokens); ok {
+				text = keyword
+			}
+		}
+		result = append(result, codeToken{text, token.StartPos.Line})
+	}
+}
+
+// accessKeywords are the keywords the printer writes for access modifiers
+var accessKeywords = map[string]string{
+	"all":  "pub",
+	"self": "priv",
+}
+
+// accessKeyword consumes the rest of an access(all) or access(self) modifier, after access,
+// and returns its keyword. Other tokens are not consumed
+func accessKeyword(code string, tokens lexer.TokenStream) (string, bool) {
+	cursor := tokens.Cursor()
+	next := func() lexer.Token {
+		token := tokens.Next()
+		for token.Is(lexer.TokenSpace) {
+			token = tokens.Next()
+		}
+		return token
+	}
+
+	if next().Is(lexer.TokenParenOpen) {
+		if name := next(); name.Is(lexer.TokenIdentifier) {
+			keyword, ok := accessKeywords[extractTokenText(code, name)]
+			if ok && next().Is(lexer.TokenParenClose) {
+				return keyword, true
+			}
+		}
+	}
+	tokens.Revert(cursor)
+	return "", false
+}
+
+// comments returns the texts of the comments, sorted,
+// as comments may move relative to each other
+func comments(code string) []string {
+	tokens := lexer.Lex([]byte(code), nil)
+	defer tokens.Reclaim()
+
+	var result []string
+	for {
+		token := tokens.Next()
+		switch token.Type {
+		case lexer.TokenEOF:
+			sort.Strings(result)
+			return result
+
+		case lexer.TokenLineComment:
+			result = append(result, strings.TrimRight(extractTokenText(code, token), " \t\r"))
+
+		case lexer.TokenBlockCommentContent:
+			//formatting may change the line endings
+			result = append(result, strings.ReplaceAll(extractTokenText(code, token), "\r\n", "\n"))
+		}
+	}
+}
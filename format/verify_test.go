@@ -0,0 +1,93 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package format
+
+import (
+	"testing"
+)
+
+func TestVerifyTokensAccess(t *testing.T) {
+	tests := []struct {
+		name      string
+		code      string
+		formatted string
+		valid     bool
+	}{
+		{
+			name:      "access(all)",
+			code:      "access(all) fun f() {}\n",
+			formatted: "pub fun f() {}\n",
+			valid:     true,
+		},
+		{
+			name:      "access(self)",
+			code:      "pub contract C {\n    access( self ) let x: Int\n}\n",
+			formatted: "pub contract C {\n    priv let x: Int\n}\n",
+			valid:     true,
+		},
+		{
+			name:      "access(contract)",
+			code:      "access(contract) fun f() {}\n",
+			formatted: "access(contract) fun f() {}\n",
+			valid:     true,
+		},
+		{
+			name:      "call of a function named access",
+			code:      "let x = access(all)\n",
+			formatted: "let x = access(all)\n",
+			valid:     true,
+		},
+		{
+			name:      "changed access",
+			code:      "access(all) fun f() {}\n",
+			formatted: "priv fun f() {}\n",
+			valid:     false,
+		},
+		{
+			name:      "removed access",
+			code:      "access(all) fun f() {}\n",
+			formatted: "fun f() {}\n",
+			valid:     false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := VerifyTokens(test.code, test.formatted, DefaultOptions)
+			if test.valid && err != nil {
+				t.Error(err)
+			}
+			if !test.valid && err == nil {
+				t.Error("expected a tokens error")
+			}
+		})
+	}
+}
+
+func TestSourceAccess(t *testing.T) {
+	code := "access(all) contract C {\n    access(self) let x: Int\n    init() { self.x = 1 }\n}\n"
+
+	formatted, err := Source(code, DefaultOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyTokens(code, formatted, DefaultOptions); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,249 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/onflow/cadence/runtime/parser/lexer"
+
+	"cadencefmt/format"
+)
+
+// runFuzz mutates the files of the corpus, formats the mutated code,
+// and reports the inputs on which the formatter panics, or fails a safety check,
+// e.g. the result changes when it is formatted again, or has another syntax tree.
+// Failing inputs are minimized and written to a directory
+func runFuzz(args []string) {
+	flags := flag.NewFlagSet("fuzz", flag.ExitOnError)
+	optionFlags := addOptionFlags(flags)
+	roundsFlag := flags.Int("n", 1000, "number of mutated inputs")
+	seedFlag := flags.Int64("seed", 1, "random seed")
+	outFlag := flags.String("out", "", "directory for the minimized failing inputs (default: a new temporary directory)")
+	_ = flags.Parse(args)
+
+	cliOptions, err := optionFlags.cliOptions()
+	if err != nil {
+		fatal(err)
+	}
+	options := cliOptions.Options
+
+	if flags.NArg() == 0 {
+		fatal(errors.New("usage: cadencefmt fuzz <corpus>..."))
+	}
+
+	filenames, err := cadenceFiles(flags.Args())
+	if err != nil {
+		fatal(err)
+	}
+	var corpus []string
+	for _, filename := range filenames {
+		code, err := os.ReadFile(filename)
+		if err != nil {
+			fatal(err)
+		}
+		corpus = append(corpus, string(code))
+	}
+	if len(corpus) == 0 {
+		fatal(errors.New("the corpus has no Cadence files"))
+	}
+
+	random := rand.New(rand.NewSource(*seedFlag))
+	out := *outFlag
+
+	failures := 0
+	parsed := 0
+	//each kind of failure is only reported once
+	reported := map[string]bool{}
+	for round := 0; round < *roundsFlag; round++ {
+		seed := random.Intn(len(corpus))
+		code := mutate(corpus[seed], corpus, random)
+
+		failure, ok := fuzzCheck(code, options)
+		if !ok {
+			continue
+		}
+		parsed++
+		if failure == nil {
+			continue
+		}
+		failures++
+
+		key := failure.Check + ": " + failure.Message
+		if reported[key] {
+			continue
+		}
+		reported[key] = true
+
+		minimized := minimize(code, func(candidate string) bool {
+			f, ok := fuzzCheck(candidate, options)
+			return ok && f != nil && f.Check == failure.Check
+		})
+
+		if out == "" {
+			out, err = os.MkdirTemp("", "cadencefmt-fuzz-")
+			if err != nil {
+				fatal(err)
+			}
+		} else if err := os.MkdirAll(out, 0755); err != nil {
+			fatal(err)
+		}
+		path := filepath.Join(out, fmt.Sprintf("%s-%d.cdc", failure.Check, len(reported)))
+		if err := os.WriteFile(path, []byte(minimized), 0644); err != nil {
+			fatal(err)
+		}
+		fmt.Printf("FAIL %s, mutated from %s: %s\n", path, filenames[seed], firstLine(failure.Message))
+	}
+
+	fmt.Printf("%d inputs, %d parsed, %d failed, %d distinct failures\n", *roundsFlag, parsed, failures, len(reported))
+	if failures > 0 {
+		os.Exit(exitInternal)
+	}
+}
+
+// fuzzCheck formats the code and runs the safety checks on the result.
+// It reports false if the code is not valid, e.g. it does not parse
+func fuzzCheck(code string, options format.Options) (failure *format.SafetyError, ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			failure = &format.SafetyError{Check: "panic", Message: fmt.Sprint(r)}
+			ok = true
+		}
+	}()
+
+	result, err := format.Source(code, options)
+	if err != nil {
+		return nil, false
+	}
+
+	err = format.Verify(code, result, options)
+	if err == nil {
+		err = format.VerifyAST(code, result, options)
+	}
+	if errors.As(err, &failure) {
+		return failure, true
+	}
+	return nil, err == nil
+}
+
+// mutate returns the code with a random mutation
+func mutate(code string, corpus []string, random *rand.Rand) string {
+	lines := strings.SplitAfter(code, "\n")
+
+	switch random.Intn(6) {
+	case 0:
+		return perturbWhitespace(code, random)
+
+	case 1:
+		//a comment between tokens
+		comments := []string{"// c\n", "/* c */", "/* c\n */", "/* /* nested */ */", "/// doc\n"}
+		return insertAtToken(code, comments[random.Intn(len(comments))], random)
+
+	case 2:
+		//a line break between tokens
+		return insertAtToken(code, "\n", random)
+
+	case 3:
+		//a duplicated line
+		i := random.Intn(len(lines))
+		return strings.Join(append(lines[:i+1:i+1], lines[i:]...), "")
+
+	case 4:
+		//a removed line
+		i := random.Intn(len(lines))
+		return strings.Join(append(lines[:i:i], lines[i+1:]...), "")
+
+	default:
+		//a line of another file
+		other := strings.SplitAfter(corpus[random.Intn(len(corpus))], "\n")
+		i := random.Intn(len(lines) + 1)
+		line := other[random.Intn(len(other))]
+		if !strings.HasSuffix(line, "\n") {
+			line += "\n"
+		}
+		return strings.Join(lines[:i], "") + line + strings.Join(lines[i:], "")
+	}
+}
+
+// insertAtToken inserts the text before a random token of the code
+func insertAtToken(code string, text string, random *rand.Rand) string {
+	tokens := lexer.Lex([]byte(code), nil)
+	defer tokens.Reclaim()
+
+	var offsets []int
+	for {
+		token := tokens.Next()
+		if token.Is(lexer.TokenEOF) {
+			break
+		}
+		if !token.Is(lexer.TokenSpace) {
+			offsets = append(offsets, token.StartPos.Offset)
+		}
+	}
+	if len(offsets) == 0 {
+		return code
+	}
+
+	offset := offsets[random.Intn(len(offsets))]
+	return code[:offset] + text + " " + code[offset:]
+}
+
+// minimize removes declarations and statements, then lines, and then tokens,
+// of the code as long as it keeps failing
+func minimize(code string, failing func(string) bool) string {
+	code = minimizeDeclarations(code, failing)
+	lines := strings.SplitAfter(code, "\n")
+	lines = minimizeParts(lines, failing)
+
+	code = strings.Join(lines, "")
+	tokens := lexer.Lex([]byte(code), nil)
+	defer tokens.Reclaim()
+
+	var parts []string
+	for {
+		token := tokens.Next()
+		if token.Is(lexer.TokenEOF) {
+			break
+		}
+		parts = append(parts, code[token.StartPos.Offset:token.EndPos.Offset+1])
+	}
+	return strings.Join(minimizeParts(parts, failing), "")
+}
+
+// minimizeParts removes chunks of parts, halving the size of the chunks,
+// as long as the joined parts keep failing
+func minimizeParts(parts []string, failing func(string) bool) []string {
+	for size := len(parts) / 2; size >= 1; size /= 2 {
+		for i := 0; i+size <= len(parts); {
+			candidate := append(parts[:i:i], parts[i+size:]...)
+			if failing(strings.Join(candidate, "")) {
+				parts = candidate
+				continue
+			}
+			i += size
+		}
+	}
+	return parts
+}
@@ -0,0 +1,112 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"cadencefmt/format"
+)
+
+// git runs a git command and returns its output
+func git(args ...string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return string(output), nil
+}
+
+// changedFiles returns the Cadence files in the current directory changed since the base ref,
+// relative to the current directory
+func changedFiles(base string) ([]string, error) {
+	output, err := git("diff", "--name-only", "-z", "--no-relative", "--diff-filter=ACMR", base, "--", "*.cdc")
+	if err != nil {
+		return nil, err
+	}
+
+	//the names are relative to the top of the repository, also with diff.relative set
+	toplevel, err := git("rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	toplevel = strings.TrimSuffix(toplevel, "\n")
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	//the top level has its symbolic links resolved
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+
+	var filenames []string
+	for _, name := range strings.Split(output, "\x00") {
+		if name == "" {
+			continue
+		}
+		filename, err := filepath.Rel(dir, filepath.Join(toplevel, filepath.FromSlash(name)))
+		if err != nil {
+			return nil, err
+		}
+		filenames = append(filenames, filename)
+	}
+	return filenames, nil
+}
+
+var hunkHeaderPattern = regexp.MustCompile(`(?m)^@@ -\S+ \+(\d+)(?:,(\d+))? @@`)
+
+// changedLines returns the lines of the file changed since the base ref
+func changedLines(base string, filename string) ([]format.LineRange, error) {
+	output, err := git("diff", "--unified=0", "--no-color", base, "--", filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var ranges []format.LineRange
+	for _, match := range hunkHeaderPattern.FindAllStringSubmatch(output, -1) {
+		start, _ := strconv.Atoi(match[1])
+		count := 1
+		if match[2] != "" {
+			count, _ = strconv.Atoi(match[2])
+		}
+
+		//pure deletions give the line before the removed ones, 0 at the top,
+		//and touch it and the line after
+		if count == 0 {
+			ranges = append(ranges, format.LineRange{From: max(start, 1), To: start + 1})
+			continue
+		}
+		ranges = append(ranges, format.LineRange{From: start, To: start + count - 1})
+	}
+
+	return ranges, nil
+}
@@ -4,7 +4,6 @@ go 1.21.0
 
 require (
 	github.com/onflow/cadence v0.40.0
-	github.com/openconfig/goyang v1.4.1
 	github.com/turbolent/prettier v0.0.0-20220320183459-661cc755135d
 	golang.org/x/exp v0.0.0-20230905200255-921286631fa9
 )
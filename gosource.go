@@ -0,0 +1,107 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"go/scanner"
+	"go/token"
+	"strings"
+)
+
+// goMarker is the comment which tags the raw string literal starting on its line or the next one
+const goMarker = "//cadence:fmt"
+
+// formatGoSource formats the cadence code of the raw string literals tagged with the marker comment,
+// and keeps the rest of the Go source as written
+func formatGoSource(text string, options cliOptions) (string, error) {
+	src := []byte(text)
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+
+	var s scanner.Scanner
+	s.Init(file, src, nil, scanner.ScanComments)
+
+	var result strings.Builder
+	written := 0
+	markerLine := 0
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		line := file.Line(pos)
+		switch {
+		case tok == token.COMMENT && lit == goMarker:
+			markerLine = line
+		case tok == token.STRING && strings.HasPrefix(lit, "`") && markerLine > 0 && line-markerLine <= 1:
+			markerLine = 0
+			formatted, err := formatLiteral(lit[1:len(lit)-1], options)
+			if err != nil {
+				return "", err
+			}
+			offset := file.Offset(pos)
+			result.WriteString(text[written : offset+1])
+			result.WriteString(formatted)
+			written = offset + len(lit) - 1
+		}
+	}
+	result.WriteString(text[written:])
+	return result.String(), nil
+}
+
+// formatLiteral formats the cadence code of a raw string literal,
+// keeping its first and last line and the indentation common to its lines
+func formatLiteral(content string, options cliOptions) (string, error) {
+	lines := strings.Split(content, "\n")
+	first, last := 0, len(lines)
+	if len(lines) > 1 && strings.TrimSpace(lines[0]) == "" {
+		first++
+	}
+	if last-first > 1 && strings.TrimSpace(lines[last-1]) == "" {
+		last--
+	}
+	block := lines[first:last]
+
+	formatted, err := formatBlock(block, commonIndent(block), options)
+	if err != nil {
+		return "", err
+	}
+	lines = append(append(lines[:first:first], formatted...), lines[last:]...)
+	return strings.Join(lines, "\n"), nil
+}
+
+// commonIndent returns the leading whitespace common to the lines which are not blank
+func commonIndent(lines []string) string {
+	indent := ""
+	found := false
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		lead := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if !found {
+			indent, found = lead, true
+			continue
+		}
+		for !strings.HasPrefix(lead, indent) {
+			indent = indent[:len(indent)-1]
+		}
+	}
+	return indent
+}
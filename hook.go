@@ -0,0 +1,160 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const hookMarker = "# installed by cadencefmt install-hook"
+
+// runInstallHook installs a git pre-commit hook,
+// which checks (or fixes) the staged Cadence files
+func runInstallHook(args []string) {
+	flags := flag.NewFlagSet("install-hook", flag.ExitOnError)
+	fixFlag := flags.Bool("fix", false, "format staged files instead of rejecting the commit")
+	commandFlag := flags.String("command", "cadencefmt", "command the hook runs")
+	forceFlag := flags.Bool("force", false, "replace an existing hook")
+	_ = flags.Parse(args)
+
+	hooksDir, err := git("rev-parse", "--git-path", "hooks")
+	if err != nil {
+		fatal(err)
+	}
+	path := filepath.Join(strings.TrimSpace(hooksDir), "pre-commit")
+
+	existing, err := os.ReadFile(path)
+	if err == nil && !bytes.Contains(existing, []byte(hookMarker)) && !*forceFlag {
+		fatal(fmt.Errorf("%s already exists, use -force to replace it", path))
+	} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+		fatal(err)
+	}
+
+	command := *commandFlag + " pre-commit"
+	if *fixFlag {
+		command += " -fix"
+	}
+	hook := fmt.Sprintf("#!/bin/sh\n%s\nexec %s\n", hookMarker, command)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(hook), 0755); err != nil {
+		fatal(err)
+	}
+	fmt.Printf("Installed %s\n", path)
+}
+
+// runPreCommit checks the staged Cadence files.
+// The staged content is read from the index, not the working tree,
+// so partially staged files are checked as they will be committed
+func runPreCommit(args []string) {
+	flags := flag.NewFlagSet("pre-commit", flag.ExitOnError)
+	fixFlag := flags.Bool("fix", false, "format staged files instead of rejecting the commit")
+	optionFlags := addOptionFlags(flags)
+	_ = flags.Parse(args)
+
+	options, err := optionFlags.cliOptions()
+	if err != nil {
+		fatal(err)
+	}
+
+	//the names are separated by NUL, as names with spaces or quotes would be split or quoted otherwise
+	output, err := git("diff", "--cached", "--name-only", "-z", "--diff-filter=ACM", "--", "*.cdc")
+	if err != nil {
+		fatal(err)
+	}
+
+	var unformatted []string
+	for _, filename := range strings.Split(output, "\x00") {
+		if filename == "" {
+			continue
+		}
+		staged, err := git("show", ":"+filename)
+		if err != nil {
+			fatal(err)
+		}
+
+		formatted, err := formatSource(staged, nil, options)
+		if err != nil {
+			fatal(fmt.Errorf("%s: %w", filename, err))
+		}
+		if formatted == staged {
+			continue
+		}
+
+		if !*fixFlag {
+			unformatted = append(unformatted, filename)
+			continue
+		}
+
+		if err := stageFormatted(filename, staged, formatted); err != nil {
+			fatal(err)
+		}
+	}
+
+	if len(unformatted) > 0 {
+		fmt.Fprintln(os.Stderr, "cadencefmt: the following staged files are not formatted:")
+		for _, filename := range unformatted {
+			fmt.Fprintf(os.Stderr, "\t%s\n", filename)
+		}
+		os.Exit(exitNotFormatted)
+	}
+}
+
+// stageFormatted replaces the staged content of the file.
+// The working tree is only updated if it matches the index,
+// so unstaged changes of partially staged files are kept
+func stageFormatted(filename string, staged string, formatted string) error {
+	cmd := exec.Command("git", "hash-object", "-w", "--stdin", "--path", filename)
+	cmd.Stdin = strings.NewReader(formatted)
+	hash, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("%s: git hash-object: %w", filename, err)
+	}
+
+	entry, err := git("ls-files", "--stage", "--", filename)
+	if err != nil {
+		return err
+	}
+	mode, _, _ := strings.Cut(entry, " ")
+
+	cacheInfo := fmt.Sprintf("%s,%s,%s", mode, strings.TrimSpace(string(hash)), filename)
+	if _, err := git("update-index", "--cacheinfo", cacheInfo); err != nil {
+		return err
+	}
+
+	working, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	if string(working) != staged {
+		fmt.Fprintf(os.Stderr, "cadencefmt: formatted staged %s, unstaged changes left as they are\n", filename)
+		return nil
+	}
+
+	return writeFormatted(filename, working, []byte(formatted), "")
+}
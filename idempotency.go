@@ -0,0 +1,236 @@
+//go:build !noui
+
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// idempotencyTTL is how long responses are kept for retries
+const idempotencyTTL = 24 * time.Hour
+
+// idempotencyBudget is the number of bytes of responses kept,
+// the least recently used are dropped first
+const idempotencyBudget = 16 << 20
+
+// idempotencyCache keeps the responses of requests with an Idempotency-Key header,
+// so retries of mutating requests replay the response instead of repeating the mutation
+type idempotencyCache struct {
+	mu     sync.Mutex
+	budget int
+	// size is the number of bytes of the kept responses
+	size  int
+	order *list.List
+	// expiry has the responses in the order they expire,
+	// which is the order they were added in, as they are kept for the same time
+	expiry  *list.List
+	entries map[string]*list.Element
+}
+
+type idempotentResponse struct {
+	key string
+	// fingerprint is the hash of the request body,
+	// a key must not be reused for a different request
+	fingerprint [sha256.Size]byte
+	done        bool
+	status      int
+	header      http.Header
+	body        []byte
+	expires     time.Time
+	// expiryElement is the element of the response in the expiry list
+	expiryElement *list.Element
+}
+
+// size is about the memory used by the response
+func (r *idempotentResponse) size() int {
+	size := len(r.key) + len(r.body)
+	for name, values := range r.header {
+		size += len(name)
+		for _, value := range values {
+			size += len(value)
+		}
+	}
+	return size
+}
+
+// newIdempotencyCache returns a cache keeping responses of up to budget bytes
+func newIdempotencyCache(budget int) *idempotencyCache {
+	return &idempotencyCache{
+		budget:  budget,
+		order:   list.New(),
+		expiry:  list.New(),
+		entries: map[string]*list.Element{},
+	}
+}
+
+// wrap makes the handler replay responses of requests with a known key.
+// Only handlers with side effects are wrapped, repeating the others is harmless
+func (c *idempotencyCache) wrap(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyKeyHeader)
+		if key == "" || r.Method == http.MethodGet || r.Method == http.MethodHead {
+			next(w, r)
+			return
+		}
+		//the same key of another session, e.g. for its settings, is another request
+		key = r.Method + " " + r.URL.Path + " " + r.Header.Get("Cookie") + " " + key
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeBodyError(w, err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		fingerprint := sha256.Sum256(body)
+
+		c.mu.Lock()
+		c.removeExpired()
+		var entry *idempotentResponse
+		element, ok := c.entries[key]
+		if ok {
+			c.order.MoveToFront(element)
+			entry = element.Value.(*idempotentResponse)
+		}
+		switch {
+		case ok && entry.fingerprint != fingerprint:
+			c.mu.Unlock()
+			writeError(w, http.StatusUnprocessableEntity, errIdempotencyKeyReused)
+			return
+		case ok && !entry.done:
+			c.mu.Unlock()
+			writeError(w, http.StatusConflict, errIdempotencyKeyInFlight)
+			return
+		case ok:
+			c.mu.Unlock()
+			for name, values := range entry.header {
+				w.Header()[name] = values
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(entry.status)
+			_, _ = w.Write(entry.body)
+			return
+		}
+		entry = &idempotentResponse{
+			key:         key,
+			fingerprint: fingerprint,
+			expires:     time.Now().Add(idempotencyTTL),
+		}
+		element = c.order.PushFront(entry)
+		entry.expiryElement = c.expiry.PushBack(entry)
+		c.entries[key] = element
+		c.mu.Unlock()
+
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(recorder, r)
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		//failed requests may be retried with the same key
+		if recorder.status >= http.StatusInternalServerError {
+			c.remove(element)
+			return
+		}
+		entry.done = true
+		entry.status = recorder.status
+		entry.header = w.Header().Clone()
+		//the body is recorded before it is compressed, which the handlers around decide on for the replay again
+		for _, name := range []string{"Content-Encoding", "Content-Length", "Vary"} {
+			entry.header.Del(name)
+		}
+		entry.body = recorder.body.Bytes()
+		c.size += entry.size()
+		c.evict()
+	}
+}
+
+// evict drops the least recently used responses over the budget,
+// the requests in progress are kept
+func (c *idempotencyCache) evict() {
+	for element := c.order.Back(); element != nil && c.size > c.budget; {
+		previous := element.Prev()
+		if entry := element.Value.(*idempotentResponse); entry.done {
+			c.remove(element)
+		}
+		element = previous
+	}
+}
+
+// removeExpired drops the expired responses, from the oldest until one has not expired
+func (c *idempotencyCache) removeExpired() {
+	now := time.Now()
+	for element := c.expiry.Front(); element != nil; {
+		entry := element.Value.(*idempotentResponse)
+		if !now.After(entry.expires) {
+			return
+		}
+		next := element.Next()
+		if entry.done {
+			c.remove(c.entries[entry.key])
+		}
+		element = next
+	}
+}
+
+func (c *idempotencyCache) remove(element *list.Element) {
+	entry := element.Value.(*idempotentResponse)
+	c.order.Remove(element)
+	c.expiry.Remove(entry.expiryElement)
+	delete(c.entries, entry.key)
+	//the size counts once the response is recorded
+	if entry.done {
+		c.size -= entry.size()
+	}
+}
+
+var (
+	errIdempotencyKeyReused   = errors.New(idempotencyKeyHeader + " was already used for a different request")
+	errIdempotencyKeyInFlight = errors.New("a request with this " + idempotencyKeyHeader + " is still in progress")
+)
+
+// responseRecorder passes a response through, keeping a copy of it
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
@@ -0,0 +1,301 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// jsTags are the template tags and the property of the FCL functions whose template literals are cadence code
+var jsTags = map[string]bool{
+	"cdc":             true,
+	"fcl.cdc":         true,
+	"fcl.script":      true,
+	"fcl.transaction": true,
+	"cadence:":        true,
+}
+
+// jsToken is a token of JavaScript or TypeScript code, as far as needed to find the template literals
+type jsToken struct {
+	text     string
+	offset   int
+	line     int
+	template bool
+	comment  bool
+}
+
+// jsScanner splits JavaScript or TypeScript code into tokens, skipping strings, comments and regular expressions
+type jsScanner struct {
+	text     string
+	offset   int
+	line     int
+	previous string
+}
+
+// formatJSSource formats the cadence code of the template literals following a `// cadence` comment,
+// tagged with `fcl.cdc`, `fcl.script` or `fcl.transaction`, or passed as the `cadence` property
+// of `fcl.query` and `fcl.mutate`, and keeps the rest of the code as written.
+// Templates with substitutions are kept as written
+func formatJSSource(text string, options cliOptions) (string, error) {
+	s := &jsScanner{text: text, line: 1}
+	var result strings.Builder
+	written := 0
+	markerLine := 0
+	var tokens []string
+	for {
+		token, err := s.next()
+		if err != nil {
+			return "", err
+		}
+		if token == nil {
+			break
+		}
+		if token.comment {
+			if jsMarker(token.text) {
+				markerLine = token.line
+			}
+			continue
+		}
+		if token.template {
+			marked := markerLine > 0 && token.line-markerLine <= 1
+			if marked || jsTags[jsTag(tokens)] {
+				markerLine = 0
+				content := token.text[1 : len(token.text)-1]
+				if !strings.Contains(content, "${") {
+					formatted, err := formatLiteral(content, options)
+					if err != nil {
+						return "", err
+					}
+					result.WriteString(text[written : token.offset+1])
+					result.WriteString(formatted)
+					written = token.offset + len(token.text) - 1
+				}
+			}
+		}
+		tokens = append(tokens, token.text)
+	}
+	result.WriteString(text[written:])
+	return result.String(), nil
+}
+
+// jsMarker reports if the comment is the marker of a template literal
+func jsMarker(comment string) bool {
+	if strings.HasPrefix(comment, "//") {
+		return strings.TrimSpace(comment[2:]) == "cadence"
+	}
+	return strings.TrimSpace(strings.TrimSuffix(comment[2:], "*/")) == "cadence"
+}
+
+// jsTag returns the tag of a template literal following the tokens,
+// like `fcl.script`, or `cadence:` for a property
+func jsTag(tokens []string) string {
+	n := len(tokens)
+	if n >= 2 && tokens[n-1] == ":" {
+		return tokens[n-2] + ":"
+	}
+	tag := ""
+	for i := n - 1; i >= 0 && isJSIdentifier(tokens[i]); i -= 2 {
+		tag = tokens[i] + tag
+		if i == 0 || tokens[i-1] != "." {
+			return tag
+		}
+		tag = "." + tag
+	}
+	return tag
+}
+
+// isJSIdentifier reports if the token is an identifier or a keyword
+func isJSIdentifier(token string) bool {
+	for i, r := range token {
+		if !(r == '_' || r == '$' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || i > 0 && '0' <= r && r <= '9') {
+			return false
+		}
+	}
+	return token != ""
+}
+
+// next returns the next token, or nil at the end of the code
+func (s *jsScanner) next() (*jsToken, error) {
+	for s.offset < len(s.text) && strings.ContainsRune(" \t\r\n", rune(s.text[s.offset])) {
+		s.advance(1)
+	}
+	if s.offset == len(s.text) {
+		return nil, nil
+	}
+
+	token := &jsToken{offset: s.offset, line: s.line}
+	rest := s.text[s.offset:]
+	var err error
+	switch c := rest[0]; {
+	case strings.HasPrefix(rest, "//"):
+		end := strings.IndexByte(rest, '\n')
+		if end < 0 {
+			end = len(rest)
+		}
+		s.advance(end)
+		token.comment = true
+	case strings.HasPrefix(rest, "/*"):
+		end := strings.Index(rest, "*/")
+		if end < 0 {
+			return nil, s.errorf("unterminated comment")
+		}
+		s.advance(end + 2)
+		token.comment = true
+	case c == '\'' || c == '"':
+		err = s.quoted(c)
+	case c == '`':
+		err = s.template()
+		token.template = true
+	case c == '/' && s.regexAllowed():
+		err = s.regex()
+	case isJSIdentifier(rest[:1]) || '0' <= c && c <= '9':
+		end := 1
+		for end < len(rest) && isJSIdentifier("a"+rest[1:end+1]) {
+			end++
+		}
+		s.advance(end)
+	default:
+		s.advance(1)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	token.text = s.text[token.offset:s.offset]
+	if !token.comment {
+		s.previous = token.text
+	}
+	return token, nil
+}
+
+// advance moves the scanner by n bytes, counting the lines
+func (s *jsScanner) advance(n int) {
+	s.line += strings.Count(s.text[s.offset:s.offset+n], "\n")
+	s.offset += n
+}
+
+// errorf returns an error at the current line
+func (s *jsScanner) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %s", s.line, fmt.Sprintf(format, args...))
+}
+
+// regexAllowed reports if a slash starts a regular expression instead of being a division
+func (s *jsScanner) regexAllowed() bool {
+	switch s.previous {
+	case "", "return", "typeof", "case", "do", "else", "in", "of", "new", "delete", "void", "throw", "yield", "await":
+		return true
+	case ")", "]":
+		return false
+	}
+	last := s.previous[0]
+	return !isJSIdentifier(s.previous) && !('0' <= last && last <= '9') && last != '\'' && last != '"' && last != '`'
+}
+
+// quoted skips a string literal
+func (s *jsScanner) quoted(quote byte) error {
+	s.advance(1)
+	for s.offset < len(s.text) {
+		switch s.text[s.offset] {
+		case '\\':
+			s.advance(min(2, len(s.text)-s.offset))
+		case quote:
+			s.advance(1)
+			return nil
+		case '\n':
+			return s.errorf("unterminated string")
+		default:
+			s.advance(1)
+		}
+	}
+	return s.errorf("unterminated string")
+}
+
+// template skips a template literal, with the code of its substitutions
+func (s *jsScanner) template() error {
+	s.advance(1)
+	for s.offset < len(s.text) {
+		switch {
+		case s.text[s.offset] == '\\':
+			s.advance(min(2, len(s.text)-s.offset))
+		case s.text[s.offset] == '`':
+			s.advance(1)
+			return nil
+		case strings.HasPrefix(s.text[s.offset:], "${"):
+			s.advance(2)
+			if err := s.substitution(); err != nil {
+				return err
+			}
+		default:
+			s.advance(1)
+		}
+	}
+	return s.errorf("unterminated template literal")
+}
+
+// substitution skips the code of a substitution up to its closing brace
+func (s *jsScanner) substitution() error {
+	s.previous = "${"
+	depth := 0
+	for {
+		token, err := s.next()
+		if err != nil {
+			return err
+		}
+		switch {
+		case token == nil:
+			return s.errorf("unterminated template literal")
+		case token.text == "{":
+			depth++
+		case token.text == "}" && depth == 0:
+			return nil
+		case token.text == "}":
+			depth--
+		}
+	}
+}
+
+// regex skips a regular expression literal with its flags
+func (s *jsScanner) regex() error {
+	s.advance(1)
+	class := false
+	for s.offset < len(s.text) {
+		switch s.text[s.offset] {
+		case '\\':
+			s.advance(min(2, len(s.text)-s.offset))
+			continue
+		case '[':
+			class = true
+		case ']':
+			class = false
+		case '/':
+			if !class {
+				s.advance(1)
+				for s.offset < len(s.text) && isJSIdentifier(s.text[s.offset:s.offset+1]) {
+					s.advance(1)
+				}
+				return nil
+			}
+		case '\n':
+			return s.errorf("unterminated regular expression")
+		}
+		s.advance(1)
+	}
+	return s.errorf("unterminated regular expression")
+}
@@ -0,0 +1,67 @@
+//go:build !noui
+
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// limitBody makes reading request bodies fail after the given number of bytes.
+// A limit of zero allows bodies of any size
+func limitBody(limit int64, next http.Handler) http.Handler {
+	if limit <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		//streamed batches limit each of their entries instead
+		if !streamsBody(r) {
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// writeBodyError reports a request body which could not be read or decoded
+func writeBodyError(w http.ResponseWriter, err error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("request body exceeds %d bytes", maxBytesErr.Limit))
+		return
+	}
+	writeError(w, http.StatusBadRequest, err)
+}
+
+// formatTimeout responds with 503 if the handler does not finish in time,
+// and cancels the context of the request, which stops the formatting.
+// A timeout of zero disables it
+func formatTimeout(timeout time.Duration, next http.HandlerFunc) http.HandlerFunc {
+	if timeout <= 0 {
+		return next
+	}
+	message, _ := json.Marshal(ErrorResponse{
+		Error: fmt.Sprintf("formatting took longer than %s", timeout),
+	})
+	return http.TimeoutHandler(next, timeout, string(message)).ServeHTTP
+}
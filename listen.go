@@ -0,0 +1,81 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+)
+
+const unixScheme = "unix://"
+
+// listen listens on a TCP address, "host:port",
+// or on a Unix socket, "unix:///path.sock"
+func listen(address string) (net.Listener, error) {
+	if path, ok := strings.CutPrefix(address, unixScheme); ok {
+		return listenUnix(path)
+	}
+	return net.Listen("tcp", address)
+}
+
+// listenUnix listens on a Unix socket, replacing the stale socket of a previous run.
+// Files which are not sockets are kept, a mistyped path must not delete them
+func listenUnix(path string) (net.Listener, error) {
+	info, err := os.Lstat(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, err
+	case info.Mode()&os.ModeSocket == 0:
+		return nil, fmt.Errorf("%s exists and is not a socket", path)
+	default:
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return net.Listen("unix", path)
+}
+
+// closeOnSignal closes the listener on SIGINT or SIGTERM, which ends its accept loop
+// and removes its Unix socket
+func closeOnSignal(ln net.Listener) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		signal.Stop(signals)
+		_ = ln.Close()
+	}()
+}
+
+// listenerURL is the URL clients use to reach the listener
+func listenerURL(ln net.Listener, tls bool) string {
+	if ln.Addr().Network() == "unix" {
+		return unixScheme + ln.Addr().String()
+	}
+	if tls {
+		return "https://" + ln.Addr().String() + "/"
+	}
+	return "http://" + ln.Addr().String() + "/"
+}
@@ -0,0 +1,220 @@
+//go:build !noui
+
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"cadencefmt/format"
+)
+
+// LiveRequest is a message of the /ws channel,
+// a Request with an ID, which the response carries
+type LiveRequest struct {
+	ID int64 `json:"id"`
+	Request
+}
+
+// LiveResponse is the formatted code of the request with the ID,
+// or the error if it could not be formatted
+type LiveResponse struct {
+	ID int64 `json:"id"`
+	*PrettyResponse
+	*ErrorResponse
+}
+
+// liveResult is the outcome of formatting a request of the channel
+type liveResult struct {
+	response LiveResponse
+	// formatted is the code and its result, if it was formatted
+	formatted format.Formatted
+	err       error
+}
+
+// handleLive returns the handler of /ws, a WebSocket channel for formatting while the user types.
+// A request is only formatted once no newer one arrived for the debounce time,
+// and a newer request cancels the formatting of older ones, which are not answered
+func handleLive(
+	profiles profileDir,
+	cache *formatCache,
+	limiter *rateLimiter,
+	pool *workerPool,
+	maxMessageSize int64,
+	timeout time.Duration,
+	debounce time.Duration,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgradeWebSocket(w, r, maxMessageSize)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		done := make(chan struct{})
+		defer close(done)
+
+		requests := make(chan LiveRequest)
+		go func() {
+			defer close(requests)
+			for {
+				message, err := conn.readMessage()
+				if err != nil {
+					return
+				}
+				var req LiveRequest
+				if err := json.Unmarshal(message, &req); err != nil {
+					_ = conn.writeJSON(LiveResponse{ErrorResponse: newErrorResponse(err)})
+					continue
+				}
+				select {
+				case requests <- req:
+				case <-done:
+					return
+				}
+			}
+		}()
+
+		results := make(chan liveResult)
+		var pending LiveRequest
+		var debounced <-chan time.Time
+		//latest is the ID of the request formatted last, only it is answered
+		var latest int64
+		//previous is the result of the last answered request, which the next edit updates
+		var previous format.Formatted
+		cancel := context.CancelFunc(func() {})
+		defer func() {
+			cancel()
+		}()
+
+		for {
+			select {
+			case req, ok := <-requests:
+				if !ok {
+					return
+				}
+				//the request supersedes the pending one, and the one being formatted
+				cancel()
+				pending = req
+				debounced = time.After(debounce)
+
+			case <-debounced:
+				debounced = nil
+				req := pending
+				latest = req.ID
+
+				if limiter != nil {
+					if _, ok := limiter.allow(clientIP(r), time.Now()); !ok {
+						err := conn.writeJSON(LiveResponse{ID: req.ID, ErrorResponse: newErrorResponse(errRateLimited)})
+						if err != nil {
+							return
+						}
+						continue
+					}
+				}
+
+				var ctx context.Context
+				var stop context.CancelFunc
+				if timeout > 0 {
+					ctx, stop = context.WithTimeout(r.Context(), timeout)
+				} else {
+					ctx, stop = context.WithCancel(r.Context())
+				}
+				cancel = stop
+				go func(previous format.Formatted) {
+					var response LiveResponse
+					var formatted format.Formatted
+					release, err := pool.acquire(ctx)
+					if err == nil {
+						response, formatted, err = formatLive(ctx, req, previous, profiles, cache, timeout)
+						release()
+					} else if errors.Is(err, errBusy) {
+						response, err = LiveResponse{ID: req.ID, ErrorResponse: newErrorResponse(err)}, nil
+					}
+					select {
+					case results <- liveResult{response: response, formatted: formatted, err: err}:
+					case <-done:
+					}
+				}(previous)
+
+			case result := <-results:
+				//superseded requests are not answered
+				if result.err != nil || result.response.ID != latest {
+					continue
+				}
+				if result.formatted.Result != "" {
+					previous = result.formatted
+				}
+				if err := conn.writeJSON(result.response); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
+
+// formatLive formats the code of the request, and returns it with its result.
+// Edits inside a single declaration of the previous code only format that declaration.
+// It only returns an error if the formatting was canceled,
+// other errors are reported in the response
+func formatLive(
+	ctx context.Context,
+	req LiveRequest,
+	previous format.Formatted,
+	profiles profileDir,
+	cache *formatCache,
+	timeout time.Duration,
+) (LiveResponse, format.Formatted, error) {
+	response := LiveResponse{ID: req.ID}
+
+	options, err := profiles.options(req.RequestOptions)
+	if err != nil {
+		response.ErrorResponse = newErrorResponse(err)
+		return response, format.Formatted{}, nil
+	}
+
+	result, ok := format.Reformat(previous, req.Code, options)
+	if ok {
+		err = checkFormatted(req.Code, result, cliOptions{Options: options})
+	} else {
+		result, err = cache.format(ctx, req.Code, options)
+	}
+	if errors.Is(err, context.Canceled) {
+		return response, format.Formatted{}, err
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = fmt.Errorf("formatting took longer than %s", timeout)
+	}
+	if err != nil {
+		response.ErrorResponse = newErrorResponse(err)
+		return response, format.Formatted{}, nil
+	}
+
+	response.PrettyResponse = &PrettyResponse{
+		Code:  result,
+		Lines: lineMetadata(result, options.MaxLineLength),
+	}
+	return response, format.Formatted{Code: req.Code, Result: result, Options: options}, nil
+}
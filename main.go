@@ -20,368 +20,227 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
-	"log"
-	"net"
-	"net/http"
 	"os"
-	"strings"
+	"path/filepath"
+	"runtime"
+	"time"
 
-	"github.com/openconfig/goyang/pkg/indent"
-	"github.com/turbolent/prettier"
 	"golang.org/x/exp/slices"
 
-	"github.com/onflow/cadence/runtime/parser"
-	"github.com/onflow/cadence/runtime/parser/lexer"
+	"cadencefmt/format"
 )
 
-func pretty(code string, maxLineWidth int) string {
-	program, err := parser.ParseProgram(nil, []byte(code), parser.Config{})
-	if err != nil {
-		return err.Error()
-	}
-
-	var b strings.Builder
-	prettier.Prettier(&b, program.Doc(), maxLineWidth, "    ")
-	return b.String()
+// commands are the subcommands, selected by the first argument
+var commands = map[string]func(args []string){
+	"daemon":       runDaemon,
+	"bundle":       runBundle,
+	"install-hook": runInstallHook,
+	"pre-commit":   runPreCommit,
+	"stability":    runStability,
+	"fuzz":         runFuzz,
+	"minimize":     runMinimize,
+	"test-corpus":  runTestCorpus,
+	"adopt":        runAdopt,
+	"docgen":       runDocgen,
+	"rules":        runRules,
+	"project":      runProject,
+	"args":         runArgs,
+	"bench":        runBench,
 }
 
-// language=html
-const page = `
-<html>
-<head>
-    <title>Pretty</title>
-    <style>
-        :root {
-            --line-length: 0ch;
-        }
-
-        body {
-            margin: 0;
-            padding: 0;
-            font-family: monospace;
-            height: 100vh;
-        }
-
-        #panels {
-            display: grid;
-            grid-template-rows: 100vh;
-            grid-template-columns: 50% 50%;
-            grid-template-areas: "editor editor2";
-        }
-
-        #editor {
-            grid-area: editor;
-            border: 1px solid #ccc;
-            resize: none;
-        }
-
- 		#editor2 {
-            grid-area: editor2;
-            border: 1px solid #ccc;
-            resize: none;
-        }
-
-        #pretty {
-            position: relative;
-            grid-area: ast;
-        }
-
-        #output {
-            white-space: pre;
-            height: 100%;
-            overflow: scroll;
-        }
-
-        #bar {
-            position: absolute;
-            left: var(--line-length);
-            top: 0;
-            bottom: 0;
-            width: 2px;
-            background-color: black;
-        }
-
-        #stepper {
-            position: sticky;
-            top: 0
-        }
-    </style>
-</head>
-<body id="panels">
-<textarea id="editor" onkeydown="if(event.keyCode===9){var v=this.value,s=this.selectionStart,e=this.selectionEnd;this.value=v.substring(0, s)+'    '+v.substring(e);this.selectionStart=this.selectionEnd=s+4;return false;}"></textarea>
-<textarea id="editor2"></textarea>
-
-<div id="pretty">
-    <input id="stepper" type="number" min="1" step="1">
-    <div id="output">
-    </div>
-    <div id="bar"></div>
-</div>
-</body>
-<script>
-    let code = localStorage.getItem('code') || ''
-    let maxLineLength = Number(localStorage.getItem('maxLineLength')) || 80;
-
-    const root = document.documentElement;
-    const editor = document.getElementById("editor")
-    const output = document.getElementById("output")
-    const stepper = document.getElementById("stepper")
-
-    document.addEventListener('DOMContentLoaded', () => {
-        stepper.value = maxLineLength
-        editor.innerHTML = code
-        update()
-    })
-
-    editor.addEventListener("input", (e) => {
-        code = e.target.value
-        localStorage.setItem('code', code)
-        update()
-    })
-
-    stepper.addEventListener("input", (e) => {
-        maxLineLength = Number(e.target.value)
-        localStorage.setItem('maxLineLength', maxLineLength)
-        update()
-    })
-
-    async function update() {
-        root.style.setProperty('--line-length', maxLineLength + 'ch')
-        const response = await fetch('/pretty', {
-            method: "POST",
-            body: JSON.stringify({
-                code,
-                maxLineLength
-            })
-		})
-		editor2.innerHTML = await response.text()
-    }
-</script>
-</html>
-`
-
-type Request struct {
-	Code          string `json:"code"`
-	MaxLineLength int    `json:"maxLineLength"`
-}
+func main() {
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			command(os.Args[2:])
+			return
+		}
+	}
 
-func extractTokenText(text string, token lexer.Token) string {
-	return text[token.StartPos.Offset : token.EndPos.Offset+1]
-}
+	optionFlags := addOptionFlags(flag.CommandLine)
+	serverFlags := addServerFlags(flag.CommandLine)
+	budgetFlag := flag.Int("budget", 0, "report the formatted size and warn above this many bytes")
+	linesFlag := flag.String("lines", "", "format only the declarations touching the lines from:to")
+	diffBaseFlag := flag.String("diff-base", "", "format only the declarations changed since the git ref")
+	writeFlag := flag.Bool("w", false, "write the result to the file instead of stdout")
+	checkFlag := flag.Bool("check", false, "list files which are not formatted and exit with 1")
+	verifyFlag := flag.Bool("verify", false, "also check that the formatted code has all tokens of the code")
+	astCheckFlag := flag.Bool("ast-check", true, "check that the formatted code has the same syntax tree as the code, -ast-check=false is faster")
+	baselineFlag := flag.String("baseline", "", "in check mode, allow the files listed in this file to stay unformatted, recording them if it does not exist")
+	updateBaselineFlag := flag.Bool("update-baseline", false, "record the files which are not formatted in the -baseline file")
+	jobsFlag := flag.Int("jobs", runtime.GOMAXPROCS(0), "number of files formatted in parallel")
+	watchFlag := flag.Bool("watch", false, "format the files and directories in place whenever they are saved")
+	fitFlag := flag.Int("fit", 0, "report the smallest width, at least this many columns, at which no line overflows")
+	fitTimeoutFlag := flag.Duration("fit-timeout", 10*time.Second, "time limit of the -fit search per file")
+	versionFlag := flag.Bool("version", false, "print the version and exit")
+	logFormatFlag := flag.String("log-format", "text", "log format, "+logFormats)
+	statsFlag := flag.Bool("stats", false, "print a summary of the files scanned and changed, parse failures and the time")
+	outputFlag := flag.String("output", "text", "output format, "+outputFormats)
+	editsFlag := flag.String("edits", "", "print the edits turning each file into the formatted code instead of the code, "+editsFormats)
+	migrateFlag := flag.Bool("migrate", false, "rewrite pub, priv and the account types to Cadence 1.0 after formatting")
+	maxSizeFlag := flag.Int64("max-size", 4<<20, "refuse files larger than this many bytes, 0 for no limit")
+	forceFlag := flag.Bool("force", false, "format files larger than -max-size")
+	dryRunFlag := flag.Bool("dry-run", false, "print how many lines of each file would change, and the first of them, without writing anything")
+	backupSuffixFlag := flag.String("backup-suffix", "", "with -w, keep the original of each changed file in the file with this suffix, e.g. .orig")
+	quietFlag := flag.Bool("quiet", false, "only print errors, check mode only sets the exit code")
+	verboseFlag := flag.Bool("verbose", false, "print each file as it is formatted, with the time and if it changed")
+	serveSocketFlag := flag.String("serve-socket", "", "format the code sent to this Unix socket, with length-prefixed requests")
+	cpuProfileFlag := flag.String("cpuprofile", "", "write a CPU profile of the run to this file")
+	memProfileFlag := flag.String("memprofile", "", "write a heap profile at the end of the run to this file")
+	compactFlag := flag.Bool("compact", false, "print the code with minimal whitespace and without comments, e.g. for embedding or hashing")
+	assumeFilenameFlag := flag.String("assume-filename", "", "with - as the file, format the code on stdin as this file, for the configuration, diagnostics and the format of embedded code")
+	whitespaceOnlyFlag := flag.Bool("whitespace-only", false, "only remove trailing whitespace, normalize the indentation characters and fix the final line break")
 
-func prettyCode(existingCode string, maxLineLength int, tabs bool) string {
-	existingCodeLines := strings.Split(existingCode, "\n")
-	oldTokens := lexer.Lex([]byte(existingCode), nil)
+	flag.Parse()
 
-	prettyCode := pretty(existingCode, maxLineLength)
-	if strings.HasPrefix(prettyCode, "Parsing failed:") {
-		return prettyCode
+	if err := setLogFormat(*logFormatFlag); err != nil {
+		fatal(err)
 	}
-	newTokens := lexer.Lex([]byte(prettyCode), nil)
-
-	oldToken := lexer.Token{Type: lexer.TokenSpace}
-	newToken := lexer.Token{Type: lexer.TokenSpace}
 
-	ignoredTokenTypes := []lexer.TokenType{
-		lexer.TokenParenClose,
-		lexer.TokenParenOpen,
-		lexer.TokenBracketOpen,
-		lexer.TokenBracketClose,
+	stopProfiles, err := startProfiles(*cpuProfileFlag, *memProfileFlag)
+	if err != nil {
+		fatal(err)
 	}
+	defer stopProfiles()
 
-	result := strings.Builder{}
-	spaces := strings.Builder{}
-	comment := strings.Builder{}
-
-	for {
-
-		if !newToken.Is(lexer.TokenEOF) {
-			newToken = newTokens.Next()
+	if *versionFlag {
+		v := currentVersion()
+		if *outputFlag == "json" {
+			if err := json.NewEncoder(os.Stdout).Encode(v); err != nil {
+				fatal(err)
+			}
+			return
 		}
+		fmt.Printf("cadencefmt %s (commit %s, cadence %s %s syntax, %s)\n", v.Version, v.Commit, v.Cadence, v.Syntax, v.Go)
+		return
+	}
 
-		if newToken.Is(lexer.TokenSpace) {
-			spaces.WriteString(extractTokenText(prettyCode, newToken))
-			continue
+	//the code on stdin uses the configuration nearest to the file it is assumed to be
+	if *assumeFilenameFlag != "" && *optionFlags.config == "" {
+		path, err := filepath.Abs(*assumeFilenameFlag)
+		if err != nil {
+			fatal(err)
 		}
+		optionFlags.configDir = filepath.Dir(path)
+	}
 
-		//temporary fix for pretty producing extra {} for interface members without default impl.
-		if newToken.Is(lexer.TokenBraceOpen) {
-			cursor := newTokens.Cursor()
-			if newTokens.Next().Type == lexer.TokenBraceClose {
-				result.WriteString("{}")
-				continue
-			} else {
-				result.WriteString("{")
-				newTokens.Revert(cursor)
-				continue
-			}
-
+	options, err := optionFlags.cliOptions()
+	if err != nil {
+		fatal(err)
+	}
+	options.assumeFilename = *assumeFilenameFlag
+	options.budget = *budgetFlag
+	options.diffBase = *diffBaseFlag
+	options.write = *writeFlag
+	options.check = *checkFlag
+	options.verifyTokens = *verifyFlag
+	options.skipASTCheck = !*astCheckFlag
+	options.migrate = *migrateFlag
+	options.whitespaceOnly = *whitespaceOnlyFlag
+	options.compact = *compactFlag
+	options.backupSuffix = *backupSuffixFlag
+	options.dryRun = *dryRunFlag
+	if *quietFlag && *verboseFlag {
+		fatal(errors.New("-quiet and -verbose exclude each other"))
+	}
+	options.quiet = *quietFlag
+	options.verbose = *verboseFlag
+	if !*forceFlag {
+		options.maxSize = *maxSizeFlag
+	}
+	switch *outputFlag {
+	case "text":
+	case "json":
+		options.jsonOutput = true
+	default:
+		fatal(fmt.Errorf("unknown output format %q, expected %s", *outputFlag, outputFormats))
+	}
+	switch *editsFlag {
+	case "":
+	case "json":
+		if options.write || options.check || options.dryRun || options.jsonOutput {
+			fatal(errors.New("-edits excludes -w, -check, -dry-run and -output json"))
 		}
+		options.edits = true
+	default:
+		fatal(fmt.Errorf("unknown edits format %q, expected %s", *editsFlag, editsFormats))
+	}
 
-		if slices.Contains(ignoredTokenTypes, newToken.Type) {
-			result.WriteString(spaces.String())
-			result.WriteString(extractTokenText(prettyCode, newToken))
-			spaces.Reset()
-			continue
+	if *baselineFlag != "" {
+		if !options.check {
+			fatal(errors.New("-baseline requires -check"))
 		}
-
-		if !oldToken.Is(lexer.TokenEOF) {
-			for {
-				oldToken = oldTokens.Next()
-
-				//check only comments
-				if oldToken.Is(lexer.TokenLineComment) || oldToken.Is(lexer.TokenBlockCommentContent) {
-
-					switch oldToken.Type {
-					case lexer.TokenLineComment:
-						isTrailing := false
-
-						//check trailing
-						oldLine := existingCodeLines[oldToken.StartPosition().Line-1][:oldToken.StartPosition().Column]
-						oldLine = strings.Trim(oldLine, " \t")
-						if len(oldLine) > 0 {
-							isTrailing = true
-						}
-
-						//check previous line empty
-						if !isTrailing && oldToken.StartPosition().Line > 1 {
-							if len(strings.Trim(existingCodeLines[oldToken.StartPosition().Line-2], " \t")) == 0 {
-								//leading comment
-								if len(oldLine) == 0 && !strings.HasSuffix(strings.Replace(spaces.String(), " ", "", -1), "\n\n") {
-									comment.WriteString("\n")
-								}
-							}
-						}
-
-						//add comment
-						comment.WriteString(extractTokenText(existingCode, oldToken))
-
-						//check next line empty
-						if !isTrailing && oldToken.StartPosition().Line < len(existingCodeLines) {
-							if len(strings.Trim(existingCodeLines[oldToken.StartPosition().Line], " \t")) == 0 {
-								//leading comment
-								if len(oldLine) == 0 {
-									comment.WriteString("\n")
-								}
-							}
-						}
-
-						//trailing comment
-						if isTrailing {
-							//space before trailing comment
-							result.WriteString(" ")
-							result.WriteString(comment.String())
-							comment.Reset()
-						} else {
-							comment.WriteString("\n")
-						}
-
-					case lexer.TokenBlockCommentContent:
-						commentString := extractTokenText(existingCode, oldToken)
-						comment.WriteString("/*")
-						comment.WriteString(commentString)
-						comment.WriteString("*/")
-
-						if oldToken.StartPos.Line < oldToken.EndPos.Line {
-							//multiline block comment
-							comment.WriteString("\n\n")
-						}
-					}
-
-				}
-
-				if oldToken.Type == newToken.Type || oldToken.Is(lexer.TokenEOF) {
-					break
-				}
-			}
+		options.baseline, err = loadBaseline(*baselineFlag, *updateBaselineFlag)
+		if err != nil {
+			fatal(err)
 		}
+	}
 
-		if oldToken.Is(lexer.TokenEOF) && newToken.Is(lexer.TokenEOF) {
-			//add remaining comments and finish
-			result.WriteString(comment.String())
-			break
+	if *linesFlag != "" {
+		r, err := format.ParseLineRange(*linesFlag)
+		if err != nil {
+			fatal(err)
 		}
+		options.lines = []format.LineRange{r}
+	}
 
-		//add spaces without existing indent in case we put comment
-		spacesString := spaces.String()
-		existingIndent := len(spacesString) - (strings.LastIndex(spacesString, "\n") + 1)
-		result.WriteString(strings.TrimRight(spacesString, " "))
-		spaces.Reset()
-
-		if comment.Len() > 0 {
-			//add existing comment (leading), pad to next element
-			padding := strings.Repeat(" ", newToken.StartPosition().Column)
-			result.WriteString(indent.String(padding, comment.String()))
-			result.WriteString(padding)
-			comment.Reset()
-		} else {
-			result.WriteString(strings.Repeat(" ", existingIndent))
+	if *serveSocketFlag != "" {
+		if err := serveFilter(*serveSocketFlag, options); err != nil {
+			fatal(err)
 		}
-
-		//add prettified code
-		result.WriteString(extractTokenText(prettyCode, newToken))
-
+		return
 	}
 
-	if !tabs {
-		return result.String()
+	if *watchFlag {
+		if flag.NArg() == 0 {
+			fatal(errors.New("-watch requires files or directories"))
+		}
+		watch(flag.Args(), options)
+		return
 	}
 
-	tabbedResult := &strings.Builder{}
-	for _, line := range strings.Split(result.String(), "\n") {
-		newline := line
-		for {
-			if strings.Index(strings.TrimLeft(newline, "\t"), strings.Repeat(" ", 4)) == -1 {
-				break
-			}
-			newline = strings.Replace(newline, strings.Repeat(" ", 4), "\t", 1)
+	filenames := flag.Args()
+	if options.write && slices.Contains(filenames, stdinFilename) {
+		fatal(errors.New("-w can not write the code read from stdin"))
+	}
+	if options.diffBase != "" && len(filenames) == 0 {
+		var err error
+		filenames, err = changedFiles(options.diffBase)
+		if err != nil {
+			fatal(err)
 		}
-		tabbedResult.WriteString(newline)
-		tabbedResult.WriteString("\n")
 	}
 
-	return tabbedResult.String()
-}
-
-func main() {
-	columnsFlag := flag.Int("c", 80, "columns")
-	portFlag := flag.Int("port", 9090, "port")
-	tabsFlag := flag.Bool("t", false, "tabs")
-
-	flag.Parse()
-
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		_, _ = w.Write([]byte(page))
-	})
-
-	http.HandleFunc("/pretty", func(w http.ResponseWriter, r *http.Request) {
-		var req Request
-
-		err := json.NewDecoder(r.Body).Decode(&req)
+	if len(filenames) > 0 || options.diffBase != "" {
+		filenames, err := cadenceFiles(filenames)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			fatal(err)
+		}
+		if *fitFlag > 0 {
+			if err := fitFiles(filenames, *fitFlag, options, *fitTimeoutFlag); err != nil {
+				fatal(err)
+			}
 			return
 		}
-		_, _ = w.Write([]byte(prettyCode(req.Code, req.MaxLineLength, false)))
-	})
-
-	if filename := flag.Arg(0); filename != "" {
-		code, err := os.ReadFile(filename)
-		if err != nil {
-			panic(err)
+		stats := formatFiles(filenames, options, *jobsFlag)
+		if options.baseline != nil {
+			if err := options.baseline.save(); err != nil {
+				fatal(err)
+			}
 		}
-		fmt.Println(prettyCode(string(code), *columnsFlag, *tabsFlag))
+		if *statsFlag {
+			fmt.Fprintln(os.Stderr, stats)
+		}
+		//os.Exit does not run the deferred functions
+		stopProfiles()
+		os.Exit(stats.exitCode())
 
 	} else {
-		ln, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", *portFlag))
-		if err != nil {
-			panic(err)
+		if err := serverFlags.serve(); err != nil {
+			fatal(err)
 		}
-		log.Printf("Listening on http://%s/", ln.Addr().String())
-		var srv http.Server
-		_ = srv.Serve(ln)
 	}
 
 }
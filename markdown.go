@@ -0,0 +1,146 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/onflow/cadence/runtime/parser"
+
+	"cadencefmt/format"
+)
+
+// markdownFence matches the opening fence of a code block, indented by up to three spaces,
+// with backticks or tildes, and its info string, which has no backticks after backticks
+var markdownFence = regexp.MustCompile("^( {0,3})(?:(`{3,})([^`]*)|(~{3,})(.*))$")
+
+// embeddedFormatter returns the formatter of the cadence code embedded in the file,
+// or nil if the file is cadence code itself
+func embeddedFormatter(filename string) func(text string, options cliOptions) (string, error) {
+	switch filepath.Ext(filename) {
+	case ".md", ".mdx":
+		return formatMarkdown
+	case ".go":
+		return formatGoSource
+	case ".js", ".mjs", ".cjs", ".ts", ".mts", ".cts":
+		return formatJSSource
+	}
+	return nil
+}
+
+// formatMarkdown formats the cadence code blocks of the Markdown text, and keeps the rest as written.
+// Blocks which do not parse, e.g. fragments of code, and blocks without a closing fence are kept as well
+func formatMarkdown(text string, options cliOptions) (string, error) {
+	lines := strings.Split(text, "\n")
+	var result []string
+	for i := 0; i < len(lines); i++ {
+		result = append(result, lines[i])
+		match := markdownFence.FindStringSubmatch(lines[i])
+		if match == nil {
+			continue
+		}
+		indent, fence, info := match[1], match[2]+match[4], match[3]+match[5]
+
+		//blocks run to the closing fence, or the end of the text, and fences in them are text
+		end := i + 1
+		for end < len(lines) && !closesFence(lines[end], fence) {
+			end++
+		}
+		if end == len(lines) {
+			result = append(result, lines[i+1:]...)
+			break
+		}
+
+		block := lines[i+1 : end]
+		if isCadenceInfo(info) {
+			formatted, err := formatBlock(block, indent, options)
+			if err != nil {
+				return "", err
+			}
+			block = formatted
+		}
+		result = append(result, block...)
+		result = append(result, lines[end])
+		i = end
+	}
+	return strings.Join(result, "\n"), nil
+}
+
+// isCadenceInfo reports if the info string of a fence is of cadence code,
+// its first word is cadence, e.g. "cadence" or "cadence title=A.cdc", but not "cadence-json"
+func isCadenceInfo(info string) bool {
+	words := strings.Fields(info)
+	return len(words) > 0 && words[0] == "cadence"
+}
+
+// closesFence reports if the line is the closing fence of a block opened with the fence
+func closesFence(line string, fence string) bool {
+	trimmed := strings.TrimRight(line, " \t")
+	marker := strings.TrimLeft(trimmed, " ")
+	return len(trimmed)-len(marker) <= 3 &&
+		len(marker) >= len(fence) &&
+		strings.Trim(marker, fence[:1]) == ""
+}
+
+// formatBlock formats the lines of a code block, indented like its fence
+func formatBlock(block []string, indent string, options cliOptions) ([]string, error) {
+	code := make([]string, len(block))
+	for i, line := range block {
+		code[i] = strings.TrimPrefix(line, indent)
+	}
+
+	if options.whitespaceOnly {
+		return reindent(cleanWhitespace(strings.Join(code, "\n"), options), indent), nil
+	}
+
+	formatted, err := formatVerified(strings.Join(code, "\n"), options)
+	var parseErr parser.Error
+	if errors.As(err, &parseErr) {
+		return block, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if options.migrate {
+		formatted, err = format.Migrate(formatted)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return reindent(formatted, indent), nil
+}
+
+// reindent returns the lines of the formatted code, indented like the fence of the block
+func reindent(formatted string, indent string) []string {
+	formatted = strings.TrimSuffix(formatted, "\n")
+	if formatted == "" {
+		return nil
+	}
+
+	lines := strings.Split(formatted, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = indent + line
+		}
+	}
+	return lines
+}
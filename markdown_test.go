@@ -0,0 +1,96 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"testing"
+
+	"cadencefmt/format"
+)
+
+func TestFormatMarkdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{
+			name:     "backticks",
+			text:     "```cadence\npub fun  a() {}\n```\n",
+			expected: "```cadence\npub fun a() {}\n```\n",
+		},
+		{
+			name:     "tildes",
+			text:     "~~~cadence\npub fun  a() {}\n~~~\n",
+			expected: "~~~cadence\npub fun a() {}\n~~~\n",
+		},
+		{
+			name:     "info after the language",
+			text:     "```cadence title=A.cdc\npub fun  a() {}\n```\n",
+			expected: "```cadence title=A.cdc\npub fun a() {}\n```\n",
+		},
+		{
+			name:     "indented fence",
+			text:     "  ```cadence\n  pub fun  a() {}\n  ```\n",
+			expected: "  ```cadence\n  pub fun a() {}\n  ```\n",
+		},
+		{
+			name:     "other language starting with cadence",
+			text:     "```cadence-json\n{ \"x\":  1 }\n```\n",
+			expected: "```cadence-json\n{ \"x\":  1 }\n```\n",
+		},
+		{
+			name:     "cadence block in a longer fence",
+			text:     "````markdown\n```cadence\npub fun  a() {}\n```\n````\n",
+			expected: "````markdown\n```cadence\npub fun  a() {}\n```\n````\n",
+		},
+		{
+			name:     "cadence block in a tilde fence",
+			text:     "~~~\n```cadence\npub fun  a() {}\n```\n~~~\n",
+			expected: "~~~\n```cadence\npub fun  a() {}\n```\n~~~\n",
+		},
+		{
+			name:     "block after another block",
+			text:     "```go\nx\n```\n\n```cadence\npub fun  a() {}\n```\n",
+			expected: "```go\nx\n```\n\n```cadence\npub fun a() {}\n```\n",
+		},
+		{
+			name:     "unclosed block",
+			text:     "```cadence\npub fun  a() {}\n",
+			expected: "```cadence\npub fun  a() {}\n",
+		},
+		{
+			name:     "fragment",
+			text:     "```cadence\nlet x =\n```\n",
+			expected: "```cadence\nlet x =\n```\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := formatMarkdown(test.text, cliOptions{Options: format.DefaultOptions})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, actual)
+			}
+		})
+	}
+}
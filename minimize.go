@@ -0,0 +1,187 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/parser"
+
+	"cadencefmt/format"
+)
+
+// runMinimize shrinks a file on which the formatter panics, or fails a safety check,
+// to a small input failing the same check, for bug reports
+func runMinimize(args []string) {
+	flags := flag.NewFlagSet("minimize", flag.ExitOnError)
+	optionFlags := addOptionFlags(flags)
+	verifyFlag := flags.Bool("verify", false, "also check that the formatted code has all tokens of the code")
+	astCheckFlag := flags.Bool("ast-check", true, "check that the formatted code has the same syntax tree as the code")
+	outFlag := flags.String("o", "", "write the minimized input to this file instead of stdout")
+	_ = flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		fatal(errors.New("usage: cadencefmt minimize <file>"))
+	}
+	filename := flags.Arg(0)
+
+	options, err := optionFlags.cliOptions()
+	if err != nil {
+		fatal(err)
+	}
+	options.verifyTokens = *verifyFlag
+	options.skipASTCheck = !*astCheckFlag
+
+	code, err := readCode(filename, 0)
+	if err != nil {
+		fatal(err)
+	}
+
+	failure, ok := minimizeCheck(string(code), options)
+	if !ok {
+		fatal(fmt.Errorf("%s does not parse", options.displayName(filename)))
+	}
+	if failure == nil {
+		fatal(fmt.Errorf("%s formats without failures", options.displayName(filename)))
+	}
+
+	minimized := minimize(string(code), func(candidate string) bool {
+		f, ok := minimizeCheck(candidate, options)
+		return ok && f != nil && f.Check == failure.Check
+	})
+
+	if *outFlag != "" {
+		err = os.WriteFile(*outFlag, []byte(minimized), 0644)
+	} else {
+		_, err = os.Stdout.WriteString(minimized)
+	}
+	if err != nil {
+		fatal(err)
+	}
+	fmt.Fprintf(
+		os.Stderr,
+		"the %s check fails on %d of %d lines: %s\n",
+		failure.Check,
+		len(splitLines(minimized)),
+		len(splitLines(string(code))),
+		firstLine(failure.Message),
+	)
+}
+
+// minimizeCheck formats the code and runs the safety checks the options enable.
+// It reports false if the code is not valid, e.g. it does not parse
+func minimizeCheck(code string, options cliOptions) (failure *format.SafetyError, ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			failure = &format.SafetyError{Check: "panic", Message: fmt.Sprint(r)}
+			ok = true
+		}
+	}()
+
+	result, err := format.Source(code, options.Options)
+	if err == nil {
+		err = checkFormatted(code, result, options)
+	}
+	if errors.As(err, &failure) {
+		return failure, true
+	}
+	return nil, err == nil
+}
+
+// removableSpan is the range of a declaration or statement,
+// and the number of declarations and statements containing it
+type removableSpan struct {
+	start int
+	end   int
+	depth int
+}
+
+// removableSpans returns the ranges of the declarations and statements of the program
+func removableSpans(program *ast.Program) []removableSpan {
+	var spans []removableSpan
+
+	seen := map[ast.Element]bool{}
+	var visit func(element ast.Element, depth int)
+	visit = func(element ast.Element, depth int) {
+		if element == nil || reflect.ValueOf(element).IsNil() || seen[element] {
+			return
+		}
+		seen[element] = true
+
+		_, isDeclaration := element.(ast.Declaration)
+		_, isStatement := element.(ast.Statement)
+		if isDeclaration || isStatement {
+			spans = append(spans, removableSpan{
+				start: element.StartPosition().Offset,
+				end:   element.EndPosition(nil).Offset + 1,
+				depth: depth,
+			})
+			depth++
+		}
+		if block, ok := element.(*ast.FunctionBlock); ok {
+			visit(block.Block, depth)
+		}
+		element.Walk(func(child ast.Element) {
+			visit(child, depth)
+		})
+	}
+	visit(program, 0)
+
+	return spans
+}
+
+// minimizeDeclarations removes declarations and statements of the code as long as it keeps failing,
+// the outer ones first, so whole declarations go before their members
+func minimizeDeclarations(code string, failing func(string) bool) string {
+	for depth := 0; ; depth++ {
+		program, err := parser.ParseProgram(nil, []byte(code), parser.Config{})
+		if err != nil {
+			return code
+		}
+
+		var spans []removableSpan
+		deepest := -1
+		for _, span := range removableSpans(program) {
+			deepest = max(deepest, span.depth)
+			if span.depth == depth {
+				spans = append(spans, span)
+			}
+		}
+		if depth > deepest {
+			return code
+		}
+
+		//remove from the end, so the offsets of earlier spans stay valid
+		for i := len(spans) - 1; i >= 0; i-- {
+			span := spans[i]
+			if span.end > len(code) {
+				continue
+			}
+			candidate := code[:span.start] + code[span.end:]
+			if failing(candidate) {
+				code = candidate
+			}
+		}
+	}
+}
@@ -0,0 +1,120 @@
+//go:build !noui
+
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+var errBusy = errors.New("the server is busy, try again later")
+
+// workerPool limits the requests formatted at once.
+// Requests beyond the limit wait in a queue, and are rejected
+// when the queue is full or they waited too long,
+// so a burst of large requests does not parse all of them at once
+type workerPool struct {
+	workers chan struct{}
+	//queue holds a token for each waiting request
+	queue chan struct{}
+	wait  time.Duration
+}
+
+// newWorkerPool returns a pool of the number of workers,
+// or nil if the number is zero, which disables the limit.
+// A wait of zero lets requests wait in the queue until a worker is free
+func newWorkerPool(workers int, queue int, wait time.Duration) *workerPool {
+	if workers <= 0 {
+		return nil
+	}
+	return &workerPool{
+		workers: make(chan struct{}, workers),
+		queue:   make(chan struct{}, max(queue, 0)),
+		wait:    wait,
+	}
+}
+
+func (p *workerPool) wrap(next http.HandlerFunc) http.HandlerFunc {
+	if p == nil {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		release, err := p.acquire(r.Context())
+		if err != nil {
+			//the client is gone if the context is done
+			if errors.Is(err, errBusy) {
+				w.Header().Set("Retry-After", "1")
+				writeError(w, http.StatusTooManyRequests, err)
+			}
+			return
+		}
+		defer release()
+		next(w, r)
+	}
+}
+
+// acquire takes a worker, waiting in the queue if all are busy,
+// and returns the function giving it back.
+// It fails with errBusy if the queue is full or the wait is over,
+// and with the error of the context if it is done first
+func (p *workerPool) acquire(ctx context.Context) (func(), error) {
+	if p == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case p.workers <- struct{}{}:
+		return p.release, nil
+	default:
+	}
+
+	select {
+	case p.queue <- struct{}{}:
+	default:
+		return nil, errBusy
+	}
+	defer func() {
+		<-p.queue
+	}()
+
+	var timeout <-chan time.Time
+	if p.wait > 0 {
+		timer := time.NewTimer(p.wait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case p.workers <- struct{}{}:
+		return p.release, nil
+	case <-timeout:
+		return nil, errBusy
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (p *workerPool) release() {
+	<-p.workers
+}
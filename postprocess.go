@@ -0,0 +1,150 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// postProcessor adapts the final formatted text
+type postProcessor func(code string) (string, error)
+
+// postProcessorConfig configures a post-processing step.
+// Which of the fields are used depends on the type
+type postProcessorConfig struct {
+	Type    string   `json:"type"`
+	Text    string   `json:"text,omitempty"`
+	Indent  string   `json:"indent,omitempty"`
+	Command []string `json:"command,omitempty"`
+}
+
+// postProcessorTypes are the available post-processors, by type
+var postProcessorTypes = map[string]func(config postProcessorConfig) (postProcessor, error){
+	"prepend": newPrependPostProcessor,
+	"append":  newAppendPostProcessor,
+	"indent":  newIndentPostProcessor,
+	"exec":    newExecPostProcessor,
+}
+
+func newPostProcessors(configs []postProcessorConfig) ([]postProcessor, error) {
+	processors := make([]postProcessor, 0, len(configs))
+	for _, config := range configs {
+		newProcessor, ok := postProcessorTypes[config.Type]
+		if !ok {
+			return nil, fmt.Errorf("unknown post-processor type %q", config.Type)
+		}
+		processor, err := newProcessor(config)
+		if err != nil {
+			return nil, fmt.Errorf("post-processor %q: %w", config.Type, err)
+		}
+		processors = append(processors, processor)
+	}
+	return processors, nil
+}
+
+func hasExecPostProcessor(configs []postProcessorConfig) bool {
+	for _, config := range configs {
+		if config.Type == "exec" {
+			return true
+		}
+	}
+	return false
+}
+
+func withoutExecPostProcessors(configs []postProcessorConfig) []postProcessorConfig {
+	kept := make([]postProcessorConfig, 0, len(configs))
+	for _, config := range configs {
+		if config.Type != "exec" {
+			kept = append(kept, config)
+		}
+	}
+	return kept
+}
+
+func postProcess(code string, processors []postProcessor) (string, error) {
+	for _, processor := range processors {
+		var err error
+		code, err = processor(code)
+		if err != nil {
+			return "", err
+		}
+	}
+	return code, nil
+}
+
+// newPrependPostProcessor inserts text at the top, e.g. a header or build tags
+func newPrependPostProcessor(config postProcessorConfig) (postProcessor, error) {
+	return func(code string) (string, error) {
+		if strings.HasPrefix(code, config.Text) {
+			return code, nil
+		}
+		return config.Text + code, nil
+	}, nil
+}
+
+// newAppendPostProcessor adds text at the end, e.g. a generated-by trailer
+func newAppendPostProcessor(config postProcessorConfig) (postProcessor, error) {
+	return func(code string) (string, error) {
+		if strings.HasSuffix(code, config.Text) {
+			return code, nil
+		}
+		return code + config.Text, nil
+	}, nil
+}
+
+// newIndentPostProcessor replaces each level of indentation
+func newIndentPostProcessor(config postProcessorConfig) (postProcessor, error) {
+	if config.Indent == "" {
+		return nil, fmt.Errorf("missing indent")
+	}
+
+	return func(code string) (string, error) {
+		lines := strings.Split(code, "\n")
+		for i, line := range lines {
+			content := strings.TrimLeft(line, " \t")
+			indentation := line[:len(line)-len(content)]
+			levels := len(strings.ReplaceAll(indentation, "\t", "    ")) / 4
+			lines[i] = strings.Repeat(config.Indent, levels) + content
+		}
+		return strings.Join(lines, "\n"), nil
+	}, nil
+}
+
+// newExecPostProcessor pipes the code through an external command
+func newExecPostProcessor(config postProcessorConfig) (postProcessor, error) {
+	if len(config.Command) == 0 {
+		return nil, fmt.Errorf("missing command")
+	}
+
+	return func(code string) (string, error) {
+		var stdout, stderr bytes.Buffer
+		cmd := exec.Command(config.Command[0], config.Command[1:]...)
+		cmd.Stdin = strings.NewReader(code)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+
+		if err := cmd.Run(); err != nil {
+			return "", fmt.Errorf("%s: %w: %s", config.Command[0], err, strings.TrimSpace(stderr.String()))
+		}
+		return stdout.String(), nil
+	}, nil
+}
@@ -0,0 +1,69 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"cadencefmt/format"
+)
+
+// profileDir is a directory of named configurations, <name>.json,
+// so teams sharing a server can each use their style
+// without sending all options in every request
+type profileDir string
+
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// load reads the configuration of the profile.
+// The file is read for every request, so changes apply without a restart
+func (dir profileDir) load(name string) (config, error) {
+	if dir == "" {
+		return config{}, fmt.Errorf("unknown profile %q, the server has no profiles", name)
+	}
+	//names must not escape the directory
+	if !profileNamePattern.MatchString(name) {
+		return config{}, fmt.Errorf("invalid profile name %q", name)
+	}
+
+	path := filepath.Join(string(dir), name+".json")
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return config{}, fmt.Errorf("unknown profile %q", name)
+	}
+	return loadConfig(path)
+}
+
+// options returns the formatting options of the request:
+// the ones given in the request, then the ones of its profile, if any,
+// then the defaults
+func (dir profileDir) options(o RequestOptions) (format.Options, error) {
+	var base config
+	if o.Profile != "" {
+		var err error
+		base, err = dir.load(o.Profile)
+		if err != nil {
+			return format.Options{}, err
+		}
+	}
+	return o.override(base).Options()
+}
@@ -0,0 +1,117 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+)
+
+// projectSections are the sections of flow.json which declare the paths of Cadence files
+var projectSections = []string{"contracts", "transactions", "scripts"}
+
+// runProject formats the contracts, transactions and scripts declared in flow.json
+func runProject(args []string) {
+	flags := flag.NewFlagSet("project", flag.ExitOnError)
+	optionFlags := addOptionFlags(flags)
+	configFlag := flags.String("f", "flow.json", "the project configuration")
+	writeFlag := flags.Bool("w", false, "write the results to the files instead of stdout")
+	checkFlag := flags.Bool("check", false, "list files which are not formatted and exit with 1")
+	jobsFlag := flags.Int("jobs", runtime.GOMAXPROCS(0), "number of files formatted in parallel")
+	_ = flags.Parse(args)
+
+	options, err := optionFlags.cliOptions()
+	if err != nil {
+		fatal(err)
+	}
+	options.write = *writeFlag
+	options.check = *checkFlag
+
+	filenames, err := projectFiles(*configFlag)
+	if err != nil {
+		fatal(err)
+	}
+
+	stats := formatFiles(filenames, options, *jobsFlag)
+	os.Exit(stats.exitCode())
+}
+
+// projectFiles returns the Cadence files declared in the project configuration,
+// relative to the directory of the configuration.
+// Entries are either a path, or an object with a source path, like contracts with aliases;
+// contracts which only have aliases are skipped
+func projectFiles(configFilename string) ([]string, error) {
+	data, err := os.ReadFile(configFilename)
+	if err != nil {
+		return nil, err
+	}
+	var config map[string]json.RawMessage
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("%s: %w", configFilename, err)
+	}
+
+	dir := filepath.Dir(configFilename)
+	seen := map[string]bool{}
+	var filenames []string
+	for _, section := range projectSections {
+		if config[section] == nil {
+			continue
+		}
+		var entries map[string]json.RawMessage
+		if err := json.Unmarshal(config[section], &entries); err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", configFilename, section, err)
+		}
+		for name, entry := range entries {
+			source, err := projectSource(entry)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s %s: %w", configFilename, section, name, err)
+			}
+			if source == "" {
+				continue
+			}
+			filename := filepath.Join(dir, filepath.FromSlash(source))
+			if !seen[filename] {
+				seen[filename] = true
+				filenames = append(filenames, filename)
+			}
+		}
+	}
+	sort.Strings(filenames)
+	return filenames, nil
+}
+
+// projectSource returns the source path of an entry of the project configuration
+func projectSource(entry json.RawMessage) (string, error) {
+	var source string
+	if err := json.Unmarshal(entry, &source); err == nil {
+		return source, nil
+	}
+	var object struct {
+		Source string `json:"source"`
+	}
+	if err := json.Unmarshal(entry, &object); err != nil {
+		return "", fmt.Errorf("expected a path or an object with a source")
+	}
+	return object.Source, nil
+}
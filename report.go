@@ -0,0 +1,222 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/parser"
+)
+
+// outputFormats are the formats of -output
+const outputFormats = "text (default) or json for a JSON record per file"
+
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+	severityInfo    = "info"
+)
+
+// diagnostic is a message about a file, or a position in it,
+// with lines and columns starting at 1, and 0 if unknown
+type diagnostic struct {
+	Severity string `json:"severity"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Message  string `json:"message"`
+}
+
+// text is the diagnostic as file:line:column: message, the form editors jump to.
+// Warnings are prefixed with warning:
+func (d diagnostic) text(filename string) string {
+	location := filename
+	if d.Line > 0 {
+		location += fmt.Sprintf(":%d", d.Line)
+	}
+	if d.Column > 0 {
+		location += fmt.Sprintf(":%d", d.Column)
+	}
+	text := location + ": " + d.Message
+	if d.Severity == severityWarning {
+		text = "warning: " + text
+	}
+	return text
+}
+
+// parseDiagnostics returns the parse errors of the error, with the note appended,
+// and reports if the error is a parse error
+func parseDiagnostics(err error, note string) ([]diagnostic, bool) {
+	var parseErr parser.Error
+	if !errors.As(err, &parseErr) {
+		return nil, false
+	}
+	var diagnostics []diagnostic
+	for _, childErr := range parseErr.Errors {
+		d := diagnostic{Severity: severityError, Line: 1, Message: childErr.Error() + note}
+		if positioned, ok := childErr.(ast.HasPosition); ok {
+			position := positioned.StartPosition()
+			d.Line = position.Line
+			d.Column = position.Column + 1
+		}
+		diagnostics = append(diagnostics, d)
+	}
+	return diagnostics, true
+}
+
+// fileReport is the outcome of formatting a file, as printed by -output json
+type fileReport struct {
+	File    string `json:"file"`
+	Changed bool   `json:"changed"`
+	// LinesChanged are the lines formatting inserts or deletes
+	LinesChanged int          `json:"linesChanged"`
+	Diagnostics  []diagnostic `json:"diagnostics"`
+	DurationMs   float64      `json:"durationMs"`
+	// sizeReport is the size of the formatted code, with -budget
+	*sizeReport
+
+	start time.Time
+	// text is where diagnostics are printed as they are added, in text mode
+	text io.Writer
+	// quiet only prints errors
+	quiet bool
+}
+
+// newFileReport starts the report of the file.
+// In text mode, diagnostics are printed to stderr, only the errors if quiet, otherwise they are only collected
+func newFileReport(filename string, jsonOutput bool, quiet bool, stderr io.Writer) *fileReport {
+	r := &fileReport{
+		File:        filename,
+		Diagnostics: []diagnostic{},
+		start:       time.Now(),
+		quiet:       quiet,
+	}
+	if !jsonOutput {
+		r.text = stderr
+	}
+	return r
+}
+
+func (r *fileReport) add(diagnostics ...diagnostic) {
+	r.Diagnostics = append(r.Diagnostics, diagnostics...)
+	if r.text == nil {
+		return
+	}
+	for _, d := range diagnostics {
+		if r.quiet && d.Severity != severityError {
+			continue
+		}
+		fmt.Fprintln(r.text, d.text(r.File))
+	}
+}
+
+// progress is the line -verbose prints for the file once it is formatted
+func (r *fileReport) progress() string {
+	state := "unchanged"
+	if r.Changed {
+		state = fmt.Sprintf("changed, %d lines", r.LinesChanged)
+	}
+	return fmt.Sprintf("%s: %s in %s", r.File, state, time.Since(r.start).Round(time.Microsecond))
+}
+
+// write prints the report as a JSON record on its own line
+func (r *fileReport) write(w io.Writer) error {
+	r.DurationMs = float64(time.Since(r.start).Microseconds()) / 1000
+	return json.NewEncoder(w).Encode(r)
+}
+
+// runStats summarize a run over many files, as printed by -stats
+type runStats struct {
+	files        int
+	changed      int
+	linesChanged int
+	// notFormatted are the files check mode reports
+	notFormatted  int
+	parseFailures int
+	// failures are the errors of the files which could not be formatted, other than parse errors
+	failures []error
+	duration time.Duration
+}
+
+// add counts the report of a file, and the error formatting it returned
+func (s *runStats) add(report *fileReport, err error) {
+	s.files++
+	if report.Changed {
+		s.changed++
+		s.linesChanged += report.LinesChanged
+	}
+	if errors.Is(err, errNotFormatted) {
+		s.notFormatted++
+	}
+	if errors.Is(err, errParseFailed) {
+		s.parseFailures++
+	}
+	if err != nil && !errors.Is(err, errNotFormatted) && !errors.Is(err, errParseFailed) {
+		s.failures = append(s.failures, err)
+	}
+}
+
+// exitCode is the exit code of the run, once all files are formatted
+func (s runStats) exitCode() int {
+	code := exitOK
+	for _, err := range s.failures {
+		code = max(code, exitCode(err))
+	}
+	if code != exitOK {
+		return code
+	}
+	if s.parseFailures > 0 {
+		return exitError
+	}
+	if s.notFormatted > 0 {
+		return exitNotFormatted
+	}
+	return exitOK
+}
+
+func (s runStats) String() string {
+	return fmt.Sprintf(
+		"files scanned: %d, changed: %d, lines changed: %d, parse failures: %d, errors: %d, time: %s",
+		s.files,
+		s.changed,
+		s.linesChanged,
+		s.parseFailures,
+		len(s.failures),
+		s.duration.Round(time.Millisecond),
+	)
+}
+
+// summary is the table printed at the end of runs in which files failed,
+// with the error of each file which could not be formatted
+func (s runStats) summary() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-16s %d\n", "files", s.files)
+	fmt.Fprintf(&b, "%-16s %d\n", "succeeded", s.files-s.parseFailures-len(s.failures))
+	fmt.Fprintf(&b, "%-16s %d\n", "parse failures", s.parseFailures)
+	fmt.Fprintf(&b, "%-16s %d\n", "errors", len(s.failures))
+	for _, err := range s.failures {
+		fmt.Fprintf(&b, "  %s\n", firstLine(err.Error()))
+	}
+	return b.String()
+}
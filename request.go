@@ -0,0 +1,122 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"cadencefmt/format"
+)
+
+// RequestOptions are the formatting options of API requests
+type RequestOptions struct {
+	MaxLineLength      int    `json:"maxLineLength"`
+	Tabs               bool   `json:"tabs"`
+	Comments           string `json:"comments,omitempty"`
+	EmptyBodies        string `json:"emptyBodies,omitempty"`
+	CommentStyle       string `json:"commentStyle,omitempty"`
+	ParameterWrap      string `json:"parameterWrap,omitempty"`
+	ReturnType         string `json:"returnType,omitempty"`
+	EnumCases          string `json:"enumCases,omitempty"`
+	OperatorPosition   string `json:"operatorPosition,omitempty"`
+	Braces             string `json:"braces,omitempty"`
+	LineEndings        string `json:"lineEndings,omitempty"`
+	GroupFields        bool   `json:"groupFields,omitempty"`
+	AlignComments      bool   `json:"alignComments,omitempty"`
+	AlignValues        bool   `json:"alignValues,omitempty"`
+	AlignParameters    bool   `json:"alignParameters,omitempty"`
+	ReflowDocs         bool   `json:"reflowDocs,omitempty"`
+	MaxBlankLines      *int   `json:"maxBlankLines,omitempty"`
+	SortImports        bool   `json:"sortImports,omitempty"`
+	CleanImports       bool   `json:"cleanImports,omitempty"`
+	NormalizeEscapes   bool   `json:"normalizeEscapes,omitempty"`
+	TrailingCommas     bool   `json:"trailingCommas,omitempty"`
+	OneLineFunctions   bool   `json:"oneLineFunctions,omitempty"`
+	OneLineCases       bool   `json:"oneLineCases,omitempty"`
+	ExactBlankLines    bool   `json:"exactBlankLines,omitempty"`
+	ReorderMembers     bool   `json:"reorderMembers,omitempty"`
+	KeepBOM            bool   `json:"keepBOM,omitempty"`
+	CollectionElements int    `json:"collectionElements,omitempty"`
+	CollectionWidth    int    `json:"collectionWidth,omitempty"`
+	// ImportGroups and CoreContracts replace the ones of the profile
+	ImportGroups  []string `json:"importGroups,omitempty"`
+	CoreContracts []string `json:"coreContracts,omitempty"`
+	// Rules enable or disable layout rules by name,
+	// over the ones of the profile
+	Rules map[string]bool `json:"rules,omitempty"`
+	// Style enables or disables style rules by name,
+	// over the ones of the profile
+	Style map[string]bool `json:"style,omitempty"`
+	// Profile is the name of a server side configuration,
+	// which provides the options not given in the request
+	Profile string `json:"profile,omitempty"`
+}
+
+// Options returns the formatting options, with defaults for the ones not given.
+// Profiles are only available on the server, see profileDir.options
+func (o RequestOptions) Options() (format.Options, error) {
+	return profileDir("").options(o)
+}
+
+// override returns the configuration with the options given in the request
+func (o RequestOptions) override(base config) config {
+	if o.MaxLineLength > 0 {
+		base.MaxLineLength = o.MaxLineLength
+	}
+	base.Tabs = base.Tabs || o.Tabs
+	base.GroupFields = base.GroupFields || o.GroupFields
+	base.AlignComments = base.AlignComments || o.AlignComments
+	base.AlignValues = base.AlignValues || o.AlignValues
+	base.AlignParameters = base.AlignParameters || o.AlignParameters
+	base.ReflowDocs = base.ReflowDocs || o.ReflowDocs
+	base.SortImports = base.SortImports || o.SortImports
+	base.CleanImports = base.CleanImports || o.CleanImports
+	base.NormalizeEscapes = base.NormalizeEscapes || o.NormalizeEscapes
+	base.TrailingCommas = base.TrailingCommas || o.TrailingCommas
+	base.OneLineFunctions = base.OneLineFunctions || o.OneLineFunctions
+	base.OneLineCases = base.OneLineCases || o.OneLineCases
+	base.ExactBlankLines = base.ExactBlankLines || o.ExactBlankLines
+	base.ReorderMembers = base.ReorderMembers || o.ReorderMembers
+	base.KeepBOM = base.KeepBOM || o.KeepBOM
+	if o.MaxBlankLines != nil {
+		base.MaxBlankLines = o.MaxBlankLines
+	}
+	if o.CollectionElements > 0 {
+		base.CollectionElements = o.CollectionElements
+	}
+	if o.CollectionWidth > 0 {
+		base.CollectionWidth = o.CollectionWidth
+	}
+	base.Comments = firstNonEmpty(o.Comments, base.Comments)
+	base.EmptyBodies = firstNonEmpty(o.EmptyBodies, base.EmptyBodies)
+	base.CommentStyle = firstNonEmpty(o.CommentStyle, base.CommentStyle)
+	base.ParameterWrap = firstNonEmpty(o.ParameterWrap, base.ParameterWrap)
+	base.ReturnType = firstNonEmpty(o.ReturnType, base.ReturnType)
+	base.EnumCases = firstNonEmpty(o.EnumCases, base.EnumCases)
+	base.OperatorPosition = firstNonEmpty(o.OperatorPosition, base.OperatorPosition)
+	base.Braces = firstNonEmpty(o.Braces, base.Braces)
+	base.LineEndings = firstNonEmpty(o.LineEndings, base.LineEndings)
+	if o.ImportGroups != nil {
+		base.ImportGroups = o.ImportGroups
+	}
+	if o.CoreContracts != nil {
+		base.CoreContracts = o.CoreContracts
+	}
+	base.Rules = mergedRules(base.Rules, o.Rules)
+	base.Style = mergedRules(base.Style, o.Style)
+	return base
+}
@@ -0,0 +1,66 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"cadencefmt/format"
+)
+
+// runRules lists the layout and style rules, and if they are enabled in the configuration
+func runRules(args []string) {
+	if len(args) == 0 || args[0] != "list" {
+		fatal(errors.New("usage: cadencefmt rules list [-config file]"))
+	}
+
+	flags := flag.NewFlagSet("rules list", flag.ExitOnError)
+	configFlag := flags.String("config", "", "configuration file (default: nearest "+configFilename+")")
+	_ = flags.Parse(args[1:])
+
+	cfg, err := loadConfig(*configFlag)
+	if err != nil {
+		fatal(err)
+	}
+	options, err := cfg.Options()
+	if err != nil {
+		fatal(err)
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, rule := range format.Rules {
+		state := "enabled"
+		if options.DisabledRules&rule.Rule != 0 {
+			state = "disabled"
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\n", rule.Name, state, rule.Description)
+	}
+	for _, rule := range format.StyleRules {
+		state := "disabled"
+		if options.StyleRules&rule.Rule != 0 {
+			state = "enabled"
+		}
+		fmt.Fprintf(writer, "style.%s\t%s\t%s\n", rule.Name, state, rule.Description)
+	}
+	_ = writer.Flush()
+}
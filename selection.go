@@ -0,0 +1,92 @@
+//go:build !noui
+
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"cadencefmt/format"
+)
+
+// RangeRequest is the body of /pretty/range: the code, with the options,
+// and the range of bytes to format, from StartOffset to EndOffset, which is exclusive
+type RangeRequest struct {
+	Request
+	StartOffset int `json:"startOffset"`
+	EndOffset   int `json:"endOffset"`
+}
+
+// handlePrettyRange returns the handler which formats the smallest declarations enclosing the range,
+// and responds with the edit replacing them, so editors can format a selection
+// without replacing the whole document. The edit is empty, at the start of the range,
+// if the declarations are formatted already
+func handlePrettyRange(profiles profileDir) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req RangeRequest
+		if err := decodeBody(r, &req); err != nil {
+			writeBodyError(w, err)
+			return
+		}
+		if req.StartOffset < 0 || req.StartOffset > req.EndOffset || req.EndOffset > len(req.Code) {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid range %d-%d of %d bytes", req.StartOffset, req.EndOffset, len(req.Code)))
+			return
+		}
+		options, err := profiles.options(req.RequestOptions)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		result, err := format.Lines(req.Code, options, []format.LineRange{offsetLines(req.Code, req.StartOffset, req.EndOffset)}, checkSpan(cliOptions{Options: options}))
+		if err != nil {
+			writeError(w, formatErrorStatus(err), err)
+			return
+		}
+
+		edit := TextEdit{StartOffset: req.StartOffset, EndOffset: req.StartOffset}
+		if result != req.Code {
+			prefix, suffix := commonAffixes(req.Code, result)
+			edit = TextEdit{
+				StartOffset: prefix,
+				EndOffset:   len(req.Code) - suffix,
+				NewText:     result[prefix : len(result)-suffix],
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(edit)
+	}
+}
+
+// offsetLines returns the lines of the range of bytes, which is exclusive,
+// so a selection ending at the start of a line does not include it
+func offsetLines(code string, start int, end int) format.LineRange {
+	if end > start {
+		end--
+	}
+	return format.LineRange{
+		From: strings.Count(code[:start], "\n") + 1,
+		To:   strings.Count(code[:end], "\n") + 1,
+	}
+}
@@ -0,0 +1,666 @@
+//go:build !noui
+
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"cadencefmt/client"
+	"cadencefmt/format"
+)
+
+func init() {
+	commands["selftest"] = runSelftest
+}
+
+// playgroundCheck is a request of the embedded web UI,
+// and the checks of the response the UI relies on
+type playgroundCheck struct {
+	name   string
+	method string
+	path   string
+	header map[string]string
+	body   string
+	// check returns why the response is not what the UI expects, if it is not
+	check func(response *http.Response, body []byte) string
+}
+
+// runSelftest sends the requests of the embedded web UI to the server handler,
+// configured by the same flags as the server, and checks the responses have the shapes the UI reads,
+// so changes of the API cannot silently break the playground
+func runSelftest(args []string) {
+	flags := flag.NewFlagSet("selftest", flag.ExitOnError)
+	serverFlags := addServerFlags(flags)
+	_ = flags.Parse(args)
+
+	server := httptest.NewServer(serverFlags.handler())
+	defer server.Close()
+
+	//the ETag of the first response, sent back by the UI as If-None-Match
+	var etag string
+	//the ID of the shared snippet, which the UI loads from /share/{id}
+	var snippetID string
+
+	checks := []playgroundCheck{
+		{
+			name:   "page",
+			method: http.MethodGet,
+			path:   "/",
+			check: func(response *http.Response, body []byte) string {
+				if response.StatusCode != http.StatusOK {
+					return fmt.Sprintf("status %d", response.StatusCode)
+				}
+				if !strings.Contains(string(body), "fetch('/pretty'") {
+					return "the page does not call /pretty"
+				}
+				return ""
+			},
+		},
+		{
+			name:   "editor",
+			method: http.MethodGet,
+			path:   "/ui/editor.js",
+			check: func(response *http.Response, body []byte) string {
+				if response.StatusCode != http.StatusOK {
+					return fmt.Sprintf("status %d", response.StatusCode)
+				}
+				if !strings.Contains(string(body), "function createEditor(") {
+					return "the editor script does not define createEditor"
+				}
+				return ""
+			},
+		},
+		{
+			name:   "format",
+			method: http.MethodPost,
+			path:   "/pretty",
+			body:   `{"code": "pub fun f(){ return }", "maxLineLength": 80}`,
+			check: func(response *http.Response, body []byte) string {
+				etag = response.Header.Get("ETag")
+				if etag == "" {
+					return "no ETag"
+				}
+				return checkPrettyResponse(response, body, "pub fun f() {\n    return\n}")
+			},
+		},
+		{
+			name:   "unchanged",
+			method: http.MethodPost,
+			path:   "/pretty",
+			header: map[string]string{"If-None-Match": "etag"},
+			body:   `{"code": "pub fun f(){ return }", "maxLineLength": 80}`,
+			check: func(response *http.Response, body []byte) string {
+				if response.StatusCode != http.StatusNotModified {
+					return fmt.Sprintf("status %d, expected 304", response.StatusCode)
+				}
+				return ""
+			},
+		},
+		{
+			name:   "empty code",
+			method: http.MethodPost,
+			path:   "/pretty",
+			body:   `{"code": "", "maxLineLength": 80}`,
+			check: func(response *http.Response, body []byte) string {
+				return checkPrettyResponse(response, body, "")
+			},
+		},
+		{
+			name:   "huge width",
+			method: http.MethodPost,
+			path:   "/pretty",
+			body:   `{"code": "pub fun f(a: Int, b: Int) {}", "maxLineLength": 1000000000}`,
+			check: func(response *http.Response, body []byte) string {
+				return checkPrettyResponse(response, body, "pub fun f(a: Int, b: Int) {}")
+			},
+		},
+		{
+			name:   "no width",
+			method: http.MethodPost,
+			path:   "/pretty",
+			body:   `{"code": "pub fun f() {}", "maxLineLength": 0}`,
+			check: func(response *http.Response, body []byte) string {
+				return checkPrettyResponse(response, body, "pub fun f() {}")
+			},
+		},
+		{
+			name:   "diff",
+			method: http.MethodPost,
+			path:   "/diff",
+			body:   `{"code": "pub fun f(){}\n", "maxLineLength": 80}`,
+			check: func(response *http.Response, body []byte) string {
+				if response.StatusCode != http.StatusOK {
+					return fmt.Sprintf("status %d: %s", response.StatusCode, body)
+				}
+				var diff DiffResponse
+				if err := json.Unmarshal(body, &diff); err != nil {
+					return err.Error()
+				}
+				expected := []DiffLine{
+					{Op: DiffDelete, Text: "pub fun f(){}"},
+					{Op: DiffInsert, Text: "pub fun f() {}"},
+				}
+				if fmt.Sprint(diff.Diff) != fmt.Sprint(expected) {
+					return fmt.Sprintf("diff %v, expected %v", diff.Diff, expected)
+				}
+				return ""
+			},
+		},
+		{
+			name:   "format range",
+			method: http.MethodPost,
+			path:   "/pretty/range",
+			body:   `{"code": "pub fun f(){}\npub fun g(){ return }\n", "startOffset": 20, "endOffset": 22}`,
+			check: func(response *http.Response, body []byte) string {
+				if response.StatusCode != http.StatusOK {
+					return fmt.Sprintf("status %d: %s", response.StatusCode, body)
+				}
+				var edit TextEdit
+				if err := json.Unmarshal(body, &edit); err != nil {
+					return err.Error()
+				}
+				expected := TextEdit{StartOffset: 25, EndOffset: 34, NewText: " {\n    return\n"}
+				if edit != expected {
+					return fmt.Sprintf("edit %+v, expected %+v", edit, expected)
+				}
+				return ""
+			},
+		},
+		{
+			name:   "compare",
+			method: http.MethodPost,
+			path:   "/compare",
+			body:   `{"code": "pub fun f(a: Int, b: Int) {}", "before": {"maxLineLength": 80}, "after": {"maxLineLength": 20}}`,
+			check: func(response *http.Response, body []byte) string {
+				if response.StatusCode != http.StatusOK {
+					return fmt.Sprintf("status %d: %s", response.StatusCode, body)
+				}
+				var compare CompareResponse
+				if err := json.Unmarshal(body, &compare); err != nil {
+					return err.Error()
+				}
+				if compare.Before != "pub fun f(a: Int, b: Int) {}" || compare.Deleted != 1 || compare.Inserted != 4 {
+					return fmt.Sprintf("unexpected comparison %+v", compare)
+				}
+				return ""
+			},
+		},
+		{
+			name:   "share",
+			method: http.MethodPost,
+			path:   "/share",
+			body:   `{"code": "pub fun f() {}", "maxLineLength": 60}`,
+			check: func(response *http.Response, body []byte) string {
+				if response.StatusCode != http.StatusCreated {
+					return fmt.Sprintf("status %d: %s", response.StatusCode, body)
+				}
+				var share ShareResponse
+				if err := json.Unmarshal(body, &share); err != nil {
+					return err.Error()
+				}
+				if share.ID == "" {
+					return "no id"
+				}
+				snippetID = share.ID
+				return ""
+			},
+		},
+		{
+			name:   "shared",
+			method: http.MethodGet,
+			path:   "/share/{id}",
+			check: func(response *http.Response, body []byte) string {
+				if response.StatusCode != http.StatusOK {
+					return fmt.Sprintf("status %d: %s", response.StatusCode, body)
+				}
+				var snippet Request
+				if err := json.Unmarshal(body, &snippet); err != nil {
+					return err.Error()
+				}
+				if snippet.Code != "pub fun f() {}" || snippet.MaxLineLength != 60 {
+					return fmt.Sprintf("snippet %+v, expected the shared code and width", snippet)
+				}
+				return ""
+			},
+		},
+		{
+			name:   "examples",
+			method: http.MethodGet,
+			path:   "/examples",
+			check: func(response *http.Response, body []byte) string {
+				if response.StatusCode != http.StatusOK {
+					return fmt.Sprintf("status %d: %s", response.StatusCode, body)
+				}
+				var examples []Example
+				if err := json.Unmarshal(body, &examples); err != nil {
+					return err.Error()
+				}
+				if len(examples) == 0 {
+					return "no examples"
+				}
+				//the examples must format, or the gallery shows errors
+				for _, example := range examples {
+					if example.Name == "" || example.Title == "" {
+						return "example without name or title"
+					}
+					if _, err := format.Source(example.Code, format.DefaultOptions); err != nil {
+						return fmt.Sprintf("example %s: %s", example.Name, err)
+					}
+				}
+				return ""
+			},
+		},
+		{
+			name:   "settings",
+			method: http.MethodGet,
+			path:   "/settings",
+			check: func(response *http.Response, body []byte) string {
+				if response.StatusCode != http.StatusOK {
+					return fmt.Sprintf("status %d: %s", response.StatusCode, body)
+				}
+				if len(response.Cookies()) == 0 {
+					return "no session cookie"
+				}
+				var settings Settings
+				if err := json.Unmarshal(body, &settings); err != nil {
+					return err.Error()
+				}
+				if settings != defaultSettings {
+					return fmt.Sprintf("settings %+v, expected the defaults", settings)
+				}
+				return ""
+			},
+		},
+		{
+			name:   "save settings",
+			method: http.MethodPut,
+			path:   "/settings",
+			body:   `{"maxLineLength": 100, "tabs": true, "theme": "dark"}`,
+			check: func(response *http.Response, body []byte) string {
+				if response.StatusCode != http.StatusOK {
+					return fmt.Sprintf("status %d: %s", response.StatusCode, body)
+				}
+				var settings Settings
+				if err := json.Unmarshal(body, &settings); err != nil {
+					return err.Error()
+				}
+				if settings != (Settings{MaxLineLength: 100, Tabs: true, Theme: "dark"}) {
+					return fmt.Sprintf("settings %+v, expected the saved ones", settings)
+				}
+				return ""
+			},
+		},
+		{
+			name:   "parse error",
+			method: http.MethodPost,
+			path:   "/pretty",
+			body:   `{"code": "pub fun (", "maxLineLength": 80}`,
+			check: func(response *http.Response, body []byte) string {
+				if problem := checkErrorResponse(response, body, http.StatusUnprocessableEntity); problem != "" {
+					return problem
+				}
+				//the UI underlines the positions of the parse errors
+				var errorResponse ErrorResponse
+				if err := json.Unmarshal(body, &errorResponse); err != nil {
+					return err.Error()
+				}
+				if len(errorResponse.Errors) == 0 {
+					return "no positions of the parse errors"
+				}
+				for _, parseError := range errorResponse.Errors {
+					if parseError.Line < 1 || parseError.EndLine < parseError.Line {
+						return fmt.Sprintf("invalid position %+v", parseError)
+					}
+				}
+				return ""
+			},
+		},
+		{
+			name:   "invalid request",
+			method: http.MethodPost,
+			path:   "/pretty",
+			body:   `{"code": `,
+			check: func(response *http.Response, body []byte) string {
+				return checkErrorResponse(response, body, http.StatusBadRequest)
+			},
+		},
+	}
+
+	failures := 0
+	for _, check := range checks {
+		path := strings.ReplaceAll(check.path, "{id}", snippetID)
+		request, err := http.NewRequest(check.method, server.URL+path, strings.NewReader(check.body))
+		if err != nil {
+			fatalInternal(err)
+		}
+		//the UI always asks for JSON
+		request.Header.Set("Accept", "application/json")
+		for name, value := range check.header {
+			if value == "etag" {
+				value = etag
+			}
+			request.Header.Set(name, value)
+		}
+
+		problem := ""
+		response, err := server.Client().Do(request)
+		if err != nil {
+			problem = err.Error()
+		} else {
+			body, err := io.ReadAll(response.Body)
+			_ = response.Body.Close()
+			if err != nil {
+				problem = err.Error()
+			} else {
+				problem = check.check(response, body)
+			}
+		}
+
+		if problem != "" {
+			fmt.Printf("FAIL %s: %s\n", check.name, problem)
+			failures++
+		} else {
+			fmt.Printf("ok   %s\n", check.name)
+		}
+	}
+
+	if problem := checkLive(server.URL + "/ws"); problem != "" {
+		fmt.Printf("FAIL live: %s\n", problem)
+		failures++
+	} else {
+		fmt.Printf("ok   live\n")
+	}
+
+	if problem := checkConcurrent(server.URL, server.Client()); problem != "" {
+		fmt.Printf("FAIL concurrent: %s\n", problem)
+		failures++
+	} else {
+		fmt.Printf("ok   concurrent\n")
+	}
+
+	if problem := checkClient(server.URL, server.Client()); problem != "" {
+		fmt.Printf("FAIL client: %s\n", problem)
+		failures++
+	} else {
+		fmt.Printf("ok   client\n")
+	}
+
+	if failures > 0 {
+		fmt.Printf("%d of %d checks failed\n", failures, len(checks)+3)
+		os.Exit(exitInternal)
+	}
+}
+
+// checkClient calls the endpoints with the client package,
+// and checks the API is the one it and its OpenAPI description expect
+func checkClient(url string, httpClient *http.Client) string {
+	response, err := httpClient.Get(url + "/openapi.json")
+	if err != nil {
+		return err.Error()
+	}
+	var spec struct {
+		Paths map[string]any `json:"paths"`
+	}
+	err = json.NewDecoder(response.Body).Decode(&spec)
+	_ = response.Body.Close()
+	if err != nil {
+		return fmt.Sprintf("invalid OpenAPI description: %s", err)
+	}
+	for _, path := range []string{"/pretty", "/pretty/batch", "/ast"} {
+		if spec.Paths[path] == nil {
+			return fmt.Sprintf("the OpenAPI description lacks %s", path)
+		}
+	}
+
+	c := &client.Client{BaseURL: url, HTTPClient: httpClient}
+	ctx := context.Background()
+
+	pretty, err := c.Pretty(ctx, client.PrettyRequest{Code: "pub fun f(){ return }", Options: client.Options{MaxLineLength: 80}})
+	if err != nil {
+		return err.Error()
+	}
+	if pretty.Code != "pub fun f() {\n    return\n}" || len(pretty.Lines) != 3 {
+		return fmt.Sprintf("unexpected formatted code %q", pretty.Code)
+	}
+
+	_, err = c.Pretty(ctx, client.PrettyRequest{Code: "pub fun f("})
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnprocessableEntity || len(apiErr.Errors) == 0 {
+		return fmt.Sprintf("expected a parse error, got %v", err)
+	}
+
+	batch, err := c.Batch(ctx, client.BatchRequest{Entries: []client.BatchEntry{
+		{Path: "a.cdc", Code: "pub fun f(){}"},
+		{Path: "b.cdc", Code: "pub fun f("},
+	}})
+	if err != nil {
+		return err.Error()
+	}
+	if len(batch.Results) != 2 || batch.Results[0].ErrorResponse != nil || batch.Results[1].ErrorResponse == nil {
+		return fmt.Sprintf("unexpected batch results %+v", batch.Results)
+	}
+
+	program, err := c.AST(ctx, "pub fun f(){}")
+	if err != nil {
+		return err.Error()
+	}
+	if !json.Valid(program) {
+		return "the AST is not JSON"
+	}
+	return ""
+}
+
+// checkLive sends two requests over the live channel at once, like fast typing,
+// and checks only the latter is answered, with the formatted code
+func checkLive(url string) string {
+	conn, err := dialWebSocket(url)
+	if err != nil {
+		return err.Error()
+	}
+	defer conn.Close()
+
+	for id, code := range []string{"pub fun f(", "pub fun f(){}"} {
+		request := LiveRequest{ID: int64(id + 1), Request: Request{Code: code}}
+		if err := conn.writeJSON(request); err != nil {
+			return err.Error()
+		}
+	}
+
+	_ = conn.conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	message, err := conn.readMessage()
+	if err != nil {
+		return err.Error()
+	}
+	var response LiveResponse
+	if err := json.Unmarshal(message, &response); err != nil {
+		return err.Error()
+	}
+	if response.ID != 2 {
+		return fmt.Sprintf("answer to request %d, expected only the latest request 2", response.ID)
+	}
+	if response.PrettyResponse == nil || response.Code != "pub fun f() {}" {
+		return fmt.Sprintf("response %s, expected the formatted code", message)
+	}
+	return ""
+}
+
+// checkConcurrent formats the examples at several widths at once, like many users of the playground,
+// and checks each response is the code formatted on its own, which it is not if requests share state.
+// With a binary built with -race, the race detector also reports data races between the requests
+func checkConcurrent(url string, client *http.Client) string {
+	examples, err := examples()
+	if err != nil {
+		return err.Error()
+	}
+
+	var wg sync.WaitGroup
+	problems := make(chan string, 4*len(examples)*3)
+	for round := 0; round < 4; round++ {
+		for _, example := range examples {
+			for _, width := range []int{40, 80, 120} {
+				//the code of each round differs, so it is not answered from the cache
+				code := fmt.Sprintf("// round %d\n%s", round, example.Code)
+				wg.Add(1)
+				go func(name string, code string, width int) {
+					defer wg.Done()
+					if problem := checkPretty(url, client, code, width); problem != "" {
+						problems <- fmt.Sprintf("%s at width %d: %s", name, width, problem)
+					}
+					//the other endpoints of the UI, for the race detector
+					for _, path := range []string{"/diff", "/tokens", "/doc"} {
+						if problem := checkStatus(url+path, client, code); problem != "" {
+							problems <- fmt.Sprintf("%s %s: %s", path, name, problem)
+						}
+					}
+				}(example.Name, code, width)
+			}
+		}
+	}
+	wg.Wait()
+	close(problems)
+
+	for problem := range problems {
+		return problem
+	}
+	return ""
+}
+
+// checkPretty checks /pretty formats the code at the width like format.Source
+func checkPretty(url string, client *http.Client, code string, width int) string {
+	options := format.DefaultOptions
+	options.MaxLineLength = width
+	expected, err := format.Source(code, options)
+	if err != nil {
+		return err.Error()
+	}
+
+	body, err := json.Marshal(Request{Code: code, RequestOptions: RequestOptions{MaxLineLength: width}})
+	if err != nil {
+		return err.Error()
+	}
+	request, err := http.NewRequest(http.MethodPost, url+"/pretty", bytes.NewReader(body))
+	if err != nil {
+		return err.Error()
+	}
+	request.Header.Set("Accept", "application/json")
+	response, err := client.Do(request)
+	if err != nil {
+		return err.Error()
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Sprintf("status %d", response.StatusCode)
+	}
+
+	var pretty PrettyResponse
+	if err := json.NewDecoder(response.Body).Decode(&pretty); err != nil {
+		return err.Error()
+	}
+	if pretty.Code != expected {
+		return "the code differs from the code formatted on its own"
+	}
+	return ""
+}
+
+// checkStatus checks the endpoint answers the code with 200
+func checkStatus(url string, client *http.Client, code string) string {
+	body, err := json.Marshal(Request{Code: code})
+	if err != nil {
+		return err.Error()
+	}
+	response, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err.Error()
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, response.Body)
+	if response.StatusCode != http.StatusOK {
+		return fmt.Sprintf("status %d", response.StatusCode)
+	}
+	return ""
+}
+
+// checkPrettyResponse checks the response has the formatted code,
+// and a width for each of its lines
+func checkPrettyResponse(response *http.Response, body []byte, expected string) string {
+	if response.StatusCode != http.StatusOK {
+		return fmt.Sprintf("status %d: %s", response.StatusCode, body)
+	}
+	if contentType := response.Header.Get("Content-Type"); contentType != "application/json" {
+		return fmt.Sprintf("content type %q", contentType)
+	}
+
+	var pretty struct {
+		Code  *string `json:"code"`
+		Lines []struct {
+			Width    *int  `json:"width"`
+			Overflow *bool `json:"overflow"`
+		} `json:"lines"`
+	}
+	if err := json.Unmarshal(body, &pretty); err != nil {
+		return err.Error()
+	}
+	if pretty.Code == nil {
+		return "no code"
+	}
+	if strings.TrimRight(*pretty.Code, "\n") != expected {
+		return fmt.Sprintf("code %q, expected %q", *pretty.Code, expected)
+	}
+	if len(pretty.Lines) != strings.Count(expected, "\n")+1 {
+		return fmt.Sprintf("%d lines, expected %d", len(pretty.Lines), strings.Count(expected, "\n")+1)
+	}
+	for i, line := range pretty.Lines {
+		if line.Width == nil || line.Overflow == nil {
+			return fmt.Sprintf("line %d has no width or overflow", i+1)
+		}
+	}
+	return ""
+}
+
+// checkErrorResponse checks the response has the status and an error message
+func checkErrorResponse(response *http.Response, body []byte, status int) string {
+	if response.StatusCode != status {
+		return fmt.Sprintf("status %d, expected %d", response.StatusCode, status)
+	}
+
+	var errorResponse struct {
+		Error *string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &errorResponse); err != nil {
+		return err.Error()
+	}
+	if errorResponse.Error == nil || *errorResponse.Error == "" {
+		return "no error message"
+	}
+	return ""
+}
@@ -0,0 +1,416 @@
+//go:build !noui
+
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"embed"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/parser"
+
+	"cadencefmt/client"
+	"cadencefmt/format"
+)
+
+// serverFlags are the settings of the HTTP server
+type serverFlags struct {
+	port        *int
+	listen      *string
+	tlsCert     *string
+	tlsKey      *string
+	tlsClientCA *string
+	apiKey      *string
+	rateLimit   *float64
+	rateBurst   *int
+	// maxConcurrency limits the requests formatted at once,
+	// queueSize and queueTimeout the ones waiting for them
+	maxConcurrency *int
+	queueSize      *int
+	queueTimeout   *time.Duration
+	corsOrigins    *string
+	corsMethods    *string
+	profiles       *string
+	// maxRequestSize limits request bodies, in bytes
+	maxRequestSize *int64
+	readTimeout    *time.Duration
+	writeTimeout   *time.Duration
+	formatTimeout  *time.Duration
+	// cacheSize is the number of formatting results kept
+	cacheSize *int
+	// shutdownTimeout is how long requests in progress may take after a signal
+	shutdownTimeout *time.Duration
+	// snippets is the directory of shared snippets
+	snippets *string
+	// liveDebounce is how long /ws waits for newer input before formatting
+	liveDebounce *time.Duration
+	// sessions is the directory of the settings of web UI sessions
+	sessions *string
+	// pprof serves the Go profiler under /debug/pprof/
+	pprof *bool
+}
+
+func addServerFlags(flags *flag.FlagSet) *serverFlags {
+	return &serverFlags{
+		port:            flags.Int("port", 9090, "port"),
+		listen:          flags.String("listen", "", "listen on host:port or unix:///path.sock (default 127.0.0.1 and -port)"),
+		tlsCert:         flags.String("tls-cert", "", "serve HTTPS with this certificate file"),
+		tlsKey:          flags.String("tls-key", "", "key file of the -tls-cert certificate"),
+		tlsClientCA:     flags.String("tls-client-ca", "", "require client certificates signed by the CAs in this file"),
+		apiKey:          flags.String("api-key", os.Getenv(apiKeyEnv), "require this API key, or one of these comma separated keys, for POST requests (default $"+apiKeyEnv+")"),
+		rateLimit:       flags.Float64("rate-limit", 0, "formatting requests per second allowed for each client IP (default unlimited)"),
+		rateBurst:       flags.Int("rate-burst", 10, "formatting requests a client IP can make at once, before -rate-limit applies"),
+		maxConcurrency:  flags.Int("max-concurrency", 0, "formatting requests handled at once, the others wait in a queue (default unlimited)"),
+		queueSize:       flags.Int("queue-size", 100, "formatting requests waiting for -max-concurrency, more are rejected with 429"),
+		queueTimeout:    flags.Duration("queue-timeout", 10*time.Second, "time a request waits for -max-concurrency before it is rejected with 429, 0 for no limit"),
+		corsOrigins:     flags.String("cors-origins", "", "comma separated origins of browsers allowed to call the API, or * for all"),
+		corsMethods:     flags.String("cors-methods", "GET, POST", "methods allowed for -cors-origins"),
+		profiles:        flags.String("profiles", "", "directory of configuration files, <name>.json, which requests can select with \"profile\""),
+		maxRequestSize:  flags.Int64("max-request-size", 1<<20, "largest request body in bytes, 0 for no limit"),
+		readTimeout:     flags.Duration("read-timeout", 10*time.Second, "time to read a request, 0 for no limit"),
+		writeTimeout:    flags.Duration("write-timeout", 30*time.Second, "time to handle a request and write the response, 0 for no limit"),
+		formatTimeout:   flags.Duration("format-timeout", 5*time.Second, "time to format the code of a request, 0 for no limit"),
+		cacheSize:       flags.Int("cache-size", 512, "number of formatting results kept for repeated requests, 0 to disable"),
+		shutdownTimeout: flags.Duration("shutdown-timeout", 30*time.Second, "time requests in progress may take to finish on SIGINT or SIGTERM"),
+		liveDebounce:    flags.Duration("live-debounce", 150*time.Millisecond, "time the live formatting channel, /ws, waits for newer input before formatting"),
+		sessions:        flags.String("sessions", "", "directory keeping the settings of web UI sessions (default in memory)"),
+		snippets:        flags.String("snippets", "", "directory keeping the snippets shared from the web UI (default in memory, the latest 1000)"),
+		pprof:           flags.Bool("pprof", false, "serve the Go profiler under /debug/pprof/, for diagnosing slow formatting"),
+	}
+}
+
+// handler returns the handler of all endpoints
+func (f *serverFlags) handler() http.Handler {
+	mux := http.NewServeMux()
+
+	servePage := func(w http.ResponseWriter, r *http.Request) {
+		page, _ := ui.ReadFile("ui/index.html")
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write(page)
+	}
+	mux.HandleFunc("/", servePage)
+	//the page loads shared snippets
+	mux.HandleFunc("/s/", servePage)
+	mux.Handle("/ui/", http.FileServer(http.FS(ui)))
+
+	idempotency := newIdempotencyCache(idempotencyBudget)
+	limiter := newRateLimiter(*f.rateLimit, *f.rateBurst)
+	pool := newWorkerPool(*f.maxConcurrency, *f.queueSize, *f.queueTimeout)
+	profiles := profileDir(*f.profiles)
+	cache := newFormatCache(*f.cacheSize)
+	snippets := newSnippetStore(*f.snippets)
+	settings := newSettingsStore(*f.sessions)
+
+	mux.HandleFunc("/pretty", gzipped(*f.maxRequestSize, limiter.wrap(pool.wrap(formatTimeout(*f.formatTimeout, func(w http.ResponseWriter, r *http.Request) {
+		var req Request
+
+		err := decodeBody(r, &req)
+		if err != nil {
+			writeBodyError(w, err)
+			return
+		}
+		options, err := profiles.options(req.RequestOptions)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		etag := formatETag(req.Code, options, acceptsJSON(r))
+		if notModified(r, etag) {
+			w.Header().Set("ETag", etag)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		result, err := cache.format(r.Context(), req.Code, options)
+		if err != nil {
+			writeError(w, formatErrorStatus(err), err)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		if !acceptsJSON(r) {
+			_, _ = w.Write([]byte(result))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(PrettyResponse{
+			Code:  result,
+			Lines: lineMetadata(result, options.MaxLineLength),
+		})
+	})))))
+
+	mux.HandleFunc("/pretty/batch", gzipped(*f.maxRequestSize, limiter.wrap(pool.wrap(handleBatch(profiles, cache, batchLimits{
+		size:          *f.maxRequestSize,
+		formatTimeout: *f.formatTimeout,
+		readTimeout:   *f.readTimeout,
+		writeTimeout:  *f.writeTimeout,
+	})))))
+
+	mux.HandleFunc("/pretty/range", gzipped(*f.maxRequestSize, limiter.wrap(pool.wrap(formatTimeout(*f.formatTimeout, handlePrettyRange(profiles))))))
+
+	mux.HandleFunc("/ast", gzipped(*f.maxRequestSize, limiter.wrap(pool.wrap(formatTimeout(*f.formatTimeout, handleAST)))))
+
+	mux.HandleFunc("/doc", gzipped(*f.maxRequestSize, limiter.wrap(pool.wrap(formatTimeout(*f.formatTimeout, handleDoc(profiles))))))
+	mux.HandleFunc("/diff", gzipped(*f.maxRequestSize, limiter.wrap(pool.wrap(formatTimeout(*f.formatTimeout, handleDiff(profiles, cache))))))
+	mux.HandleFunc("/edits", gzipped(*f.maxRequestSize, limiter.wrap(pool.wrap(formatTimeout(*f.formatTimeout, handleEdits(profiles, cache))))))
+	mux.HandleFunc("/compare", gzipped(*f.maxRequestSize, limiter.wrap(pool.wrap(formatTimeout(*f.formatTimeout, handleCompare(profiles, cache))))))
+	mux.HandleFunc("/tokens", gzipped(*f.maxRequestSize, limiter.wrap(pool.wrap(formatTimeout(*f.formatTimeout, handleTokens(profiles))))))
+
+	mux.HandleFunc("/share", gzipped(*f.maxRequestSize, limiter.wrap(pool.wrap(idempotency.wrap(handleShare(snippets, profiles))))))
+	mux.HandleFunc("/share/", gzipped(*f.maxRequestSize, handleSnippet(snippets)))
+
+	mux.HandleFunc("/settings", idempotency.wrap(handleSettings(settings)))
+	mux.HandleFunc("/examples", gzipped(*f.maxRequestSize, handleExamples))
+
+	mux.HandleFunc("/ws", handleLive(profiles, cache, limiter, pool, *f.maxRequestSize, *f.formatTimeout, *f.liveDebounce))
+
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok\n"))
+	})
+
+	mux.HandleFunc("/version", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(currentVersion())
+	})
+
+	mux.HandleFunc("/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(openAPI)
+	})
+
+	if *f.pprof {
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+
+	handler := limitBody(*f.maxRequestSize, mux)
+	if *f.apiKey != "" {
+		handler = requireAPIKey(*f.apiKey, handler)
+	}
+	if *f.corsOrigins != "" {
+		handler = allowCORS(*f.corsOrigins, *f.corsMethods, handler)
+	}
+	return logRequests(handler)
+}
+
+// serve listens and serves requests until the server fails,
+// or until it is interrupted, then it shuts down gracefully
+func (f *serverFlags) serve() error {
+	if err := f.checkTLS(); err != nil {
+		return err
+	}
+	address := *f.listen
+	if address == "" {
+		address = fmt.Sprintf("127.0.0.1:%d", *f.port)
+	}
+	ln, err := listen(address)
+	if err != nil {
+		return err
+	}
+
+	srv := http.Server{
+		Handler:           f.handler(),
+		ReadHeaderTimeout: *f.readTimeout,
+		ReadTimeout:       *f.readTimeout,
+		WriteTimeout:      *f.writeTimeout,
+	}
+
+	useTLS := *f.tlsCert != ""
+	if useTLS {
+		srv.TLSConfig, err = newTLSConfig(*f.tlsClientCA)
+		if err != nil {
+			return err
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errs := make(chan error, 1)
+	go func() {
+		slog.Info("listening", "url", listenerURL(ln, useTLS))
+		if useTLS {
+			errs <- srv.ServeTLS(ln, *f.tlsCert, *f.tlsKey)
+		} else {
+			errs <- srv.Serve(ln)
+		}
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	//a second signal stops the server immediately
+	stop()
+
+	slog.Info("shutting down, waiting for requests in progress", "timeout", *f.shutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), *f.shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("shutdown: %w", err)
+	}
+	slog.Info("stopped")
+	return nil
+}
+
+// ui are the files of the web UI,
+// the page and the editor it uses
+//
+//go:embed ui
+var ui embed.FS
+
+// openAPI describes the API of the server, the client package calls it
+//
+//go:embed openapi.json
+var openAPI []byte
+
+// the types of the client package have the fields of the ones of the server
+var (
+	_ = client.Options(RequestOptions{})
+	_ = client.LineMetadata(LineMetadata{})
+	_ = client.BatchEntry(BatchEntry{})
+	_ = client.ASTRequest(ASTRequest{})
+	_ = client.ParseError(ParseError{})
+)
+
+// PrettyResponse is the body of a successful request which accepts JSON,
+// the formatted code is returned as text otherwise
+type PrettyResponse struct {
+	Code  string         `json:"code"`
+	Lines []LineMetadata `json:"lines"`
+}
+
+func acceptsJSON(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "application/json")
+}
+
+type Request struct {
+	Code string `json:"code"`
+	RequestOptions
+}
+
+// ErrorResponse is the body of a failed request
+type ErrorResponse struct {
+	Error string `json:"error"`
+	// Errors are the individual parse errors, if the code does not parse
+	Errors []ParseError `json:"errors,omitempty"`
+}
+
+// ParseError is a parse error and its position,
+// with lines starting at 1 and columns at 0
+type ParseError struct {
+	Message   string `json:"message"`
+	Line      int    `json:"line"`
+	Column    int    `json:"column"`
+	EndLine   int    `json:"endLine"`
+	EndColumn int    `json:"endColumn"`
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(newErrorResponse(err))
+}
+
+// formatErrorStatus is the status of a request whose code could not be formatted:
+// 500 if the result failed a safety check, which is a bug of the formatter,
+// and 422 otherwise, e.g. if the code does not parse
+func formatErrorStatus(err error) int {
+	var safetyErr *format.SafetyError
+	if errors.As(err, &safetyErr) {
+		return http.StatusInternalServerError
+	}
+	return http.StatusUnprocessableEntity
+}
+
+func newErrorResponse(err error) *ErrorResponse {
+	response := &ErrorResponse{Error: err.Error()}
+
+	var parseErr parser.Error
+	if errors.As(err, &parseErr) {
+		for _, childErr := range parseErr.Errors {
+			parseError := ParseError{Message: childErr.Error()}
+			if positioned, ok := childErr.(ast.HasPosition); ok {
+				start := positioned.StartPosition()
+				end := positioned.EndPosition(nil)
+				parseError.Line = start.Line
+				parseError.Column = start.Column
+				parseError.EndLine = end.Line
+				parseError.EndColumn = end.Column
+			}
+			response.Errors = append(response.Errors, parseError)
+		}
+	}
+
+	return response
+}
+
+// checkTLS refuses the TLS flags without each other,
+// so the server does not serve plain HTTP when HTTPS was meant
+func (f *serverFlags) checkTLS() error {
+	if *f.tlsCert == "" && (*f.tlsKey != "" || *f.tlsClientCA != "") {
+		return errors.New("-tls-key and -tls-client-ca require -tls-cert")
+	}
+	if *f.tlsCert != "" && *f.tlsKey == "" {
+		return errors.New("-tls-cert requires -tls-key")
+	}
+	return nil
+}
+
+// newTLSConfig returns the TLS configuration of the server.
+// If a client CA file is given, clients must present a certificate signed by it
+func newTLSConfig(clientCAFile string) (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+	if clientCAFile == "" {
+		return config, nil
+	}
+
+	pem, err := os.ReadFile(clientCAFile)
+	if err != nil {
+		return nil, err
+	}
+	config.ClientCAs = x509.NewCertPool()
+	if !config.ClientCAs.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("%s: no certificates found", clientCAFile)
+	}
+	config.ClientAuth = tls.RequireAndVerifyClientCert
+
+	return config, nil
+}
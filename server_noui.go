@@ -0,0 +1,39 @@
+//go:build noui
+
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+)
+
+// serverFlags has no settings in builds without the server
+type serverFlags struct{}
+
+func addServerFlags(*flag.FlagSet) *serverFlags {
+	return &serverFlags{}
+}
+
+var errNoServer = errors.New("no files given, and this build has no web UI and server (built with -tags noui)")
+
+func (f *serverFlags) serve() error {
+	return errNoServer
+}
@@ -0,0 +1,485 @@
+//go:build !noui
+
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newTestServer serves the endpoints with the server flags
+func newTestServer(t *testing.T, args ...string) *httptest.Server {
+	t.Helper()
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	f := addServerFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(f.handler())
+	t.Cleanup(server.Close)
+	return server
+}
+
+// post sends the body to the path like the web UI, which accepts JSON
+func post(t *testing.T, server *httptest.Server, path string, body string, header http.Header) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, server.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept", "application/json")
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	res, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { res.Body.Close() })
+	return res
+}
+
+func decodeResponse[T any](t *testing.T, res *http.Response) T {
+	t.Helper()
+	var value T
+	if err := json.NewDecoder(res.Body).Decode(&value); err != nil {
+		t.Fatal(err)
+	}
+	return value
+}
+
+func TestPretty(t *testing.T) {
+	server := newTestServer(t)
+
+	tests := []struct {
+		name     string
+		body     string
+		expected PrettyResponse
+	}{
+		{
+			name: "code",
+			body: `{"code": "pub fun a(){}", "maxLineLength": 80, "tabs": false}`,
+			expected: PrettyResponse{
+				Code:  "pub fun a() {}",
+				Lines: []LineMetadata{{Width: 14}},
+			},
+		},
+		{
+			name: "overflow",
+			body: `{"code": "pub fun a(){}", "maxLineLength": 10, "tabs": false}`,
+			expected: PrettyResponse{
+				Code:  "pub fun a() {}",
+				Lines: []LineMetadata{{Width: 14, Overflow: true}},
+			},
+		},
+		{
+			name: "tabs",
+			body: `{"code": "pub fun a() { return }", "maxLineLength": 80, "tabs": true}`,
+			expected: PrettyResponse{
+				//indenting with tabs ends every line with a line break
+				Code:  "pub fun a() {\n\treturn\n}\n",
+				Lines: []LineMetadata{{Width: 13}, {Width: 10}, {Width: 1}},
+			},
+		},
+		{
+			name: "empty code",
+			body: `{"code": "", "maxLineLength": 80, "tabs": false}`,
+			expected: PrettyResponse{
+				Code:  "",
+				Lines: []LineMetadata{{Width: 0}},
+			},
+		},
+		{
+			name: "huge width",
+			body: `{"code": "pub fun a(x: Int, y: Int) {}", "maxLineLength": 1000000, "tabs": false}`,
+			expected: PrettyResponse{
+				Code:  "pub fun a(x: Int, y: Int) {}",
+				Lines: []LineMetadata{{Width: 28}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res := post(t, server, "/pretty", test.body, nil)
+			if res.StatusCode != http.StatusOK {
+				t.Fatalf("expected status 200, got %d", res.StatusCode)
+			}
+			actual := decodeResponse[PrettyResponse](t, res)
+			if actual.Code != test.expected.Code {
+				t.Errorf("expected code %q, got %q", test.expected.Code, actual.Code)
+			}
+			if len(actual.Lines) != len(test.expected.Lines) {
+				t.Fatalf("expected lines %v, got %v", test.expected.Lines, actual.Lines)
+			}
+			for i, line := range actual.Lines {
+				if line != test.expected.Lines[i] {
+					t.Errorf("expected lines %v, got %v", test.expected.Lines, actual.Lines)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestPrettyText(t *testing.T) {
+	server := newTestServer(t)
+
+	res := post(t, server, "/pretty", `{"code": "pub fun a(){}"}`, http.Header{"Accept": {"text/plain"}})
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", res.StatusCode)
+	}
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != "pub fun a() {}" {
+		t.Errorf("expected %q, got %q", "pub fun a() {}", body)
+	}
+}
+
+func TestPrettyNotModified(t *testing.T) {
+	server := newTestServer(t)
+
+	body := `{"code": "pub fun a(){}"}`
+	etag := post(t, server, "/pretty", body, nil).Header.Get("ETag")
+	if etag == "" {
+		t.Fatal("expected an ETag")
+	}
+	res := post(t, server, "/pretty", body, http.Header{"If-None-Match": {etag}})
+	if res.StatusCode != http.StatusNotModified {
+		t.Errorf("expected status 304, got %d", res.StatusCode)
+	}
+}
+
+func TestPrettyParseError(t *testing.T) {
+	server := newTestServer(t)
+
+	res := post(t, server, "/pretty", `{"code": "pub fun ("}`, nil)
+	if res.StatusCode != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status 422, got %d", res.StatusCode)
+	}
+	//the UI underlines the errors at their positions
+	actual := decodeResponse[ErrorResponse](t, res)
+	if actual.Error == "" || len(actual.Errors) != 1 {
+		t.Fatalf("expected an error with one parse error, got %+v", actual)
+	}
+	if parseErr := actual.Errors[0]; parseErr.Line != 1 || parseErr.Column != 8 || parseErr.Message == "" {
+		t.Errorf("expected a parse error at 1:8, got %+v", parseErr)
+	}
+}
+
+func TestPrettyBadRequest(t *testing.T) {
+	server := newTestServer(t)
+
+	res := post(t, server, "/pretty", `{"code": `, nil)
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", res.StatusCode)
+	}
+}
+
+func TestRequestTooLarge(t *testing.T) {
+	server := newTestServer(t, "-max-request-size", "100")
+
+	body := `{"code": "` + strings.Repeat("a", 200) + `"}`
+	res := post(t, server, "/pretty", body, nil)
+	if res.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected status 413, got %d", res.StatusCode)
+	}
+	if actual := decodeResponse[ErrorResponse](t, res); actual.Error == "" {
+		t.Error("expected an error message")
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	server := newTestServer(t, "-rate-limit", "0.001", "-rate-burst", "2")
+
+	body := `{"code": "pub fun a(){}"}`
+	for i := 0; i < 2; i++ {
+		if res := post(t, server, "/pretty", body, nil); res.StatusCode != http.StatusOK {
+			t.Fatalf("expected status 200 within the burst, got %d", res.StatusCode)
+		}
+	}
+	res := post(t, server, "/pretty", body, nil)
+	if res.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected status 429, got %d", res.StatusCode)
+	}
+	if res.Header.Get("Retry-After") == "" {
+		t.Error("expected a Retry-After header")
+	}
+}
+
+func TestCORS(t *testing.T) {
+	server := newTestServer(t, "-cors-origins", "https://a.example")
+
+	tests := []struct {
+		name    string
+		method  string
+		origin  string
+		allowed string
+		status  int
+	}{
+		{
+			name:    "preflight",
+			method:  http.MethodOptions,
+			origin:  "https://a.example",
+			allowed: "https://a.example",
+			status:  http.StatusNoContent,
+		},
+		{
+			name:    "request",
+			method:  http.MethodPost,
+			origin:  "https://a.example",
+			allowed: "https://a.example",
+			status:  http.StatusOK,
+		},
+		{
+			name:    "other origin",
+			method:  http.MethodPost,
+			origin:  "https://b.example",
+			allowed: "",
+			status:  http.StatusOK,
+		},
+		{
+			name:    "same origin",
+			method:  http.MethodPost,
+			allowed: "",
+			status:  http.StatusOK,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest(test.method, server.URL+"/pretty", strings.NewReader(`{"code": "pub fun a(){}"}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.origin != "" {
+				req.Header.Set("Origin", test.origin)
+			}
+			if test.method == http.MethodOptions {
+				req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			}
+			res, err := server.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+
+			if res.StatusCode != test.status {
+				t.Errorf("expected status %d, got %d", test.status, res.StatusCode)
+			}
+			if allowed := res.Header.Get("Access-Control-Allow-Origin"); allowed != test.allowed {
+				t.Errorf("expected allowed origin %q, got %q", test.allowed, allowed)
+			}
+			if test.method == http.MethodOptions && !strings.Contains(res.Header.Get("Access-Control-Allow-Methods"), http.MethodPost) {
+				t.Errorf("expected POST to be allowed, got %q", res.Header.Get("Access-Control-Allow-Methods"))
+			}
+		})
+	}
+}
+
+// TestConcurrentRequests is meant for the race detector, go test -race,
+// which reports requests sharing state
+func TestConcurrentRequests(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{
+			name: "cached",
+		},
+		{
+			name: "uncached",
+			args: []string{"-cache-size", "0"},
+		},
+		{
+			name: "limited concurrency",
+			args: []string{"-max-concurrency", "2", "-queue-size", "1000", "-queue-timeout", "0"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			//the race detector makes formatting many times slower than the timeout expects
+			server := newTestServer(t, append(test.args, "-format-timeout", "0")...)
+			if problem := checkConcurrent(server.URL, server.Client()); problem != "" {
+				t.Error(problem)
+			}
+		})
+	}
+}
+
+func TestFormatTimeout(t *testing.T) {
+	server := newTestServer(t, "-format-timeout", "1ns")
+
+	res := post(t, server, "/pretty", `{"code": "pub fun a(){}"}`, nil)
+	if res.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", res.StatusCode)
+	}
+}
+
+func TestPartialTLSFlags(t *testing.T) {
+	tests := []struct {
+		args  []string
+		valid bool
+	}{
+		{nil, true},
+		{[]string{"-tls-cert", "cert.pem", "-tls-key", "key.pem"}, true},
+		{[]string{"-tls-cert", "cert.pem", "-tls-key", "key.pem", "-tls-client-ca", "ca.pem"}, true},
+		{[]string{"-tls-key", "key.pem"}, false},
+		{[]string{"-tls-client-ca", "ca.pem"}, false},
+		{[]string{"-tls-key", "key.pem", "-tls-client-ca", "ca.pem"}, false},
+		{[]string{"-tls-cert", "cert.pem"}, false},
+	}
+	for _, test := range tests {
+		flags := flag.NewFlagSet("test", flag.ContinueOnError)
+		f := addServerFlags(flags)
+		if err := flags.Parse(test.args); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.checkTLS(); (err == nil) != test.valid {
+			t.Errorf("%v: expected valid %v, got %v", test.args, test.valid, err)
+		}
+	}
+}
+
+func TestProfilerAPIKey(t *testing.T) {
+	server := newTestServer(t, "-pprof", "-api-key", "secret")
+
+	get := func(key string) int {
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/debug/pprof/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		res, err := server.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		return res.StatusCode
+	}
+	if status := get(""); status != http.StatusUnauthorized {
+		t.Errorf("without the key: expected %d, got %d", http.StatusUnauthorized, status)
+	}
+	if status := get("secret"); status != http.StatusOK {
+		t.Errorf("with the key: expected %d, got %d", http.StatusOK, status)
+	}
+}
+
+func TestStreamedBatchSize(t *testing.T) {
+	server := newTestServer(t, "-max-request-size", "200")
+
+	entry := `{"path": "a.cdc", "code": "pub fun f() {}"}` + "\n"
+	body := `{"maxLineLength": 80}` + "\n" + strings.Repeat(entry, 10)
+	header := http.Header{
+		"Accept":       {ndjsonContentType},
+		"Content-Type": {ndjsonContentType},
+	}
+	res := post(t, server, "/pretty/batch", body, header)
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected %d, got %d", http.StatusOK, res.StatusCode)
+	}
+	decoder := json.NewDecoder(res.Body)
+	for i := 0; i < 10; i++ {
+		var result BatchResult
+		if err := decoder.Decode(&result); err != nil {
+			t.Fatal(err)
+		}
+		if result.ErrorResponse != nil || result.Code != "pub fun f() {}" {
+			t.Errorf("entry %d: unexpected result %+v", i, result)
+		}
+	}
+
+	//a single entry is still limited
+	long := `{"path": "b.cdc", "code": "` + strings.Repeat("/", 300) + `"}` + "\n"
+	res = post(t, server, "/pretty/batch", `{}`+"\n"+entry+long, header)
+	decoder = json.NewDecoder(res.Body)
+	var result BatchResult
+	if err := decoder.Decode(&result); err != nil || result.ErrorResponse != nil {
+		t.Fatalf("expected the first entry to be formatted, got %+v, %v", result, err)
+	}
+	var failure ErrorResponse
+	if err := decoder.Decode(&failure); err != nil || !strings.Contains(failure.Error, "exceeds 200 bytes") {
+		t.Errorf("expected the size error, got %+v, %v", failure, err)
+	}
+}
+
+func TestBatchEntryTimeout(t *testing.T) {
+	server := newTestServer(t, "-format-timeout", "1ns")
+
+	body := `{"entries": [{"path": "a.cdc", "code": "pub fun f() {}"}, {"path": "b.cdc", "code": "pub fun g() {}"}]}`
+	res := post(t, server, "/pretty/batch", body, nil)
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected %d, got %d", http.StatusOK, res.StatusCode)
+	}
+	response := decodeResponse[BatchResponse](t, res)
+	if len(response.Results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(response.Results))
+	}
+	for _, result := range response.Results {
+		if result.ErrorResponse == nil {
+			t.Errorf("%s: expected the timeout error", result.Path)
+		}
+	}
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	server := newTestServer(t)
+	body := `{"code": "pub fun a() {}"}`
+
+	first := post(t, server, "/share", body, http.Header{
+		"Idempotency-Key": {"retry"},
+		"Accept-Encoding": {"gzip"},
+	})
+	if first.StatusCode != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d", first.StatusCode)
+	}
+	if encoding := first.Header.Get("Content-Encoding"); encoding != "gzip" {
+		t.Fatalf("expected a gzipped response, got %q", encoding)
+	}
+
+	//the retry does not accept gzip, so the replay must not claim it
+	retry := post(t, server, "/share", body, http.Header{
+		"Idempotency-Key": {"retry"},
+		"Accept-Encoding": {"identity"},
+	})
+	if retry.StatusCode != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d", retry.StatusCode)
+	}
+	if retry.Header.Get("Idempotent-Replayed") != "true" {
+		t.Error("expected a replayed response")
+	}
+	if encoding := retry.Header.Get("Content-Encoding"); encoding != "" {
+		t.Errorf("expected an uncompressed response, got %q", encoding)
+	}
+	if id := decodeResponse[map[string]string](t, retry)["id"]; id == "" {
+		t.Error("expected the id of the snippet")
+	}
+}
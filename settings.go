@@ -0,0 +1,200 @@
+//go:build !noui
+
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const sessionCookie = "cadencefmt_session"
+
+// sessionMaxAge is how long the session cookie is kept by browsers
+const sessionMaxAge = 365 * 24 * time.Hour
+
+// maxMemorySessions is the number of sessions kept without a directory,
+// others are dropped when it is exceeded
+const maxMemorySessions = 10000
+
+// Settings are the preferences of the web UI
+type Settings struct {
+	MaxLineLength int  `json:"maxLineLength"`
+	Tabs          bool `json:"tabs"`
+	// Theme is light or dark
+	Theme string `json:"theme"`
+}
+
+var defaultSettings = Settings{
+	MaxLineLength: 80,
+	Theme:         "light",
+}
+
+func (s Settings) validate() error {
+	if s.MaxLineLength < 0 {
+		return fmt.Errorf("invalid maxLineLength %d", s.MaxLineLength)
+	}
+	if s.Theme != "light" && s.Theme != "dark" {
+		return fmt.Errorf("unknown theme %q, expected light or dark", s.Theme)
+	}
+	return nil
+}
+
+// settingsStore keeps the settings of each session of the web UI,
+// as files of a directory, or in memory if there is none
+type settingsStore struct {
+	dir string
+
+	mu     sync.Mutex
+	memory map[string]Settings
+}
+
+func newSettingsStore(dir string) *settingsStore {
+	return &settingsStore{
+		dir:    dir,
+		memory: map[string]Settings{},
+	}
+}
+
+// load returns the settings of the session, or the defaults if it has none
+func (s *settingsStore) load(session string) (Settings, error) {
+	if s.dir != "" {
+		data, err := os.ReadFile(filepath.Join(s.dir, session+".json"))
+		if errors.Is(err, os.ErrNotExist) {
+			return defaultSettings, nil
+		} else if err != nil {
+			return Settings{}, err
+		}
+		settings := defaultSettings
+		if err := json.Unmarshal(data, &settings); err != nil {
+			return Settings{}, err
+		}
+		return settings, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	settings, ok := s.memory[session]
+	if !ok {
+		return defaultSettings, nil
+	}
+	return settings, nil
+}
+
+func (s *settingsStore) save(session string, settings Settings) error {
+	if s.dir != "" {
+		data, err := json.Marshal(settings)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(s.dir, session+".json"), data, 0644)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.memory[session]; !ok && len(s.memory) >= maxMemorySessions {
+		for dropped := range s.memory {
+			delete(s.memory, dropped)
+			break
+		}
+	}
+	s.memory[session] = settings
+	return nil
+}
+
+// session returns the session of the request,
+// and starts a new one if it has none
+func session(w http.ResponseWriter, r *http.Request) (string, error) {
+	//IDs must not escape the directory
+	if cookie, err := r.Cookie(sessionCookie); err == nil && idPattern.MatchString(cookie.Value) {
+		return cookie.Value, nil
+	}
+
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return "", err
+	}
+	session := base64.RawURLEncoding.EncodeToString(id[:])
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    session,
+		Path:     "/",
+		MaxAge:   int(sessionMaxAge.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return session, nil
+}
+
+// handleSettings returns the handler which responds with the settings of the session on GET,
+// and saves them on PUT
+func handleSettings(store *settingsStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		session, err := session(w, r)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		var settings Settings
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+			settings, err = store.load(session)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, err)
+				return
+			}
+
+		case http.MethodPut:
+			settings = defaultSettings
+			if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+				writeBodyError(w, err)
+				return
+			}
+			if err := settings.validate(); err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+			if err := store.save(session, settings); err != nil {
+				writeError(w, http.StatusInternalServerError, err)
+				return
+			}
+
+		default:
+			w.Header().Set("Allow", "GET, HEAD, PUT")
+			writeError(w, http.StatusMethodNotAllowed, errors.New("get settings with GET, or save them with PUT"))
+			return
+		}
+
+		//the settings differ per session
+		w.Header().Set("Cache-Control", "private, no-store")
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(settings)
+	}
+}
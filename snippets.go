@@ -0,0 +1,172 @@
+//go:build !noui
+
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// maxMemorySnippets is the number of snippets kept without a directory,
+// older ones are dropped
+const maxMemorySnippets = 1000
+
+// snippetStore keeps the snippets shared from the web UI,
+// as files of a directory, or in memory if there is none.
+// Snippets are addressed by the hash of their content,
+// so sharing the same snippet again returns the same ID
+type snippetStore struct {
+	dir string
+
+	mu     sync.Mutex
+	memory map[string][]byte
+	// order are the IDs in memory, oldest first
+	order []string
+}
+
+var idPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+var errSnippetNotFound = errors.New("snippet not found")
+
+func newSnippetStore(dir string) *snippetStore {
+	return &snippetStore{
+		dir:    dir,
+		memory: map[string][]byte{},
+	}
+}
+
+// save stores the snippet and returns its ID
+func (s *snippetStore) save(data []byte) (string, error) {
+	hash := sha256.Sum256(data)
+	id := base64.RawURLEncoding.EncodeToString(hash[:8])
+
+	if s.dir != "" {
+		path := filepath.Join(s.dir, id+".json")
+		if _, err := os.Stat(path); err == nil {
+			return id, nil
+		}
+		return id, os.WriteFile(path, data, 0644)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.memory[id]; ok {
+		return id, nil
+	}
+	if len(s.order) >= maxMemorySnippets {
+		delete(s.memory, s.order[0])
+		s.order = s.order[1:]
+	}
+	s.memory[id] = data
+	s.order = append(s.order, id)
+	return id, nil
+}
+
+// load returns the snippet with the ID
+func (s *snippetStore) load(id string) ([]byte, error) {
+	//IDs must not escape the directory
+	if !idPattern.MatchString(id) {
+		return nil, errSnippetNotFound
+	}
+
+	if s.dir != "" {
+		data, err := os.ReadFile(filepath.Join(s.dir, id+".json"))
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, errSnippetNotFound
+		}
+		return data, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.memory[id]
+	if !ok {
+		return nil, errSnippetNotFound
+	}
+	return data, nil
+}
+
+// ShareResponse is the response of POST /share,
+// the snippet is loaded by GET /share/{id}, and into the web UI by /s/{id}
+type ShareResponse struct {
+	ID string `json:"id"`
+}
+
+// handleShare returns the handler which stores the code and options of the request
+func handleShare(store *snippetStore, profiles profileDir) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, errors.New("share snippets with POST"))
+			return
+		}
+
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeBodyError(w, err)
+			return
+		}
+		//snippets must load with the options they were shared with
+		if _, err := profiles.options(req.RequestOptions); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		data, err := json.Marshal(req)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		id, err := store.save(data)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(ShareResponse{ID: id})
+	}
+}
+
+// handleSnippet returns the handler which responds with the snippet of /share/{id}
+func handleSnippet(store *snippetStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		data, err := store.load(strings.TrimPrefix(r.URL.Path, "/share/"))
+		if errors.Is(err, errSnippetNotFound) {
+			writeError(w, http.StatusNotFound, err)
+			return
+		} else if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(data)
+	}
+}
@@ -0,0 +1,294 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/onflow/cadence/runtime/parser/lexer"
+
+	"cadencefmt/format"
+)
+
+// runStability is a differential test of the comment placement:
+// each file of the corpus is formatted as it is,
+// and again after randomly perturbing its whitespace,
+// and both results must be identical.
+// String literals must also survive formatting byte for byte.
+//
+// Line breaks are kept, as they determine
+// if a comment is leading or trailing
+func runStability(args []string) {
+	flags := flag.NewFlagSet("stability", flag.ExitOnError)
+	optionFlags := addOptionFlags(flags)
+	roundsFlag := flags.Int("n", 10, "perturbations per file")
+	seedFlag := flags.Int64("seed", 1, "random seed")
+	_ = flags.Parse(args)
+
+	cliOptions, err := optionFlags.cliOptions()
+	if err != nil {
+		fatal(err)
+	}
+	options := cliOptions.Options
+
+	if flags.NArg() == 0 {
+		fatal(errors.New("usage: cadencefmt stability <corpus>..."))
+	}
+
+	random := rand.New(rand.NewSource(*seedFlag))
+
+	filenames, err := cadenceFiles(flags.Args())
+	if err != nil {
+		fatal(err)
+	}
+
+	failures := 0
+	for _, filename := range filenames {
+		code, err := os.ReadFile(filename)
+		if err != nil {
+			fatal(err)
+		}
+
+		expected, err := format.Source(string(code), options)
+		if err != nil {
+			fmt.Printf("SKIP %s: %s\n", filename, firstLine(err.Error()))
+			continue
+		}
+
+		if literal, ok := changedStringLiteral(string(code), expected); !ok {
+			fmt.Printf("FAIL %s: string literal changed: %s\n", filename, literal)
+			failures++
+			continue
+		}
+
+		failed := false
+		for round := 0; round < *roundsFlag && !failed; round++ {
+			perturbed := perturbWhitespace(string(code), random)
+
+			actual, err := format.Source(perturbed, options)
+			if err != nil {
+				fmt.Printf("FAIL %s: perturbed input does not parse: %s\n", filename, firstLine(err.Error()))
+				failed = true
+				break
+			}
+
+			if line, ok := firstDifference(expected, actual); !ok {
+				fmt.Printf("FAIL %s: output differs at line %d\n", filename, line)
+				fmt.Printf("  expected: %q\n", lineAt(expected, line))
+				fmt.Printf("  actual:   %q\n", lineAt(actual, line))
+				failed = true
+			}
+		}
+
+		if failed {
+			failures++
+		} else {
+			fmt.Printf("ok   %s\n", filename)
+		}
+	}
+
+	if failures > 0 {
+		fmt.Printf("%d of %d files unstable\n", failures, len(filenames))
+		os.Exit(exitInternal)
+	}
+}
+
+// perturbWhitespace randomly changes the horizontal whitespace between tokens.
+// Regions between cadencefmt:off and cadencefmt:on comments are kept as written
+func perturbWhitespace(code string, random *rand.Rand) string {
+	tokens := lexer.Lex([]byte(code), nil)
+	defer tokens.Reclaim()
+
+	var result strings.Builder
+	off := false
+	var space string
+	for {
+		token := tokens.Next()
+		if token.Is(lexer.TokenEOF) {
+			result.WriteString(perturbSpace(space, off, random))
+			break
+		}
+
+		text := code[token.StartPos.Offset : token.EndPos.Offset+1]
+		if token.Is(lexer.TokenSpace) {
+			space += text
+			continue
+		}
+
+		directive := ""
+		if token.Is(lexer.TokenLineComment) {
+			directive = strings.TrimSpace(strings.TrimPrefix(text, "//"))
+		}
+		if directive == "cadencefmt:off" && !off {
+			//the indentation of the comment is part of the region
+			before, indentation := space, ""
+			if i := strings.LastIndex(space, "\n"); i >= 0 {
+				before, indentation = space[:i], space[i:]
+			}
+			result.WriteString(perturbSpace(before, false, random))
+			result.WriteString(indentation)
+			off = true
+		} else {
+			result.WriteString(perturbSpace(space, off, random))
+		}
+		space = ""
+
+		result.WriteString(text)
+		if directive == "cadencefmt:on" {
+			off = false
+		}
+	}
+
+	return result.String()
+}
+
+// perturbSpace randomly changes the horizontal whitespace,
+// unless it is in a region which is kept as written
+func perturbSpace(text string, off bool, random *rand.Rand) string {
+	if off || text == "" {
+		return text
+	}
+
+	var result strings.Builder
+	segments := strings.Split(text, "\n")
+	for i := range segments {
+		if i > 0 {
+			result.WriteString("\n")
+		}
+
+		switch {
+		case i < len(segments)-1:
+			//trailing whitespace, the line endings are kept
+			result.WriteString(strings.Repeat(" ", random.Intn(3)))
+			if strings.HasSuffix(segments[i], "\r") {
+				result.WriteString("\r")
+			}
+		case i > 0:
+			//indentation
+			result.WriteString(strings.Repeat(" ", random.Intn(9)))
+		default:
+			//separator, must be kept
+			result.WriteString(strings.Repeat(" ", 1+random.Intn(3)))
+		}
+	}
+	return result.String()
+}
+
+// changedStringLiteral returns the first string literal of the code
+// which is not in the formatted code as it was written
+func changedStringLiteral(code string, formatted string) (string, bool) {
+	literals := stringLiterals(code)
+	formattedLiterals := stringLiterals(formatted)
+	for i, literal := range literals {
+		if i >= len(formattedLiterals) || formattedLiterals[i] != literal {
+			return literal, false
+		}
+	}
+	return "", true
+}
+
+// stringLiterals returns the string literals of the code, in order.
+// The locations of imports are not included, as imports may be sorted
+func stringLiterals(code string) []string {
+	tokens := lexer.Lex([]byte(code), nil)
+	defer tokens.Reclaim()
+
+	var literals []string
+	previous := ""
+	for {
+		token := tokens.Next()
+		switch token.Type {
+		case lexer.TokenEOF:
+			return literals
+		case lexer.TokenSpace, lexer.TokenLineComment,
+			lexer.TokenBlockCommentStart, lexer.TokenBlockCommentContent, lexer.TokenBlockCommentEnd:
+			continue
+		}
+
+		text := code[token.StartPos.Offset : token.EndPos.Offset+1]
+		if token.Is(lexer.TokenString) && previous != "import" && previous != "from" {
+			literals = append(literals, text)
+		}
+		previous = text
+	}
+}
+
+// cadenceFiles returns the given files,
+// and the Cadence files in the given directories
+func cadenceFiles(paths []string) ([]string, error) {
+	var filenames []string
+	for _, root := range paths {
+		if root == stdinFilename {
+			filenames = append(filenames, root)
+			continue
+		}
+		err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if path == root && !entry.IsDir() {
+				filenames = append(filenames, path)
+			} else if entry.Type().IsRegular() && filepath.Ext(path) == ".cdc" {
+				filenames = append(filenames, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return filenames, nil
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimPrefix(s, "Parsing failed:\n"), "\n")
+	return line
+}
+
+// firstDifference returns the first line, starting at 1,
+// on which the texts differ, and false if they differ
+func firstDifference(expected, actual string) (int, bool) {
+	if expected == actual {
+		return 0, true
+	}
+
+	expectedLines := strings.Split(expected, "\n")
+	actualLines := strings.Split(actual, "\n")
+	for i := 0; i < len(expectedLines) && i < len(actualLines); i++ {
+		if expectedLines[i] != actualLines[i] {
+			return i + 1, false
+		}
+	}
+	return min(len(expectedLines), len(actualLines)) + 1, false
+}
+
+func lineAt(text string, line int) string {
+	lines := strings.Split(text, "\n")
+	if line > len(lines) {
+		return ""
+	}
+	return lines[line-1]
+}
@@ -0,0 +1,61 @@
+//go:build !noui
+
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"cadencefmt/format"
+)
+
+// TokensResponse is the response of /tokens
+type TokensResponse struct {
+	Source []format.Token `json:"source"`
+	Layout []format.Token `json:"layout"`
+}
+
+// handleTokens returns the handler which responds with the token streams of the code and its layout,
+// for debugging comments which are put back at the wrong place
+func handleTokens(profiles profileDir) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req Request
+		if err := decodeBody(r, &req); err != nil {
+			writeBodyError(w, err)
+			return
+		}
+		options, err := profiles.options(req.RequestOptions)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		var response TokensResponse
+		response.Source, response.Layout, err = format.TokenStreams(req.Code, options)
+		if err != nil {
+			writeError(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	}
+}
@@ -0,0 +1,243 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"unicode"
+
+	"github.com/onflow/cadence/runtime/parser/lexer"
+
+	"cadencefmt/format"
+)
+
+const triageReadme = `This directory reproduces a failure of cadencefmt.
+
+  input.cdc           the code which was formatted
+  input.redacted.cdc  the code, with the text of comments and string literals replaced
+  options.json        the formatting options
+  version.json        the versions of the formatter and the Cadence parser
+  error.txt           the failed safety check
+  stack.txt           the stack of the panic, if the formatter panicked
+  stages/             the output of each formatting stage
+
+"cadencefmt minimize input.cdc" shrinks the code to a small input with the same failure.
+Please check if the code reproduces the failure with input.redacted.cdc,
+and remove anything confidential before attaching the files to a bug report.
+`
+
+// formatVerified formats the code and checks the result,
+// with the additional checks the options enable.
+// If a safety check fails, or the formatter panics,
+// a repro bundle is written, and the error points to it
+func formatVerified(code string, cliOptions cliOptions) (result string, err error) {
+	options := cliOptions.Options
+	var stack []byte
+
+	defer func() {
+		if r := recover(); r != nil {
+			stack = debug.Stack()
+			err = &format.SafetyError{Check: "panic", Message: fmt.Sprint(r)}
+		}
+
+		safetyErr, ok := err.(*format.SafetyError)
+		if !ok {
+			return
+		}
+		dir, bundleErr := writeTriageBundle(code, options, safetyErr, stack)
+		if bundleErr != nil {
+			err = fmt.Errorf("%w (writing repro bundle failed: %s)", err, bundleErr)
+			return
+		}
+		err = fmt.Errorf("%w\nA repro bundle was written to %s, please attach it to a bug report", err, dir)
+	}()
+
+	result, err = format.Source(code, options)
+	if err != nil {
+		return "", err
+	}
+	if err := checkFormatted(code, result, cliOptions); err != nil {
+		return "", err
+	}
+	return result, nil
+}
+
+// verifiedSource formats the code and runs the safety checks of every command line run on the result,
+// so the server and the daemon never return code which lost comments or parses differently.
+// These are the checks of format.WithSafetyChecks, which the WebAssembly build uses as well
+func verifiedSource(ctx context.Context, code string, options format.Options) (string, error) {
+	return format.NewFormatter(format.WithOptions(options), format.WithSafetyChecks(true)).FormatContext(ctx, code)
+}
+
+// checkSpan returns the check of the spans of range formatting,
+// which are formatted with their own options
+func checkSpan(cliOptions cliOptions) func(code, formatted string, options format.Options) error {
+	return func(code, formatted string, options format.Options) error {
+		cliOptions.Options = options
+		return checkFormatted(code, formatted, cliOptions)
+	}
+}
+
+// checkFormatted runs the safety checks on the formatted code,
+// with the additional checks the options enable
+func checkFormatted(code string, formatted string, cliOptions cliOptions) error {
+	options := cliOptions.Options
+	if err := format.Verify(code, formatted, options); err != nil {
+		return err
+	}
+	if !cliOptions.skipASTCheck {
+		if err := format.VerifyAST(code, formatted, options); err != nil {
+			return err
+		}
+	}
+	if cliOptions.verifyTokens {
+		if err := format.VerifyTokens(code, formatted, options); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeTriageBundle writes the files reproducing the failure to a new temporary directory
+func writeTriageBundle(code string, options format.Options, failure error, stack []byte) (string, error) {
+	dir, err := os.MkdirTemp("", "cadencefmt-triage-")
+	if err != nil {
+		return "", err
+	}
+	if err := os.Mkdir(filepath.Join(dir, "stages"), 0755); err != nil {
+		return "", err
+	}
+
+	encodedOptions, err := json.MarshalIndent(options, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	encodedVersion, err := json.MarshalIndent(currentVersion(), "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	files := map[string]string{
+		"README.txt":         triageReadme,
+		"input.cdc":          code,
+		"input.redacted.cdc": redact(code),
+		"options.json":       string(encodedOptions) + "\n",
+		"version.json":       string(encodedVersion) + "\n",
+		"error.txt":          failure.Error() + "\n",
+	}
+	if stack != nil {
+		files["stack.txt"] = string(stack)
+	}
+
+	layout := triageStage(func() (string, error) {
+		return format.Layout(code, options)
+	})
+	files[filepath.Join("stages", "1-layout.cdc")] = layout
+
+	output := triageStage(func() (string, error) {
+		return format.Source(code, options)
+	})
+	files[filepath.Join("stages", "2-output.cdc")] = output
+
+	files[filepath.Join("stages", "3-reformatted.cdc")] = triageStage(func() (string, error) {
+		return format.Source(output, options)
+	})
+
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			return "", err
+		}
+	}
+
+	return dir, nil
+}
+
+// triageStage runs a formatting stage, and returns its output,
+// or the error or panic it failed with
+func triageStage(stage func() (string, error)) (output string) {
+	defer func() {
+		if r := recover(); r != nil {
+			output = fmt.Sprintf("panic: %v\n", r)
+		}
+	}()
+
+	output, err := stage()
+	if err != nil {
+		return fmt.Sprintf("error: %s\n", err)
+	}
+	return output
+}
+
+// redact replaces the letters and digits of comments and string literals,
+// keeping their length, so the layout stays the same
+func redact(code string) string {
+	tokens := lexer.Lex([]byte(code), nil)
+	defer tokens.Reclaim()
+
+	var result strings.Builder
+	offset := 0
+	for {
+		token := tokens.Next()
+		if token.Is(lexer.TokenEOF) {
+			break
+		}
+
+		switch token.Type {
+		case lexer.TokenLineComment, lexer.TokenBlockCommentContent, lexer.TokenString:
+			start, end := token.StartPos.Offset, token.EndPos.Offset+1
+			result.WriteString(code[offset:start])
+			result.WriteString(redactText(code[start:end]))
+			offset = end
+		}
+	}
+	result.WriteString(code[offset:])
+
+	return result.String()
+}
+
+// redactText replaces the letters and digits of the text,
+// except in escape sequences, which must stay valid
+func redactText(text string) string {
+	var result strings.Builder
+	escape := false
+	unicodeEscape := false
+	for _, r := range text {
+		switch {
+		case unicodeEscape:
+			unicodeEscape = r != '}'
+		case escape:
+			escape = false
+			unicodeEscape = r == 'u'
+		case r == '\\':
+			escape = true
+		case unicode.IsLetter(r):
+			r = 'x'
+		case unicode.IsDigit(r):
+			r = '0'
+		}
+		result.WriteRune(r)
+	}
+	return result.String()
+}
@@ -0,0 +1,164 @@
+//a code editor for Cadence without dependencies:
+//a transparent textarea over the highlighted code,
+//with line numbers, marked lines, underlined errors and a ruler at the maximum line length
+
+const keywords = new Set([
+    'access', 'all', 'as', 'attach', 'attachment', 'auth', 'break', 'case', 'continue', 'contract',
+    'create', 'default', 'destroy', 'else', 'emit', 'enum', 'event', 'execute', 'fail', 'false',
+    'for', 'from', 'fun', 'if', 'import', 'in', 'init', 'interface', 'let', 'nil', 'post', 'pre',
+    'prepare', 'priv', 'pub', 'remove', 'resource', 'return', 'self', 'set', 'struct', 'switch',
+    'to', 'transaction', 'true', 'var', 'view', 'while',
+])
+
+const tokenPattern = /(\/\/[^\n]*|\/\*[\s\S]*?(?:\*\/|$))|("(?:[^"\\\n]|\\.)*"?)|(\b(?:0x[0-9a-fA-F_]+|0b[01_]+|0o[0-7_]+|\d[\d_]*(?:\.\d[\d_]*)?)\b)|([A-Za-z_][A-Za-z0-9_]*)/g
+
+function escapeHTML(text) {
+    return text.replace(/&/g, '&amp;').replace(/</g, '&lt;').replace(/>/g, '&gt;')
+}
+
+function highlight(code) {
+    let html = ''
+    let last = 0
+    for (const match of code.matchAll(tokenPattern)) {
+        const [text, comment, string, number, identifier] = match
+        const kind = comment ? 'comment'
+            : string ? 'string'
+            : number ? 'number'
+            : keywords.has(identifier) ? 'keyword'
+            : /^[A-Z]/.test(identifier) ? 'type'
+            : ''
+        html += escapeHTML(code.slice(last, match.index))
+        html += kind ? '<span class="' + kind + '">' + escapeHTML(text) + '</span>' : escapeHTML(text)
+        last = match.index + text.length
+    }
+    //the trailing newline keeps the last empty line as high as the one of the textarea
+    return html + escapeHTML(code.slice(last)) + '\n'
+}
+
+function createEditor(parent, { readOnly = false } = {}) {
+    parent.classList.add('code-editor')
+    parent.innerHTML =
+        '<div class="gutter"></div>' +
+        '<div class="code">' +
+        '<pre class="highlight"></pre>' +
+        '<pre class="markers"></pre>' +
+        '<div class="ruler" hidden></div>' +
+        '<textarea spellcheck="false" wrap="off"></textarea>' +
+        '</div>'
+
+    const gutter = parent.querySelector('.gutter')
+    const highlighted = parent.querySelector('.highlight')
+    const markers = parent.querySelector('.markers')
+    const ruler = parent.querySelector('.ruler')
+    const textarea = parent.querySelector('textarea')
+    textarea.readOnly = readOnly
+
+    let marked = new Set()
+    let errors = []
+    let indent = '    '
+
+    function scroll() {
+        highlighted.style.transform = 'translate(' + -textarea.scrollLeft + 'px, ' + -textarea.scrollTop + 'px)'
+        markers.style.transform = highlighted.style.transform
+        ruler.style.transform = 'translateX(' + -textarea.scrollLeft + 'px)'
+        gutter.style.transform = 'translateY(' + -textarea.scrollTop + 'px)'
+    }
+
+    function render() {
+        highlighted.innerHTML = highlight(textarea.value)
+        const count = textarea.value.split('\n').length
+        gutter.replaceChildren(...Array.from({ length: count }, (_, i) => {
+            const number = document.createElement('div')
+            number.textContent = i + 1
+            number.classList.toggle('marked', marked.has(i + 1))
+            return number
+        }))
+        renderMarkers()
+        scroll()
+    }
+
+    //underlines the errors in a layer of transparent text over the highlighted code
+    function renderMarkers() {
+        if (errors.length === 0) {
+            markers.textContent = ''
+            return
+        }
+
+        //columns of errors count code points, not UTF-16 units
+        const points = Array.from(textarea.value)
+        const lineStarts = []
+        let start = 0
+        for (const line of textarea.value.split('\n')) {
+            lineStarts.push(start)
+            start += Array.from(line).length + 1
+        }
+        const offset = (line, column) => (lineStarts[line - 1] ?? points.length) + column
+
+        const ranges = errors
+            .map(({ line, column, endLine, endColumn }) => ({
+                start: offset(line, column),
+                end: offset(endLine || line, endColumn ?? column) + 1,
+            }))
+            .sort((a, b) => a.start - b.start)
+
+        let html = ''
+        let last = 0
+        for (const { start, end } of ranges) {
+            if (start < last) {
+                continue
+            }
+            let text = points.slice(start, end).join('')
+            //errors at the end of a line or of the code underline a space
+            if (text === '' || text.startsWith('\n')) {
+                text = ' ' + text
+            }
+            html += escapeHTML(points.slice(last, start).join(''))
+            html += '<span class="error">' + escapeHTML(text) + '</span>'
+            last = Math.max(start, end)
+        }
+        markers.innerHTML = html + escapeHTML(points.slice(last).join('')) + '\n'
+    }
+
+    textarea.addEventListener('input', render)
+    textarea.addEventListener('scroll', scroll)
+    textarea.addEventListener('keydown', (event) => {
+        if (event.key !== 'Tab' || readOnly) {
+            return
+        }
+        event.preventDefault()
+        textarea.setRangeText(indent, textarea.selectionStart, textarea.selectionEnd, 'end')
+        textarea.dispatchEvent(new Event('input'))
+    })
+
+    return {
+        get value() {
+            return textarea.value
+        },
+        set value(value) {
+            textarea.value = value
+            render()
+        },
+        //shows the ruler after the given number of columns
+        set ruler(columns) {
+            ruler.hidden = !(columns > 0)
+            ruler.style.left = 'calc(' + columns + 'ch + var(--padding))'
+        },
+        //sets the text the Tab key inserts
+        set indent(text) {
+            indent = text
+        },
+        //marks the line numbers of the given lines, starting at 1
+        mark(lines) {
+            marked = new Set(lines)
+            render()
+        },
+        //underlines the ranges of the errors, with lines starting at 1 and columns at 0
+        markErrors(ranges) {
+            errors = ranges
+            render()
+        },
+        onInput(listener) {
+            textarea.addEventListener('input', () => listener(textarea.value))
+        },
+    }
+}
@@ -0,0 +1,149 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"runtime/debug"
+	"strings"
+)
+
+// version is set when building releases,
+// with -ldflags "-X main.version=v1.2.3"
+var version string
+
+// VersionResponse is the body of /version.
+// The formatted output can change between versions,
+// also of the Cadence parser, so clients can check both
+type VersionResponse struct {
+	Version string `json:"version"`
+	// Commit is the git commit the binary was built from, with -dirty for uncommitted changes
+	Commit string `json:"commit"`
+	// Cadence is the version of the onflow/cadence module, whose parser determines the grammar
+	Cadence string `json:"cadence"`
+	// Syntax is legacy for parsers of releases before Cadence 1.0, otherwise current
+	Syntax string `json:"syntax"`
+	Go     string `json:"go"`
+}
+
+func currentVersion() VersionResponse {
+	response := VersionResponse{
+		Version: version,
+		Commit:  "unknown",
+		Cadence: "unknown",
+		Syntax:  "unknown",
+	}
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		if response.Version == "" {
+			response.Version = "unknown"
+		}
+		return response
+	}
+
+	response.Go = info.GoVersion
+	if response.Version == "" {
+		response.Version = moduleVersion(info)
+	}
+	if commit := buildCommit(info); commit != "" {
+		response.Commit = commit
+	}
+	for _, dependency := range info.Deps {
+		if dependency.Path == "github.com/onflow/cadence" {
+			response.Cadence = dependency.Version
+			response.Syntax = cadenceCurrent
+			if strings.HasPrefix(dependency.Version, "v0.") {
+				response.Syntax = cadenceLegacy
+			}
+		}
+	}
+
+	return response
+}
+
+const (
+	// cadenceLegacy selects the syntax of the releases before Cadence 1.0, e.g. of archived contracts
+	cadenceLegacy = "legacy"
+	// cadenceCurrent selects the syntax of Cadence 1.0 and later releases
+	cadenceCurrent = "current"
+)
+
+// checkCadenceVersion checks that the linked Cadence parser is of the requested release,
+// e.g. "0.40" or "1.0", or of the requested syntax, legacy or current.
+// A binary links a single parser, as the formatter is built on its AST,
+// so code of other releases must be formatted by a build with their parser
+func checkCadenceVersion(requested string) error {
+	if requested == "" {
+		return nil
+	}
+
+	linked := strings.TrimPrefix(currentVersion().Cadence, "v")
+	legacy := strings.HasPrefix(linked, "0.")
+	switch requested {
+	case cadenceLegacy:
+		if legacy {
+			return nil
+		}
+		return fmt.Errorf("legacy cadence is not supported, this build formats cadence %s, use a build of a release before 1.0", linked)
+	case cadenceCurrent:
+		if !legacy {
+			return nil
+		}
+		return fmt.Errorf("current cadence is not supported, this build formats legacy cadence %s, use a build of 1.0 or later", linked)
+	}
+
+	release := strings.TrimPrefix(requested, "v")
+	if linked == release || strings.HasPrefix(linked, release+".") || strings.HasPrefix(linked, release+"-") {
+		return nil
+	}
+	return fmt.Errorf("cadence %s is not supported, this build formats cadence %s", requested, linked)
+}
+
+// moduleVersion is the version of the installed module,
+// or the commit it was built from
+func moduleVersion(info *debug.BuildInfo) string {
+	if info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+
+	revision := buildCommit(info)
+	if revision == "" {
+		return "devel"
+	}
+	return "devel-" + revision
+}
+
+// buildCommit is the git commit the binary was built from, with -dirty for uncommitted changes,
+// or empty if it was not built in a git checkout
+func buildCommit(info *debug.BuildInfo) string {
+	revision, modified := "", false
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value == "true"
+		}
+	}
+	if revision != "" && modified {
+		revision += "-dirty"
+	}
+	return revision
+}
@@ -0,0 +1,75 @@
+//go:build js && wasm
+
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Command wasm exposes the formatter to JavaScript,
+// so browsers can format Cadence code without the HTTP server:
+//
+//	const { code, error } = format(source, { maxLineLength: 80, tabs: false, comments: "re-anchor" })
+//
+// The options are the ones of the configuration file, and the result passes the safety checks
+// of the server and the command line
+package main
+
+import (
+	"encoding/json"
+	"syscall/js"
+
+	"cadencefmt/format"
+)
+
+func formatFunc(_ js.Value, args []js.Value) any {
+	if len(args) < 1 || args[0].Type() != js.TypeString {
+		return result("", "format(code, options): code must be a string")
+	}
+
+	var config format.Config
+	if len(args) > 1 && args[1].Type() == js.TypeObject {
+		//the options are decoded like a configuration file
+		encoded := js.Global().Get("JSON").Call("stringify", args[1]).String()
+		if err := json.Unmarshal([]byte(encoded), &config); err != nil {
+			return result("", "format(code, options): "+err.Error())
+		}
+	}
+	options, err := config.Options()
+	if err != nil {
+		return result("", err.Error())
+	}
+
+	formatter := format.NewFormatter(format.WithOptions(options), format.WithSafetyChecks(true))
+	code, err := formatter.Format(args[0].String())
+	if err != nil {
+		return result("", err.Error())
+	}
+	return result(code, "")
+}
+
+func result(code string, err string) map[string]any {
+	if err != "" {
+		return map[string]any{"error": err}
+	}
+	return map[string]any{"code": code}
+}
+
+func main() {
+	js.Global().Set("format", js.FuncOf(formatFunc))
+
+	//keep the exported function alive
+	select {}
+}
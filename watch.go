@@ -0,0 +1,72 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"log"
+	"os"
+	"time"
+)
+
+const watchInterval = 500 * time.Millisecond
+
+// watch formats the files in place whenever they are saved.
+// Directories are rescanned, so new files are picked up.
+// It polls modification times, and runs until the process is stopped
+func watch(paths []string, options cliOptions) {
+	options.write = true
+	options.check = false
+
+	modified := map[string]time.Time{}
+	first := true
+
+	for {
+		filenames, err := cadenceFiles(paths)
+		if err != nil {
+			log.Print(err)
+		}
+
+		for _, filename := range filenames {
+			info, err := os.Stat(filename)
+			if err != nil {
+				continue
+			}
+
+			lastModified, seen := modified[filename]
+			modified[filename] = info.ModTime()
+			if first || (seen && !info.ModTime().After(lastModified)) {
+				continue
+			}
+
+			if _, err := formatFile(filename, options, os.Stdout, os.Stderr); err != nil {
+				log.Print(err)
+				continue
+			}
+			log.Printf("formatted %s", filename)
+
+			//don't format our own write again
+			if info, err := os.Stat(filename); err == nil {
+				modified[filename] = info.ModTime()
+			}
+		}
+
+		first = false
+		time.Sleep(watchInterval)
+	}
+}
@@ -0,0 +1,347 @@
+//go:build !noui
+
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// websocketGUID is appended to the key of the handshake, see RFC 6455
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// websocketWriteTimeout limits writes, so a client which does not read cannot block the server
+const websocketWriteTimeout = 10 * time.Second
+
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+)
+
+const (
+	closeProtocolError   = 1002
+	closeMessageTooLarge = 1009
+)
+
+var (
+	errNotWebSocket       = errors.New("expected a WebSocket upgrade request")
+	errWebSocketVersion   = errors.New("unsupported WebSocket version, expected 13")
+	errWebSocketMasking   = errors.New("invalid masking of a WebSocket frame")
+	errWebSocketTooLarge  = errors.New("WebSocket message too large")
+	errWebSocketHandshake = errors.New("WebSocket handshake failed")
+)
+
+// wsConn is a WebSocket connection, with the little the live channel needs:
+// text messages, pings and closing.
+// Messages may be read by one goroutine, while others write
+type wsConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	// client masks the frames it writes, servers do not
+	client bool
+	// maxMessageSize limits messages, in bytes, 0 for no limit
+	maxMessageSize int64
+	writeMu        sync.Mutex
+}
+
+func isWebSocketUpgrade(r *http.Request) bool {
+	return headerHasToken(r.Header, "Connection", "upgrade") &&
+		headerHasToken(r.Header, "Upgrade", "websocket")
+}
+
+func headerHasToken(header http.Header, name string, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func websocketAccept(key string) string {
+	hash := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// upgradeWebSocket takes over the connection of the request.
+// Requests which are not WebSocket upgrades get an error response
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request, maxMessageSize int64) (*wsConn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if r.Method != http.MethodGet || !isWebSocketUpgrade(r) || key == "" {
+		writeError(w, http.StatusBadRequest, errNotWebSocket)
+		return nil, errNotWebSocket
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		writeError(w, http.StatusUpgradeRequired, errWebSocketVersion)
+		return nil, errWebSocketVersion
+	}
+
+	conn, buffered, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return nil, err
+	}
+	//the deadlines of the server are for requests, not for the connection
+	_ = conn.SetDeadline(time.Time{})
+
+	_, err = fmt.Fprintf(buffered,
+		"HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n",
+		websocketAccept(key),
+	)
+	if err == nil {
+		err = buffered.Flush()
+	}
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	return &wsConn{
+		conn:           conn,
+		reader:         buffered.Reader,
+		maxMessageSize: maxMessageSize,
+	}, nil
+}
+
+// dialWebSocket connects to the WebSocket endpoint of the http or https URL
+func dialWebSocket(rawURL string) (*wsConn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialTimeout("tcp", u.Host, websocketWriteTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	var nonce [16]byte
+	_, _ = rand.Read(nonce[:])
+	key := base64.StdEncoding.EncodeToString(nonce[:])
+
+	request, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	request.Header.Set("Connection", "Upgrade")
+	request.Header.Set("Upgrade", "websocket")
+	request.Header.Set("Sec-WebSocket-Key", key)
+	request.Header.Set("Sec-WebSocket-Version", "13")
+	if err := request.Write(conn); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	response, err := http.ReadResponse(reader, request)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	if response.StatusCode != http.StatusSwitchingProtocols ||
+		response.Header.Get("Sec-WebSocket-Accept") != websocketAccept(key) {
+
+		_ = conn.Close()
+		return nil, fmt.Errorf("%w: status %d", errWebSocketHandshake, response.StatusCode)
+	}
+
+	return &wsConn{
+		conn:   conn,
+		reader: reader,
+		client: true,
+	}, nil
+}
+
+// readMessage returns the next text or binary message, answering pings on the way.
+// It returns io.EOF once the other side closes the connection
+func (c *wsConn) readMessage() ([]byte, error) {
+	var message []byte
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if errors.Is(err, errWebSocketMasking) {
+			_ = c.close(closeProtocolError)
+			return nil, err
+		} else if errors.Is(err, errWebSocketTooLarge) {
+			_ = c.close(closeMessageTooLarge)
+			return nil, err
+		} else if err != nil {
+			return nil, err
+		}
+
+		switch opcode {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return nil, err
+			}
+		case opPong:
+		case opClose:
+			//the closing handshake echoes the status
+			_ = c.writeFrame(opClose, payload[:min(len(payload), 2)])
+			return nil, io.EOF
+		case opText, opBinary, opContinuation:
+			message = append(message, payload...)
+			if c.maxMessageSize > 0 && int64(len(message)) > c.maxMessageSize {
+				_ = c.close(closeMessageTooLarge)
+				return nil, errWebSocketTooLarge
+			}
+			if fin {
+				return message, nil
+			}
+		default:
+			_ = c.close(closeProtocolError)
+			return nil, fmt.Errorf("unknown WebSocket opcode %d", opcode)
+		}
+	}
+}
+
+func (c *wsConn) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin = header[0]&0x80 != 0
+	opcode = header[0] & 0x0f
+	masked := header[1]&0x80 != 0
+
+	length := uint64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var extended [2]byte
+		if _, err := io.ReadFull(c.reader, extended[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		if _, err := io.ReadFull(c.reader, extended[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(extended[:])
+	}
+
+	//clients mask their frames, servers do not
+	if masked == c.client {
+		return false, 0, nil, errWebSocketMasking
+	}
+	if length > math.MaxInt32 || (c.maxMessageSize > 0 && length > uint64(c.maxMessageSize)) {
+		return false, 0, nil, errWebSocketTooLarge
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.reader, mask[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return fin, opcode, payload, nil
+}
+
+func (c *wsConn) writeFrame(opcode byte, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	buffer := getBuffer()
+	defer putBuffer(buffer)
+
+	frame := append(buffer.Bytes(), 0x80|opcode)
+	var maskBit byte
+	if c.client {
+		maskBit = 0x80
+	}
+	switch length := len(payload); {
+	case length < 126:
+		frame = append(frame, maskBit|byte(length))
+	case length <= math.MaxUint16:
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(length))
+	default:
+		frame = append(frame, maskBit|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(length))
+	}
+
+	if c.client {
+		var mask [4]byte
+		_, _ = rand.Read(mask[:])
+		frame = append(frame, mask[:]...)
+		for i, b := range payload {
+			frame = append(frame, b^mask[i%4])
+		}
+	} else {
+		frame = append(frame, payload...)
+	}
+
+	_ = c.conn.SetWriteDeadline(time.Now().Add(websocketWriteTimeout))
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+// writeJSON writes the value as a text message
+func (c *wsConn) writeJSON(value any) error {
+	buffer := getBuffer()
+	defer putBuffer(buffer)
+
+	if err := json.NewEncoder(buffer).Encode(value); err != nil {
+		return err
+	}
+	//without the line break the encoder adds
+	return c.writeFrame(opText, bytes.TrimSuffix(buffer.Bytes(), []byte("\n")))
+}
+
+// close sends the status, and closes the connection
+func (c *wsConn) close(status uint16) error {
+	_ = c.writeFrame(opClose, binary.BigEndian.AppendUint16(nil, status))
+	return c.conn.Close()
+}
+
+func (c *wsConn) Close() error {
+	return c.conn.Close()
+}
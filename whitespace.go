@@ -0,0 +1,54 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"strings"
+)
+
+// cleanWhitespace only removes the trailing whitespace of the lines of the code,
+// indents them with the configured character, and fixes the final line break,
+// without parsing or laying out the code
+func cleanWhitespace(code string, options cliOptions) string {
+	lines := strings.Split(strings.ReplaceAll(code, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		line = strings.TrimRight(line, " \t")
+		text := strings.TrimLeft(line, " \t")
+		lines[i] = indentation(line[:len(line)-len(text)], options.Tabs) + text
+	}
+	result := options.LineEndings.Convert(strings.Join(lines, "\n"), code)
+	return finalNewline(result, options.finalNewline)
+}
+
+// indentation returns the indentation with tabs, or with spaces,
+// where tabs stop every four columns
+func indentation(indent string, tabs bool) string {
+	columns := 0
+	for _, c := range indent {
+		if c == '\t' {
+			columns += 4 - columns%4
+		} else {
+			columns++
+		}
+	}
+	if tabs {
+		return strings.Repeat("\t", columns/4) + strings.Repeat(" ", columns%4)
+	}
+	return strings.Repeat(" ", columns)
+}
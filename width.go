@@ -0,0 +1,167 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// wideLine is a line of formatted code exceeding the maximum line length
+type wideLine struct {
+	Line  int `json:"line"`
+	Width int `json:"width"`
+}
+
+// wideLines returns the lines wider than the maximum line length.
+// Text matching the exceptions, e.g. long paths or type identifiers,
+// cannot be wrapped, so it does not count towards the width
+func wideLines(code string, maxLineLength int, exceptions []*regexp.Regexp) []wideLine {
+	var result []wideLine
+	for i, line := range strings.Split(code, "\n") {
+		width := lineWidth(line)
+		if width <= maxLineLength {
+			continue
+		}
+
+		if exemptWidth(line, exceptions) <= maxLineLength {
+			continue
+		}
+
+		result = append(result, wideLine{Line: i + 1, Width: width})
+	}
+	return result
+}
+
+// widestLine returns the width of the widest line, without the exceptions
+func widestLine(code string, exceptions []*regexp.Regexp) int {
+	widest := 0
+	for _, line := range strings.Split(code, "\n") {
+		widest = max(widest, exemptWidth(line, exceptions))
+	}
+	return widest
+}
+
+// exemptWidth is the width of the line without the text matching the exceptions
+func exemptWidth(line string, exceptions []*regexp.Regexp) int {
+	for _, exception := range exceptions {
+		line = exception.ReplaceAllString(line, "")
+	}
+	return lineWidth(line)
+}
+
+// tabWidth is the number of columns of an indentation level
+const tabWidth = 4
+
+// lineWidth is the number of display columns of the line.
+// Tabs advance to the next indentation level,
+// and wide characters, e.g. CJK and emoji, take two columns
+func lineWidth(line string) int {
+	width := 0
+	for _, r := range line {
+		if r == '\t' {
+			width += tabWidth - width%tabWidth
+			continue
+		}
+		width += runeWidth(r)
+	}
+	return width
+}
+
+// wideRanges are the East Asian wide and fullwidth characters,
+// and the emoji, which are displayed in two columns
+var wideRanges = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{Lo: 0x1100, Hi: 0x115f, Stride: 1},
+		{Lo: 0x231a, Hi: 0x231b, Stride: 1},
+		{Lo: 0x2329, Hi: 0x232a, Stride: 1},
+		{Lo: 0x23e9, Hi: 0x23ec, Stride: 1},
+		{Lo: 0x25fd, Hi: 0x25fe, Stride: 1},
+		{Lo: 0x2614, Hi: 0x2615, Stride: 1},
+		{Lo: 0x2e80, Hi: 0x303e, Stride: 1},
+		{Lo: 0x3041, Hi: 0x33ff, Stride: 1},
+		{Lo: 0x3400, Hi: 0x4dbf, Stride: 1},
+		{Lo: 0x4e00, Hi: 0x9fff, Stride: 1},
+		{Lo: 0xa000, Hi: 0xa4cf, Stride: 1},
+		{Lo: 0xa960, Hi: 0xa97f, Stride: 1},
+		{Lo: 0xac00, Hi: 0xd7a3, Stride: 1},
+		{Lo: 0xf900, Hi: 0xfaff, Stride: 1},
+		{Lo: 0xfe10, Hi: 0xfe19, Stride: 1},
+		{Lo: 0xfe30, Hi: 0xfe6f, Stride: 1},
+		{Lo: 0xff00, Hi: 0xff60, Stride: 1},
+		{Lo: 0xffe0, Hi: 0xffe6, Stride: 1},
+	},
+	R32: []unicode.Range32{
+		{Lo: 0x16fe0, Hi: 0x18aff, Stride: 1},
+		{Lo: 0x1b000, Hi: 0x1b2ff, Stride: 1},
+		{Lo: 0x1f300, Hi: 0x1f64f, Stride: 1},
+		{Lo: 0x1f680, Hi: 0x1f6ff, Stride: 1},
+		{Lo: 0x1f900, Hi: 0x1f9ff, Stride: 1},
+		{Lo: 0x1fa70, Hi: 0x1faff, Stride: 1},
+		{Lo: 0x20000, Hi: 0x2fffd, Stride: 1},
+		{Lo: 0x30000, Hi: 0x3fffd, Stride: 1},
+	},
+}
+
+// runeWidth is the number of display columns of the character
+func runeWidth(r rune) int {
+	switch {
+	//combining marks and format characters, e.g. zero width joiners, have no width of their own
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		return 0
+	case unicode.Is(wideRanges, r):
+		return 2
+	}
+	return 1
+}
+
+// LineMetadata describes a line of formatted code,
+// so clients can highlight overflowing lines without measuring them
+type LineMetadata struct {
+	// Width is the number of display columns
+	Width    int  `json:"width"`
+	Overflow bool `json:"overflow"`
+}
+
+func lineMetadata(code string, maxLineLength int) []LineMetadata {
+	lines := strings.Split(strings.TrimSuffix(code, "\n"), "\n")
+	result := make([]LineMetadata, 0, len(lines))
+	for _, line := range lines {
+		width := lineWidth(line)
+		result = append(result, LineMetadata{
+			Width:    width,
+			Overflow: width > maxLineLength,
+		})
+	}
+	return result
+}
+
+func compileWidthExceptions(patterns []string) ([]*regexp.Regexp, error) {
+	exceptions := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		exception, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid width exception %q: %w", pattern, err)
+		}
+		exceptions = append(exceptions, exception)
+	}
+	return exceptions, nil
+}
@@ -0,0 +1,67 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// writeFormatted replaces the content of the file with the formatted code.
+// The code is written to a temporary file next to it, which is renamed over the file,
+// so the file is never left half written. The mode and the owner of the file are kept,
+// and symbolic links are followed, so the file they point to is written, not the link.
+// With a backup suffix, the original content is kept in the file with the suffix appended
+func writeFormatted(filename string, original []byte, formatted []byte, backupSuffix string) error {
+	target, err := filepath.EvalSymlinks(filename)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		return err
+	}
+
+	if backupSuffix != "" {
+		if err := os.WriteFile(target+backupSuffix, original, info.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(target), "."+filepath.Base(target)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	//the temporary file is gone once it is renamed
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(formatted); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Chmod(info.Mode().Perm()); err != nil {
+		temp.Close()
+		return err
+	}
+	keepOwner(temp, info)
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), target)
+}